@@ -8,6 +8,7 @@
 package cli
 
 import (
+	"bytes"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
@@ -52,10 +53,12 @@ import (
 	"github.com/hasura/graphql-engine/cli/version"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
 	"github.com/spf13/viper"
 	"github.com/subosito/gotenv"
 	"golang.org/x/crypto/ssh/terminal"
 	"gopkg.in/yaml.v2"
+	v3yaml "gopkg.in/yaml.v3"
 )
 
 // Other constants used in the package
@@ -97,6 +100,9 @@ const (
 	V3
 )
 
+// LatestConfigVersion is the newest config version supported by this build of the CLI
+const LatestConfigVersion = V3
+
 // ServerAPIPaths has the custom paths defined for server api
 type ServerAPIPaths struct {
 	V1Query    string `yaml:"v1_query,omitempty"`
@@ -162,7 +168,22 @@ func (c *ConfigVersion) String() string {
 
 // IsValid returns if its a valid config version
 func (c ConfigVersion) IsValid() bool {
-	return c != 0 && c <= V3
+	return c != 0 && c <= LatestConfigVersion
+}
+
+// Before returns if c is an older config version than other
+func (c ConfigVersion) Before(other ConfigVersion) bool {
+	return c < other
+}
+
+// After returns if c is a newer config version than other
+func (c ConfigVersion) After(other ConfigVersion) bool {
+	return c > other
+}
+
+// IsAtLeast returns if c is the same as or a newer config version than other
+func (c ConfigVersion) IsAtLeast(other ConfigVersion) bool {
+	return !c.Before(other)
 }
 
 // ServerConfig has the config values required to contact the server
@@ -229,6 +250,8 @@ type HasuraServerInternalConfig struct {
 	IsJwtSet         bool   `json:"is_jwt_set"`
 	JWT              string `json:"jwt"`
 	ConsoleAssetsDir string `json:"console_assets_dir"`
+	// ExperimentalFeatures enabled on the server, only reported by servers >= v2
+	ExperimentalFeatures []string `json:"experimental_features,omitempty"`
 }
 
 // GetVersionEndpoint provides the url to contact the version API
@@ -341,6 +364,8 @@ type Config struct {
 	SeedsDirectory string `yaml:"seeds_directory,omitempty"`
 	// ActionConfig defines the config required to create or generate codegen for an action.
 	ActionConfig *types.ActionExecutionConfig `yaml:"actions,omitempty"`
+	// DefaultSource is the database used by migrate and seed commands when --database-name is not set
+	DefaultSource string `yaml:"default_source,omitempty"`
 }
 
 // ExecutionContext contains various contextual information required by the cli
@@ -378,6 +403,14 @@ type ExecutionContext struct {
 	// HGE Headers, are the custom headers which can be passed to HGE API
 	HGEHeaders map[string]string
 
+	// MetadataAPI is the API used for metadata operations, one of APIV1Metadata
+	// or APIV1Query. When empty, it is chosen based on the server metadata version
+	MetadataAPI string
+	// QueryAPI is the API used for running SQL on the server (eg: by the
+	// hdb_table state stores), one of APIV1Query or APIV2Query. When empty,
+	// it is chosen based on the server metadata version
+	QueryAPI string
+
 	// Config is the configuration object storing the endpoint and admin secret
 	// information after reading from config file or env var.
 	Config *Config
@@ -581,53 +614,14 @@ func (ec *ExecutionContext) Validate() error {
 		return errors.Wrap(err, "ensuring codegen-assets repo failed")
 	}
 
-	// validate execution directory
-	err = ec.validateDirectory()
-	if err != nil {
-		return errors.Wrap(err, "validating current directory failed")
-	}
-
-	// load .env file
-	err = ec.loadEnvfile()
+	err = ec.ReadProjectConfig()
 	if err != nil {
-		return errors.Wrap(err, "loading .env file failed")
+		return err
 	}
 
-	// set names of config file
-	ec.ConfigFile = filepath.Join(ec.ExecutionDirectory, "config.yaml")
-
-	// read config and parse the values into Config
-	err = ec.readConfig()
+	err = ec.setupProjectDirectories()
 	if err != nil {
-		return errors.Wrap(err, "cannot read config")
-	}
-
-	// set name of migration directory
-	ec.MigrationDir = filepath.Join(ec.ExecutionDirectory, ec.Config.MigrationsDirectory)
-	if _, err := os.Stat(ec.MigrationDir); os.IsNotExist(err) {
-		err = os.MkdirAll(ec.MigrationDir, os.ModePerm)
-		if err != nil {
-			return errors.Wrap(err, "cannot create migrations directory")
-		}
-	}
-
-	ec.SeedsDirectory = filepath.Join(ec.ExecutionDirectory, ec.Config.SeedsDirectory)
-	if _, err := os.Stat(ec.SeedsDirectory); os.IsNotExist(err) {
-		err = os.MkdirAll(ec.SeedsDirectory, os.ModePerm)
-		if err != nil {
-			return errors.Wrap(err, "cannot create seeds directory")
-		}
-	}
-
-	if ec.Config.Version >= V2 && ec.Config.MetadataDirectory != "" {
-		// set name of metadata directory
-		ec.MetadataDir = filepath.Join(ec.ExecutionDirectory, ec.Config.MetadataDirectory)
-		if _, err := os.Stat(ec.MetadataDir); os.IsNotExist(err) {
-			err = os.MkdirAll(ec.MetadataDir, os.ModePerm)
-			if err != nil {
-				return errors.Wrap(err, "cannot create metadata directory")
-			}
-		}
+		return err
 	}
 
 	ec.Logger.Debug("graphql engine endpoint: ", ec.Config.ServerConfig.Endpoint)
@@ -711,6 +705,76 @@ func (ec *ExecutionContext) Validate() error {
 	return nil
 }
 
+// ReadProjectConfig validates the ExecutionDirectory, loads the .env file and
+// reads the config. Unlike Validate, it does not contact the server.
+func (ec *ExecutionContext) ReadProjectConfig() error {
+	// validate execution directory
+	err := ec.validateDirectory()
+	if err != nil {
+		return errors.Wrap(err, "validating current directory failed")
+	}
+
+	// load .env file
+	err = ec.loadEnvfile()
+	if err != nil {
+		return errors.Wrap(err, "loading .env file failed")
+	}
+
+	// set names of config file
+	ec.ConfigFile = filepath.Join(ec.ExecutionDirectory, "config.yaml")
+
+	// read config and parse the values into Config
+	err = ec.readConfig()
+	if err != nil {
+		return errors.Wrap(err, "cannot read config")
+	}
+	return nil
+}
+
+// ValidateWithoutServer reads the project config and sets up the project
+// directories like Validate, but does not contact the server. It is meant for
+// commands which can work on the project directory while the server is down.
+func (ec *ExecutionContext) ValidateWithoutServer() error {
+	err := ec.ReadProjectConfig()
+	if err != nil {
+		return err
+	}
+	return ec.setupProjectDirectories()
+}
+
+// setupProjectDirectories sets the migrations, seeds and metadata
+// directories of the project, creating them if they do not exist
+func (ec *ExecutionContext) setupProjectDirectories() error {
+	// set name of migration directory
+	ec.MigrationDir = filepath.Join(ec.ExecutionDirectory, ec.Config.MigrationsDirectory)
+	if _, err := os.Stat(ec.MigrationDir); os.IsNotExist(err) {
+		err = os.MkdirAll(ec.MigrationDir, os.ModePerm)
+		if err != nil {
+			return errors.Wrap(err, "cannot create migrations directory")
+		}
+	}
+
+	ec.SeedsDirectory = filepath.Join(ec.ExecutionDirectory, ec.Config.SeedsDirectory)
+	if _, err := os.Stat(ec.SeedsDirectory); os.IsNotExist(err) {
+		err = os.MkdirAll(ec.SeedsDirectory, os.ModePerm)
+		if err != nil {
+			return errors.Wrap(err, "cannot create seeds directory")
+		}
+	}
+
+	if ec.Config.Version >= V2 && ec.Config.MetadataDirectory != "" {
+		// set name of metadata directory
+		ec.MetadataDir = filepath.Join(ec.ExecutionDirectory, ec.Config.MetadataDirectory)
+		if _, err := os.Stat(ec.MetadataDir); os.IsNotExist(err) {
+			err = os.MkdirAll(ec.MetadataDir, os.ModePerm)
+			if err != nil {
+				return errors.Wrap(err, "cannot create metadata directory")
+			}
+		}
+	}
+	return nil
+}
+
 func (ec *ExecutionContext) checkServerVersion() error {
 	v, err := version.FetchServerVersion(ec.Config.ServerConfig.GetVersionEndpoint(), ec.Config.ServerConfig.HTTPClient)
 	if err != nil {
@@ -742,6 +806,64 @@ func (ec *ExecutionContext) WriteConfig(config *Config) error {
 	return ioutil.WriteFile(ec.ConfigFile, y, 0644)
 }
 
+// UpdateConfigFields sets the given top level keys in the config file in place,
+// preserving comments and the order of all other keys. Keys which do not exist
+// in the file are appended to it.
+func (ec *ExecutionContext) UpdateConfigFields(fields yaml.MapSlice) error {
+	return ec.UpdateConfigFieldsFs(afero.NewOsFs(), fields)
+}
+
+// UpdateConfigFieldsFs works like UpdateConfigFields, reading and writing the config file using fs
+func (ec *ExecutionContext) UpdateConfigFieldsFs(fs afero.Fs, fields yaml.MapSlice) error {
+	b, err := afero.ReadFile(fs, ec.ConfigFile)
+	if err != nil {
+		return err
+	}
+	var doc v3yaml.Node
+	if err := v3yaml.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	if doc.Kind == 0 {
+		// empty config file
+		doc = v3yaml.Node{Kind: v3yaml.DocumentNode, Content: []*v3yaml.Node{{Kind: v3yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if doc.Kind != v3yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != v3yaml.MappingNode {
+		return fmt.Errorf("expected %s to contain a yaml map", ec.ConfigFile)
+	}
+	mapping := doc.Content[0]
+	for _, field := range fields {
+		key := fmt.Sprintf("%v", field.Key)
+		value := new(v3yaml.Node)
+		if err := value.Encode(field.Value); err != nil {
+			return err
+		}
+		found := false
+		for idx := 0; idx+1 < len(mapping.Content); idx += 2 {
+			if mapping.Content[idx].Value != key {
+				continue
+			}
+			old := mapping.Content[idx+1]
+			value.HeadComment, value.LineComment, value.FootComment = old.HeadComment, old.LineComment, old.FootComment
+			mapping.Content[idx+1] = value
+			found = true
+			break
+		}
+		if !found {
+			mapping.Content = append(mapping.Content, &v3yaml.Node{Kind: v3yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+		}
+	}
+	var buf bytes.Buffer
+	encoder := v3yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	return afero.WriteFile(fs, ec.ConfigFile, buf.Bytes(), 0644)
+}
+
 type DefaultAPIPath string
 
 // readConfig reads the configuration from config file, flags and env vars,
@@ -767,6 +889,9 @@ func (ec *ExecutionContext) readConfig() error {
 	v.SetDefault("metadata_directory", "")
 	v.SetDefault("migrations_directory", DefaultMigrationsDirectory)
 	v.SetDefault("seeds_directory", DefaultSeedsDirectory)
+	v.SetDefault("default_source", "")
+	v.SetDefault("metadata_api", "")
+	v.SetDefault("query_api", "")
 	v.SetDefault("actions.kind", "synchronous")
 	v.SetDefault("actions.handler_webhook_baseurl", "http://localhost:3000")
 	v.SetDefault("actions.codegen.framework", "")
@@ -811,10 +936,19 @@ func (ec *ExecutionContext) readConfig() error {
 				URI:       v.GetString("actions.codegen.uri"),
 			},
 		},
+		DefaultSource: v.GetString("default_source"),
+	}
+	if ec.Config.Version > LatestConfigVersion {
+		return fmt.Errorf("your project uses config v%d but this CLI only supports up to v%d, please upgrade the CLI", ec.Config.Version, LatestConfigVersion)
 	}
 	if !ec.Config.Version.IsValid() {
 		return ErrInvalidConfigVersion
 	}
+	ec.MetadataAPI = v.GetString("metadata_api")
+	ec.QueryAPI = v.GetString("query_api")
+	if err := validateAPIOverrides(ec.MetadataAPI, ec.QueryAPI); err != nil {
+		return err
+	}
 	err = ec.Config.ServerConfig.ParseEndpoint()
 	if err != nil {
 		return errors.Wrap(err, "unable to parse server endpoint")
@@ -855,6 +989,21 @@ func (ec *ExecutionContext) Spin(message string) {
 	}
 }
 
+// StopSpinnerOnPanic is meant to be deferred by commands which use the
+// spinner. On a panic it stops the spinner and restores the terminal (clears
+// the partially written line and shows the cursor) before panicking again.
+func (ec *ExecutionContext) StopSpinnerOnPanic() {
+	if r := recover(); r != nil {
+		if ec.Spinner != nil {
+			ec.Spinner.Stop()
+			if ec.IsTerminal {
+				fmt.Fprint(ec.Spinner.Writer, "\r\033[K\033[?25h")
+			}
+		}
+		panic(r)
+	}
+}
+
 // loadEnvfile loads .env file
 func (ec *ExecutionContext) loadEnvfile() error {
 	envfile := filepath.Join(ec.ExecutionDirectory, ec.Envfile)
@@ -915,13 +1064,53 @@ func GetAdminSecretHeaderName(v *version.Version) string {
 	return XHasuraAdminSecret
 }
 
+// APIs which can be selected using ExecutionContext.MetadataAPI and ExecutionContext.QueryAPI
+const (
+	APIV1Metadata = "v1/metadata"
+	APIV1Query    = "v1/query"
+	APIV2Query    = "v2/query"
+)
+
+// validateAPIOverrides checks the values of metadata_api and query_api, which
+// are empty when the API is chosen based on the version of the server
+func validateAPIOverrides(metadataAPI, queryAPI string) error {
+	if metadataAPI != "" && metadataAPI != APIV1Metadata && metadataAPI != APIV1Query {
+		return fmt.Errorf("invalid metadata_api %q, expected one of %s, %s", metadataAPI, APIV1Metadata, APIV1Query)
+	}
+	if queryAPI != "" && queryAPI != APIV1Query && queryAPI != APIV2Query {
+		return fmt.Errorf("invalid query_api %q, expected one of %s, %s", queryAPI, APIV1Query, APIV2Query)
+	}
+	return nil
+}
+
 func GetCommonMetadataOps(ec *ExecutionContext) hasura.CommonMetadataOperations {
+	switch ec.MetadataAPI {
+	case APIV1Query:
+		return ec.APIClient.V1Query
+	case APIV1Metadata:
+		return ec.APIClient.V1Metadata
+	}
 	if !ec.HasMetadataV3 {
 		return ec.APIClient.V1Query
 	}
 	return ec.APIClient.V1Metadata
 }
 
+// GetPGSourceOps returns the client used for running SQL on postgres sources
+// of the server, honouring ec.QueryAPI
+func GetPGSourceOps(ec *ExecutionContext) hasura.PGSourceOps {
+	switch ec.QueryAPI {
+	case APIV1Query:
+		return ec.APIClient.V1Query
+	case APIV2Query:
+		return ec.APIClient.V2Query
+	}
+	if !ec.HasMetadataV3 {
+		return ec.APIClient.V1Query
+	}
+	return ec.APIClient.V2Query
+}
+
 func GetMigrationsStateStore(ec *ExecutionContext) statestore.MigrationsStateStore {
 	const (
 		defaultMigrationsTable = "schema_migrations"
@@ -929,10 +1118,7 @@ func GetMigrationsStateStore(ec *ExecutionContext) statestore.MigrationsStateSto
 	)
 
 	if ec.Config.Version <= V2 {
-		if !ec.HasMetadataV3 {
-			return migrations.NewMigrationStateStoreHdbTable(ec.APIClient.V1Query, defaultSchema, defaultMigrationsTable)
-		}
-		return migrations.NewMigrationStateStoreHdbTable(ec.APIClient.V2Query, defaultSchema, defaultMigrationsTable)
+		return migrations.NewMigrationStateStoreHdbTable(GetPGSourceOps(ec), defaultSchema, defaultMigrationsTable)
 	}
 	return migrations.NewCatalogStateStore(statestore.NewCLICatalogState(ec.APIClient.V1Metadata))
 }
@@ -944,10 +1130,7 @@ func GetSettingsStateStore(ec *ExecutionContext) statestore.SettingsStateStore {
 	)
 
 	if ec.Config.Version <= V2 {
-		if !ec.HasMetadataV3 {
-			return settings.NewStateStoreHdbTable(ec.APIClient.V1Query, defaultSchema, defaultSettingsTable)
-		}
-		return settings.NewStateStoreHdbTable(ec.APIClient.V2Query, defaultSchema, defaultSettingsTable)
+		return settings.NewStateStoreHdbTable(GetPGSourceOps(ec), defaultSchema, defaultSettingsTable)
 	}
 	return settings.NewStateStoreCatalog(statestore.NewCLICatalogState(ec.APIClient.V1Metadata))
 }
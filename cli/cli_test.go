@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfigVersionOrdering(t *testing.T) {
+	tests := []struct {
+		name      string
+		v, other  ConfigVersion
+		before    bool
+		after     bool
+		isAtLeast bool
+	}{
+		{"older version", V1, V2, true, false, false},
+		{"same version", V2, V2, false, false, true},
+		{"newer version", V3, V2, false, true, true},
+		{"unset version", ConfigVersion(0), V1, true, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.before, tt.v.Before(tt.other))
+			assert.Equal(t, tt.after, tt.v.After(tt.other))
+			assert.Equal(t, tt.isAtLeast, tt.v.IsAtLeast(tt.other))
+		})
+	}
+}
+
+func TestExecutionContext_UpdateConfigFieldsFs(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	ec := &ExecutionContext{ConfigFile: "config.yaml"}
+	assert.NoError(t, afero.WriteFile(fs, ec.ConfigFile, []byte("# server\nendpoint: http://localhost:8080\nversion: 2\n"), 0644))
+
+	assert.NoError(t, ec.UpdateConfigFieldsFs(fs, yaml.MapSlice{
+		{Key: "version", Value: 3},
+		{Key: "default_source", Value: "default"},
+	}))
+	b, err := afero.ReadFile(fs, ec.ConfigFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "# server\nendpoint: http://localhost:8080\nversion: 3\ndefault_source: default\n", string(b))
+}
+
+// fake clients, which are only compared with the client returned
+type fakeV1Metadata struct {
+	hasura.V1Metadata
+	name string
+}
+
+type fakeV1Query struct {
+	hasura.V1Query
+	name string
+}
+
+type fakeV2Query struct {
+	hasura.V2Query
+	name string
+}
+
+func TestGetCommonMetadataOpsAndGetPGSourceOps(t *testing.T) {
+	v1Metadata := &fakeV1Metadata{name: "v1/metadata"}
+	v1Query := &fakeV1Query{name: "v1/query"}
+	v2Query := &fakeV2Query{name: "v2/query"}
+	tests := []struct {
+		name           string
+		metadataAPI    string
+		queryAPI       string
+		hasMetadataV3  bool
+		wantMetadataOp interface{}
+		wantPGSourceOp interface{}
+	}{
+		{"server with metadata v3", "", "", true, v1Metadata, v2Query},
+		{"server without metadata v3", "", "", false, v1Query, v1Query},
+		{"v1/query for metadata on server with metadata v3", APIV1Query, "", true, v1Query, v2Query},
+		{"v1/metadata for metadata on server without metadata v3", APIV1Metadata, "", false, v1Metadata, v1Query},
+		{"v1/query for sql on server with metadata v3", "", APIV1Query, true, v1Metadata, v1Query},
+		{"v2/query for sql on server without metadata v3", "", APIV2Query, false, v1Query, v2Query},
+		{"both overridden", APIV1Query, APIV2Query, false, v1Query, v2Query},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := &ExecutionContext{
+				APIClient:     &hasura.Client{V1Metadata: v1Metadata, V1Query: v1Query, V2Query: v2Query},
+				HasMetadataV3: tt.hasMetadataV3,
+				MetadataAPI:   tt.metadataAPI,
+				QueryAPI:      tt.queryAPI,
+			}
+			assert.Equal(t, tt.wantMetadataOp, GetCommonMetadataOps(ec))
+			assert.Equal(t, tt.wantPGSourceOp, GetPGSourceOps(ec))
+		})
+	}
+}
+
+func Test_validateAPIOverrides(t *testing.T) {
+	tests := []struct {
+		name        string
+		metadataAPI string
+		queryAPI    string
+		wantErr     string
+	}{
+		{"not overridden", "", "", ""},
+		{"v1/metadata for metadata", APIV1Metadata, "", ""},
+		{"v1/query for metadata", APIV1Query, "", ""},
+		{"v1/query for sql", "", APIV1Query, ""},
+		{"v2/query for sql", "", APIV2Query, ""},
+		{"v2/query for metadata", APIV2Query, "", `invalid metadata_api "v2/query", expected one of v1/metadata, v1/query`},
+		{"unknown metadata api", "v3/metadata", "", `invalid metadata_api "v3/metadata", expected one of v1/metadata, v1/query`},
+		{"v1/metadata for sql", "", APIV1Metadata, `invalid query_api "v1/metadata", expected one of v1/query, v2/query`},
+		{"unknown query api", APIV1Metadata, "query", `invalid query_api "query", expected one of v1/query, v2/query`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAPIOverrides(tt.metadataAPI, tt.queryAPI)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@
 package main
 
 import (
+	"os"
+
 	"github.com/hasura/graphql-engine/cli/commands"
 	log "github.com/sirupsen/logrus"
 )
@@ -10,6 +12,10 @@ import (
 func main() {
 	err := commands.Execute()
 	if err != nil {
+		if exitErr, ok := err.(*commands.ExitError); ok {
+			log.Error(exitErr)
+			os.Exit(exitErr.Code)
+		}
 		log.Fatal(err)
 	}
 }
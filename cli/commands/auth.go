@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/hasura/graphql-engine/cli/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// NewAuthCmd returns the auth command
+func NewAuthCmd(ec *cli.ExecutionContext) *cobra.Command {
+	v := viper.New()
+	authCmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Check authentication with Hasura GraphQL engine",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			cmd.Root().PersistentPreRun(cmd, args)
+			ec.Viper = v
+			err := ec.Prepare()
+			if err != nil {
+				return err
+			}
+			// the server is not contacted here, so that
+			// a rejected admin secret can be reported by the subcommands
+			return ec.ReadProjectConfig()
+		},
+		SilenceUsage: true,
+	}
+	authCmd.AddCommand(
+		newAuthCheckCmd(ec),
+	)
+
+	f := authCmd.PersistentFlags()
+
+	f.String("endpoint", "", "http(s) endpoint for Hasura GraphQL engine")
+	f.String("admin-secret", "", "admin secret for Hasura GraphQL engine")
+	f.String("access-key", "", "access key for Hasura GraphQL engine")
+	f.MarkDeprecated("access-key", "use --admin-secret instead")
+	f.Bool("insecure-skip-tls-verify", false, "skip TLS verification and disable cert checking (default: false)")
+	f.String("certificate-authority", "", "path to a cert file for the certificate authority")
+
+	util.BindPFlag(v, "endpoint", f.Lookup("endpoint"))
+	util.BindPFlag(v, "admin_secret", f.Lookup("admin-secret"))
+	util.BindPFlag(v, "access_key", f.Lookup("access-key"))
+	util.BindPFlag(v, "insecure_skip_tls_verify", f.Lookup("insecure-skip-tls-verify"))
+	util.BindPFlag(v, "certificate_authority", f.Lookup("certificate-authority"))
+
+	return authCmd
+}
@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/hasura/graphql-engine/cli/internal/httpc"
+	"github.com/spf13/cobra"
+)
+
+// exit codes of auth check
+const (
+	authCheckExitCodeRejected    = 2
+	authCheckExitCodeUnreachable = 3
+	authCheckExitCodeUnexpected  = 4
+)
+
+const authCheckCmdExamples = `  # Check if the admin secret in config / env is accepted by the server:
+  hasura auth check
+
+  # Check an admin secret against a server:
+  hasura auth check --endpoint https://my-graphql-engine.com --admin-secret <admin-secret>
+
+  # Exit codes: 0 - admin secret accepted, 2 - admin secret rejected, 3 - server unreachable,
+  # 4 - unexpected response from the server`
+
+func newAuthCheckCmd(ec *cli.ExecutionContext) *cobra.Command {
+	opts := &authCheckOptions{
+		EC: ec,
+	}
+	authCheckCmd := &cobra.Command{
+		Use:          "check",
+		Short:        "Check if the admin secret is accepted by the server",
+		Long:         "Make a minimal authenticated request to the server and report if the admin secret is accepted, without running any operation",
+		Example:      authCheckCmdExamples,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return opts.run()
+		},
+	}
+	return authCheckCmd
+}
+
+type authCheckOptions struct {
+	EC *cli.ExecutionContext
+}
+
+func (o *authCheckOptions) run() error {
+	var headers map[string]string
+	if o.EC.Config.AdminSecret != "" {
+		headers = map[string]string{
+			cli.XHasuraAdminSecret: o.EC.Config.AdminSecret,
+		}
+	}
+	endpoint := o.EC.Config.Endpoint
+	if !strings.HasSuffix(endpoint, "/") {
+		endpoint = fmt.Sprintf("%s/", endpoint)
+	}
+	client, err := httpc.New(o.EC.Config.HTTPClient, endpoint, headers)
+	if err != nil {
+		return err
+	}
+	o.EC.Spin("Checking admin secret...")
+	accepted, err := checkAdminSecret(client, o.EC.Config.GetV1QueryEndpoint())
+	o.EC.Spinner.Stop()
+	var unexpected *unexpectedResponseError
+	if errors.As(err, &unexpected) {
+		return &ExitError{
+			Code: authCheckExitCodeUnexpected,
+			Err:  fmt.Errorf("server at %s responded unexpectedly: %w", o.EC.Config.Endpoint, err),
+		}
+	}
+	if err != nil {
+		return &ExitError{
+			Code: authCheckExitCodeUnreachable,
+			Err:  fmt.Errorf("cannot reach server at %s: %w", o.EC.Config.Endpoint, err),
+		}
+	}
+	if !accepted {
+		return &ExitError{
+			Code: authCheckExitCodeRejected,
+			Err:  fmt.Errorf("admin secret is rejected by server at %s", o.EC.Config.Endpoint),
+		}
+	}
+	o.EC.Logger.WithField("endpoint", o.EC.Config.Endpoint).Info("admin secret is accepted")
+	return nil
+}
+
+// unexpectedResponseError is returned by checkAdminSecret when the server
+// neither accepts nor rejects the admin secret
+type unexpectedResponseError struct {
+	statusCode int
+	body       string
+}
+
+func (e *unexpectedResponseError) Error() string {
+	return fmt.Sprintf("unexpected response (%d): %s", e.statusCode, e.body)
+}
+
+// checkAdminSecret reports if the admin secret set on client is accepted, by
+// exporting metadata from the query API at url. An error is returned when the
+// server cannot be reached, or an unexpectedResponseError when it responds unexpectedly.
+func checkAdminSecret(client *httpc.Client, url string) (bool, error) {
+	req, err := client.NewRequest(http.MethodPost, url, map[string]interface{}{
+		"type": "export_metadata",
+		"args": map[string]interface{}{},
+	})
+	if err != nil {
+		return false, err
+	}
+	resp, err := client.BareDo(context.Background(), req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return true, nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return false, nil
+	}
+	var hasuraError struct {
+		Code  string `json:"code"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &hasuraError); err != nil {
+		return false, &unexpectedResponseError{statusCode: resp.StatusCode, body: string(body)}
+	}
+	if hasuraError.Code == "access-denied" {
+		return false, nil
+	}
+	return false, &unexpectedResponseError{statusCode: resp.StatusCode, body: string(body)}
+}
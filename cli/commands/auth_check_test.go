@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/hasura/graphql-engine/cli/internal/testutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("auth_check", func() {
+
+	var dirName string
+	var session *Session
+	var teardown func()
+	BeforeEach(func() {
+		dirName = testutil.RandDirName()
+		hgeEndPort, teardownHGE := testutil.StartHasura(GinkgoT(), testutil.HasuraVersion)
+		hgeEndpoint := fmt.Sprintf("http://0.0.0.0:%s", hgeEndPort)
+		testutil.RunCommandAndSucceed(testutil.CmdOpts{
+			Args: []string{"init", dirName},
+		})
+		editEndpointInConfig(filepath.Join(dirName, defaultConfigFilename), hgeEndpoint)
+
+		teardown = func() {
+			session.Kill()
+			os.RemoveAll(dirName)
+			teardownHGE()
+		}
+	})
+
+	AfterEach(func() {
+		teardown()
+	})
+
+	Context("auth check test", func() {
+		It("reports the admin secret is accepted", func() {
+			session = testutil.Hasura(testutil.CmdOpts{
+				Args:             []string{"auth", "check"},
+				WorkingDirectory: dirName,
+			})
+			want := `admin secret is accepted`
+			Eventually(session, 60*40).Should(Exit(0))
+			Eventually(session.Wait().Err.Contents()).Should(ContainSubstring(want))
+		})
+		It("exits with a distinct exit code when the server is unreachable", func() {
+			session = testutil.Hasura(testutil.CmdOpts{
+				Args:             []string{"auth", "check", "--endpoint", "http://0.0.0.0:1"},
+				WorkingDirectory: dirName,
+			})
+			Eventually(session, 60*40).Should(Exit(authCheckExitCodeUnreachable))
+		})
+		It("exits with a distinct exit code when the server responds unexpectedly", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+				fmt.Fprint(w, "internal error")
+			}))
+			defer server.Close()
+			session = testutil.Hasura(testutil.CmdOpts{
+				Args:             []string{"auth", "check", "--endpoint", server.URL},
+				WorkingDirectory: dirName,
+			})
+			Eventually(session, 60*40).Should(Exit(authCheckExitCodeUnexpected))
+		})
+	})
+})
@@ -1,13 +1,19 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/hasura/graphql-engine/cli/internal/metadataobject"
+	"github.com/hasura/graphql-engine/cli/internal/metadatautil"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
 
 	"github.com/aryann/difflib"
 	"github.com/hasura/graphql-engine/cli"
@@ -33,10 +39,12 @@ func newMetadataDiffCmd(ec *cli.ExecutionContext) *cobra.Command {
 	}
 
 	metadataDiffCmd := &cobra.Command{
-		Use:   "diff [file1] [file2]",
+		Use:   "diff [file1 | git-revision1] [file2 | git-revision2]",
 		Short: "(PREVIEW) Show a highlighted diff of Hasura metadata",
 		Long: `(PREVIEW) Show changes between two different sets of Hasura metadata.
-By default, it shows changes between the exported metadata file and server metadata`,
+By default, it shows changes between the exported metadata file and server metadata.
+When both arguments are git revisions of the project, a per object summary of the changes
+(eg: tables added or removed, permissions changed) is shown instead`,
 		Example: `  # NOTE: This command is in preview, usage and diff format may change.
 
   # Show changes between server metadata and the exported metadata file:
@@ -48,6 +56,9 @@ By default, it shows changes between the exported metadata file and server metad
   # Show changes between metadata from metadata.yaml and metadata_old.yaml:
   hasura metadata diff metadata.yaml metadata_old.yaml
 
+  # Show changed metadata objects between two git revisions of the project:
+  hasura metadata diff main HEAD
+
   # Apply admin secret for Hasura GraphQL engine:
   hasura metadata diff --admin-secret "<admin-secret>"
 
@@ -139,8 +150,106 @@ func (o *MetadataDiffOptions) runv2(args []string) error {
 	return nil
 }
 
+// runGitRevisions shows a semantic diff between metadata in the project
+// directory at two git revisions
+func (o *MetadataDiffOptions) runGitRevisions(revisions [2]string) error {
+	repo, err := git.PlainOpenWithOptions(o.EC.ExecutionDirectory, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return errors.Wrap(err, "arguments are neither directories nor git revisions")
+	}
+	o.EC.Logger.Infof("Showing changed metadata objects between %s and %s...", revisions[0], revisions[1])
+	var metadata [2]map[string]interface{}
+	for idx, revision := range revisions {
+		metadata[idx], err = o.buildMetadataAtGitRevision(repo, revision)
+		if err != nil {
+			return err
+		}
+	}
+	changes := metadatautil.SemanticDiff(metadata[0], metadata[1])
+	if len(changes) == 0 {
+		fmt.Fprintln(o.Output, "no changes")
+		return nil
+	}
+	for _, change := range changes {
+		switch change.Type {
+		case metadatautil.ChangeAdded:
+			fmt.Fprintf(o.Output, "%s\n", ansi.Color("+ "+change.Object, "green"))
+		case metadatautil.ChangeRemoved:
+			fmt.Fprintf(o.Output, "%s\n", ansi.Color("- "+change.Object, "red"))
+		case metadatautil.ChangeModified:
+			fmt.Fprintf(o.Output, "%s\n", ansi.Color("~ "+change.Object, "yellow"))
+		}
+	}
+	return nil
+}
+
+// buildMetadataAtGitRevision checks out the metadata directory at the given
+// revision to a temporary directory and builds metadata from it
+func (o *MetadataDiffOptions) buildMetadataAtGitRevision(repo *git.Repository, revision string) (map[string]interface{}, error) {
+	hash, err := repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return nil, errors.Wrapf(err, "resolving git revision %s", revision)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading commit %s", revision)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading tree of %s", revision)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	metadataDir, err := filepath.Rel(worktree.Filesystem.Root(), o.EC.MetadataDir)
+	if err != nil {
+		return nil, err
+	}
+	metadataDir = filepath.ToSlash(metadataDir) + "/"
+
+	tmpDir, err := ioutil.TempDir("", "*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if !strings.HasPrefix(f.Name, metadataDir) {
+			return nil
+		}
+		contents, err := f.Contents()
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(tmpDir, filepath.FromSlash(strings.TrimPrefix(f.Name, metadataDir)))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(path, []byte(contents), 0644)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading metadata at %s", revision)
+	}
+
+	metadataHandler := metadataobject.NewHandlerFromEC(o.EC)
+	metadataHandler.SetMetadataObjects(metadataobject.GetMetadataObjectsWithDir(o.EC, tmpDir))
+	jsonMetadata, err := metadataHandler.MakeJSONMetadata()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building metadata at %s", revision)
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(jsonMetadata, &metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
 func (o *MetadataDiffOptions) Run() error {
 	if o.EC.Config.Version >= cli.V2 && o.EC.MetadataDir != "" {
+		if len(o.Args) == 2 && checkDir(o.Args[0]) != nil && checkDir(o.Args[1]) != nil {
+			// arguments are not directories, treat them as git revisions
+			return o.runGitRevisions([2]string{o.Args[0], o.Args[1]})
+		}
 		return o.runv2(o.Args)
 	} else {
 		return fmt.Errorf("metadata diff for config %d not supported", o.EC.Config.Version)
@@ -3,10 +3,16 @@ package commands
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/hasura/graphql-engine/cli"
 	"github.com/hasura/graphql-engine/cli/internal/metadataobject"
+	"github.com/hasura/graphql-engine/cli/internal/metadataobject/sources"
+	"github.com/hasura/graphql-engine/cli/internal/metadatautil"
+	"github.com/hasura/graphql-engine/cli/internal/scripts"
 	"github.com/pkg/errors"
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 )
 
@@ -31,7 +37,13 @@ func newMetadataExportCmd(ec *cli.ExecutionContext) *cobra.Command {
   hasura metadata export --admin-secret "<admin-secret>"
 
   # Export metadata to another instance specified by the flag:
-  hasura metadata export --endpoint "<endpoint>"`,
+  hasura metadata export --endpoint "<endpoint>"
+
+  # Export metadata, leaving files of databases which cannot be reached untouched:
+  hasura metadata export --skip-unreachable-databases
+
+  # Continue an export which failed midway, without exporting everything again:
+  hasura metadata export --resume`,
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			err := opts.Run()
@@ -45,6 +57,9 @@ func newMetadataExportCmd(ec *cli.ExecutionContext) *cobra.Command {
 
 	f := metadataExportCmd.Flags()
 	f.StringVarP(&opts.output, "output", "o", "", `specify an output format for exported metadata (note: this won't modify project metadata) Allowed values: json, yaml")`)
+	f.BoolVar(&opts.skipUnreachableSources, "skip-unreachable-databases", false, "check connectivity of each database before exporting and leave metadata files of unreachable databases untouched (config v3 only)")
+	f.BoolVar(&opts.resume, "resume", false, "continue an earlier export which did not complete, skipping objects which were already exported")
+	f.IntVar(&opts.concurrency, "concurrency", 1, "number of metadata objects to export concurrently")
 
 	return metadataExportCmd
 }
@@ -52,7 +67,10 @@ func newMetadataExportCmd(ec *cli.ExecutionContext) *cobra.Command {
 type MetadataExportOptions struct {
 	EC *cli.ExecutionContext
 
-	output string
+	output                 string
+	skipUnreachableSources bool
+	resume                 bool
+	concurrency            int
 }
 
 func (o *MetadataExportOptions) Run() error {
@@ -60,20 +78,57 @@ func (o *MetadataExportOptions) Run() error {
 		return getMetadataFromServerAndWriteToStdoutByFormat(o.EC, rawOutputFormat(o.output))
 	}
 	o.EC.Spin("Exporting metadata...")
+	fs := afero.NewOsFs()
+	stagingDir := filepath.Join(o.EC.ExecutionDirectory, metadataobject.ExportStagingDirectory)
 	metadataHandler := metadataobject.NewHandlerFromEC(o.EC)
-	files, err := metadataHandler.ExportMetadata()
+	metadataHandler.SetExportConcurrency(o.concurrency)
+	files, err := metadataHandler.ExportMetadataResumable(fs, stagingDir, o.resume)
 	o.EC.Spinner.Stop()
 	if err != nil {
-		return errors.Wrap(err, "failed to export metadata")
+		return errors.Wrap(err, "failed to export metadata, use --resume to continue the export")
+	}
+	if o.skipUnreachableSources {
+		files, err = o.skipFilesOfUnreachableSources(files)
+		if err != nil {
+			return err
+		}
 	}
 	err = metadataHandler.WriteMetadata(files)
 	if err != nil {
 		return errors.Wrap(err, "cannot write metadata to project")
 	}
+	if err := fs.RemoveAll(stagingDir); err != nil {
+		o.EC.Logger.Warnf("cannot remove staged metadata export %s: %v", stagingDir, err)
+	}
 	o.EC.Logger.Info("Metadata exported")
 	return nil
 }
 
+// skipFilesOfUnreachableSources removes files belonging to sources which fail
+// a connectivity check, so that their existing files are left untouched
+func (o *MetadataExportOptions) skipFilesOfUnreachableSources(files map[string][]byte) (map[string][]byte, error) {
+	if o.EC.Config.Version < cli.V3 {
+		o.EC.Logger.Warn("--skip-unreachable-databases is supported only on config v3, exporting metadata of all databases")
+		return files, nil
+	}
+	sourceList, err := metadatautil.GetSourcesAndKind(o.EC.APIClient.V1Metadata.ExportMetadata)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing databases")
+	}
+	for _, source := range sourceList {
+		if err := scripts.CheckSourceHealth(o.EC, source); err != nil {
+			o.EC.Logger.Warnf("skipping export of metadata for database %s: %v", source.Name, err)
+			sourceDir := sources.SourceDirectory(o.EC.MetadataDir, source.Name) + string(filepath.Separator)
+			for name := range files {
+				if strings.HasPrefix(name, sourceDir) {
+					delete(files, name)
+				}
+			}
+		}
+	}
+	return files, nil
+}
+
 func getMetadataFromServerAndWriteToStdoutByFormat(ec *cli.ExecutionContext, format rawOutputFormat) error {
 	metadataReader, err := cli.GetCommonMetadataOps(ec).ExportMetadata()
 	if err != nil {
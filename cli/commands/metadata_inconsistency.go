@@ -17,6 +17,7 @@ func newMetadataInconsistencyCmd(ec *cli.ExecutionContext) *cobra.Command {
 		newMetadataInconsistencyListCmd(ec),
 		newMetadataInconsistencyDropCmd(ec),
 		newMetadataInconsistencyStatusCmd(ec),
+		newMetadataInconsistencyExportCmd(ec),
 	)
 	return metadataInconsistencyCmd
 }
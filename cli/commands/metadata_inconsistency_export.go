@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hasura/graphql-engine/cli/internal/metadatautil"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/hasura/graphql-engine/cli"
+)
+
+const metadataInconsistencyExportCmdExamples = `  # Print the definitions of inconsistent objects as yaml:
+  hasura metadata inconsistency export
+
+  # Write the definitions of inconsistent objects as json to a file:
+  hasura metadata inconsistency export -o json --file inconsistent.json
+`
+
+func newMetadataInconsistencyExportCmd(ec *cli.ExecutionContext) *cobra.Command {
+	opts := &metadataInconsistencyExportOptions{
+		EC: ec,
+	}
+
+	metadataInconsistencyExportCmd := &cobra.Command{
+		Use:          "export",
+		Short:        "Export definitions of inconsistent objects from the metadata",
+		Long:         "Export only the metadata definitions of objects which are inconsistent on the server. The output has the shape of metadata and can be attached to a bug report as a minimal reproducer",
+		Example:      metadataInconsistencyExportCmdExamples,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := opts.run()
+			opts.EC.Spinner.Stop()
+			if err != nil {
+				return errors.Wrap(err, "failed to export inconsistent objects")
+			}
+			return nil
+		},
+	}
+
+	f := metadataInconsistencyExportCmd.Flags()
+	f.StringVarP(&opts.output, "output", "o", string(rawOutputFormatYAML), "output format of exported definitions. Allowed values: json, yaml")
+	f.StringVar(&opts.file, "file", "", "write exported definitions to a file instead of stdout")
+
+	return metadataInconsistencyExportCmd
+}
+
+type metadataInconsistencyExportOptions struct {
+	EC *cli.ExecutionContext
+
+	output string
+	file   string
+}
+
+func (o *metadataInconsistencyExportOptions) run() error {
+	o.EC.Spin("Getting inconsistent metadata...")
+	inconsistentMetadata, err := o.EC.APIClient.V1Metadata.GetInconsistentMetadata()
+	if err != nil {
+		return errors.Wrap(err, "getting inconsistent metadata")
+	}
+	if inconsistentMetadata.IsConsistent {
+		o.EC.Spinner.Stop()
+		o.EC.Logger.Info("metadata is consistent")
+		return nil
+	}
+	metadataReader, err := o.EC.APIClient.V1Metadata.ExportMetadata()
+	if err != nil {
+		return errors.Wrap(err, "exporting metadata")
+	}
+	var metadata map[string]interface{}
+	if err := json.NewDecoder(metadataReader).Decode(&metadata); err != nil {
+		return errors.Wrap(err, "decoding metadata")
+	}
+	filtered, unmatched := metadatautil.InconsistentObjectsMetadata(metadata, inconsistentMetadata.InconsistentObjects)
+	o.EC.Spinner.Stop()
+	for _, object := range unmatched {
+		b, _ := json.Marshal(object)
+		o.EC.Logger.Warnf("cannot find definition of inconsistent object in metadata: %s", string(b))
+	}
+
+	b, err := json.Marshal(filtered)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = os.Stdout
+	if len(o.file) != 0 {
+		f, err := os.Create(o.file)
+		if err != nil {
+			return fmt.Errorf("creating file %s: %w", o.file, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	return writeByOutputFormat(w, b, rawOutputFormat(o.output))
+}
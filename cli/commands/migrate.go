@@ -42,7 +42,7 @@ func NewMigrateCmd(ec *cli.ExecutionContext) *cobra.Command {
 	}
 
 	f := migrateCmd.PersistentFlags()
-	f.StringVar(&ec.Source.Name, "database-name", "", "database on which operation should be applied")
+	f.StringVar(&ec.Source.Name, "database-name", "", "database on which operation should be applied (default: default_source from config)")
 
 	f.String("endpoint", "", "http(s) endpoint for Hasura GraphQL engine")
 	f.String("admin-secret", "", "admin secret for Hasura GraphQL engine")
@@ -62,6 +62,7 @@ func NewMigrateCmd(ec *cli.ExecutionContext) *cobra.Command {
 		newMigrateStatusCmd(ec),
 		newMigrateCreateCmd(ec),
 		newMigrateSquashCmd(ec),
+		newMigrateExecCmd(ec),
 	)
 
 	return migrateCmd
@@ -105,23 +106,25 @@ func executeStatus(t *migrate.Migrate) (*migrate.Status, error) {
 func validateConfigV3Flags(cmd *cobra.Command, ec *cli.ExecutionContext) error {
 	if ec.Config.Version >= cli.V3 {
 		if !cmd.Flags().Changed("database-name") {
-			return errDatabaseNameNotSet{"--database-name flag is required"}
-		} else {
-			// if database-name flag is set
-			// find out the database kind by making a API call to server
-			// and update ec to include the database name and kind
-			sourceKind, err := metadatautil.GetSourceKind(ec.APIClient.V1Metadata.ExportMetadata, ec.Source.Name)
-			if err != nil {
-				return fmt.Errorf("determining database kind of %s: %w", ec.Source.Name, err)
+			// fallback to the default database set in config, if any
+			if len(ec.Config.DefaultSource) == 0 {
+				return errDatabaseNameNotSet{"--database-name flag is required"}
 			}
-			if sourceKind == nil {
-				return fmt.Errorf("error determining database kind for %s, check if database exists on hasura", ec.Source.Name)
-			}
-			ec.Source.Kind = *sourceKind
+			ec.Source.Name = ec.Config.DefaultSource
+		}
+		// find out the database kind by making a API call to server
+		// and update ec to include the database name and kind
+		sourceKind, err := metadatautil.GetSourceKind(ec.APIClient.V1Metadata.ExportMetadata, ec.Source.Name)
+		if err != nil {
+			return fmt.Errorf("determining database kind of %s: %w", ec.Source.Name, err)
+		}
+		if sourceKind == nil {
+			return fmt.Errorf("error determining database kind for %s, check if database exists on hasura", ec.Source.Name)
+		}
+		ec.Source.Kind = *sourceKind
 
-			if !migrate.IsMigrationsSupported(*sourceKind) {
-				return fmt.Errorf("migrations on source %s of kind %s is not supported", ec.Source.Name, *sourceKind)
-			}
+		if !migrate.IsMigrationsSupported(*sourceKind) {
+			return fmt.Errorf("migrations on source %s of kind %s is not supported", ec.Source.Name, *sourceKind)
 		}
 	} else {
 		// for project using config older than v3, use PG source kind
@@ -159,6 +159,7 @@ func newMigrateApplyCmd(ec *cli.ExecutionContext) *cobra.Command {
 
 	f.BoolVar(&opts.dryRun, "dry-run", false, "print the names of migrations which are going to be applied")
 	f.BoolVar(&opts.allDatabases, "all-databases", false, "set this flag to attempt to apply migrations on all databases present on server")
+	f.IntVar(&opts.BatchSize, "batch-size", 0, "apply up to N SQL migrations in a single request to the server, versions are still recorded individually (0 to apply migrations one at a time)")
 	return migrateApplyCmd
 }
 
@@ -175,6 +176,8 @@ type MigrateApplyOptions struct {
 	dryRun        bool
 	Source        cli.Source
 	allDatabases  bool
+	// BatchSize is the number of SQL migrations applied in a single request
+	BatchSize int
 }
 type errDatabaseMigrationDirectoryNotFound struct {
 	message string
@@ -205,6 +208,7 @@ func (o *MigrateApplyOptions) Run() error {
 	}
 	migrateDrv.SkipExecution = o.SkipExecution
 	migrateDrv.DryRun = o.dryRun
+	migrateDrv.BatchSize = o.BatchSize
 
 	return ExecuteMigration(migrationType, migrateDrv, step)
 }
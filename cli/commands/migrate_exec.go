@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	mig "github.com/hasura/graphql-engine/cli/migrate/cmd"
+	log "github.com/sirupsen/logrus"
+)
+
+const migrateExecCmdExamples = `  # Run the SQL in fix.sql on the database and save it as a migration:
+  hasura migrate exec --database-name default --sql-file fix.sql --save-as fix_user_emails
+
+  # Also record the new migration as applied on the database:
+  hasura migrate exec --database-name default --sql-file fix.sql --save-as fix_user_emails --mark-applied
+`
+
+func newMigrateExecCmd(ec *cli.ExecutionContext) *cobra.Command {
+	opts := &migrateExecOptions{
+		EC: ec,
+	}
+
+	migrateExecCmd := &cobra.Command{
+		Use:          "exec",
+		Short:        "Run SQL on a database and save it as a migration",
+		Long:         "Run the SQL statements from a file on a database and, if they succeed, create a migration containing them as the up migration",
+		Example:      migrateExecCmdExamples,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateConfigV3Flags(cmd, ec)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Source = ec.Source
+			opts.EC.Spin("Executing SQL...")
+			version, err := opts.run()
+			opts.EC.Spinner.Stop()
+			if err != nil {
+				return err
+			}
+			opts.EC.Logger.WithFields(log.Fields{
+				"version": version,
+				"name":    opts.name,
+			}).Info("SQL executed and migration files created")
+			return nil
+		},
+	}
+	f := migrateExecCmd.Flags()
+	f.StringVar(&opts.sqlFile, "sql-file", "", "path to an SQL file which contains the SQL statements to be executed")
+	f.StringVar(&opts.name, "save-as", "", "name of the migration to be created from the executed SQL")
+	f.BoolVar(&opts.markApplied, "mark-applied", false, "record the created migration as applied on the database")
+
+	migrateExecCmd.MarkFlagRequired("sql-file")
+	migrateExecCmd.MarkFlagRequired("save-as")
+	migrateExecCmd.MarkFlagFilename("sql-file")
+
+	return migrateExecCmd
+}
+
+type migrateExecOptions struct {
+	EC *cli.ExecutionContext
+
+	sqlFile     string
+	name        string
+	markApplied bool
+	Source      cli.Source
+}
+
+func (o *migrateExecOptions) run() (version int64, err error) {
+	sql, err := ioutil.ReadFile(o.sqlFile)
+	if err != nil {
+		return 0, errors.Wrap(err, "cannot read sql file")
+	}
+	if err := o.execSQL(string(sql)); err != nil {
+		return 0, errors.Wrap(err, "executing sql")
+	}
+
+	timestamp := getTime()
+	createOptions := mig.New(timestamp, o.name, filepath.Join(o.EC.MigrationDir, o.Source.Name))
+	if err := createOptions.SetSQLUp(string(sql)); err != nil {
+		return 0, errors.Wrap(err, "up migration with SQL string could not be created")
+	}
+	defer func() {
+		if err != nil {
+			createOptions.Delete()
+		}
+	}()
+	err = createOptions.Create()
+	if err != nil {
+		return 0, errors.Wrap(err, "error creating migration files")
+	}
+
+	if o.markApplied {
+		store := cli.GetMigrationsStateStore(o.EC)
+		if err = store.PrepareMigrationsStateStore(); err != nil {
+			return 0, errors.Wrap(err, "preparing migrations state store")
+		}
+		if err = store.InsertVersion(o.Source.Name, timestamp); err != nil {
+			return 0, errors.Wrap(err, "marking migration as applied")
+		}
+	}
+	return timestamp, nil
+}
+
+func (o *migrateExecOptions) execSQL(sql string) error {
+	switch o.Source.Kind {
+	case hasura.SourceKindPG:
+		input := hasura.PGRunSQLInput{SQL: sql, Source: o.Source.Name}
+		if !o.EC.HasMetadataV3 {
+			_, err := o.EC.APIClient.V1Query.PGRunSQL(input)
+			return err
+		}
+		_, err := o.EC.APIClient.V2Query.PGRunSQL(input)
+		return err
+	case hasura.SourceKindMSSQL:
+		_, err := o.EC.APIClient.V2Query.MSSQLRunSQL(hasura.MSSQLRunSQLInput{SQL: sql, Source: o.Source.Name})
+		return err
+	}
+	return fmt.Errorf("executing sql on database of kind %s is not supported", o.Source.Kind)
+}
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hasura/graphql-engine/cli/internal/testutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	. "github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("migrate_exec", func() {
+
+	var dirName string
+	var session *Session
+	var teardown func()
+	BeforeEach(func() {
+		dirName = testutil.RandDirName()
+		hgeEndPort, teardownHGE := testutil.StartHasura(GinkgoT(), testutil.HasuraVersion)
+		hgeEndpoint := fmt.Sprintf("http://0.0.0.0:%s", hgeEndPort)
+		testutil.RunCommandAndSucceed(testutil.CmdOpts{
+			Args: []string{"init", dirName},
+		})
+		editEndpointInConfig(filepath.Join(dirName, defaultConfigFilename), hgeEndpoint)
+
+		teardown = func() {
+			session.Kill()
+			os.RemoveAll(dirName)
+			teardownHGE()
+		}
+	})
+
+	AfterEach(func() {
+		teardown()
+	})
+
+	Context("migrate exec test", func() {
+		It("should execute sql and create a migration inside default database", func() {
+			sqlFile := filepath.Join(dirName, "fix.sql")
+			Expect(ioutil.WriteFile(sqlFile, []byte(`create schema "testing";`), 0644)).To(Succeed())
+			session = testutil.Hasura(testutil.CmdOpts{
+				Args:             []string{"migrate", "exec", "--sql-file", "fix.sql", "--save-as", "schema_creation", "--mark-applied", "--database-name", "default"},
+				WorkingDirectory: dirName,
+			})
+			wantKeywordList := []string{
+				".*SQL executed and migration files created*.",
+				".*schema_creation*.",
+				".*version*.",
+			}
+
+			for _, keyword := range wantKeywordList {
+				Eventually(session.Err, 60*60).Should(Say(keyword))
+			}
+			Eventually(session, 60*60).Should(Exit(0))
+		})
+	})
+})
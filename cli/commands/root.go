@@ -68,6 +68,7 @@ func init() {
 		NewPluginsCmd(ec),
 		NewVersionCmd(ec),
 		NewScriptsCmd(ec),
+		NewAuthCmd(ec),
 		NewDocsCmd(ec),
 		NewCompletionCmd(ec),
 		NewUpdateCLICmd(ec),
@@ -110,6 +111,20 @@ func NewDefaultHasuraCommandWithArgs(pluginHandler PluginHandler, args []string,
 	return cmd
 }
 
+// ExitError is returned by commands which have to exit with a specific exit code
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
 // Execute executes the command and returns the error
 func Execute() error {
 	err := ec.Prepare()
@@ -15,6 +15,7 @@ func NewScriptsCmd(ec *cli.ExecutionContext) *cobra.Command {
 	scriptsCmd.AddCommand(
 		newScriptsUpdateConfigV2Cmd(ec),
 		newUpdateMultipleSources(ec),
+		newScriptsVerifyProjectV3Cmd(ec),
 	)
 	return scriptsCmd
 }
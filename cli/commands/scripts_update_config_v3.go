@@ -1,7 +1,11 @@
 package commands
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/hasura/graphql-engine/cli/internal/scripts"
+	"github.com/hasura/graphql-engine/cli/internal/statestore"
 	"github.com/hasura/graphql-engine/cli/util"
 	"github.com/spf13/afero"
 
@@ -12,12 +16,26 @@ import (
 
 func newUpdateMultipleSources(ec *cli.ExecutionContext) *cobra.Command {
 	v := viper.New()
+	var compactMigrationState, checkOnly, offline, reconcile, strict, normalizeLineEndings, reloadMetadata, stopIfServerActive, smokeTest, checkDatabasesHealth, allowInconsistentMetadata, dryRun, rollback, noRollback, keepBackup, staged, forceStateCopy, checkGlobalVersions, checkSeedSchemas, showStateDiff, nonInteractive, includeLegacyTimestamps bool
+	var metadataSnapshot, restoreMetadata, outputFormat, targetDatabase, stateStore, decisionLogPath, emitAPICalls, seedConflicts, migrationConflicts, databaseMapping, label string
+	var settingsAllowlist, databasePrefixes []string
+	var confirmationThreshold, exportConcurrency int
+	var timeout time.Duration
 	cmd := &cobra.Command{
 		Use:   "update-project-v3",
 		Short: "Update the Hasura project from config v2 to v3",
 		Long: `
 Convenience script used to upgrade your CLI project to use config v3.
-Note that this process is completely independent from your Hasura Graphql Engine server update process`,
+Note that this process is completely independent from your Hasura Graphql Engine server update process
+
+When the server is not reachable, the project directory can be updated using --offline.
+Once the server is reachable again, the update has to be completed using --reconcile
+
+Before the update, the migrations, seeds and metadata directories and the config file are backed up.
+When the update fails, the project directory is restored from the backup.
+With --no-rollback it is left as it is, and can be restored later using --rollback.
+With --staged the update is made on a copy of the project directory instead, which replaces
+the project directory once the update is complete`,
 		SilenceUsage: true,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			ec.Viper = v
@@ -25,9 +43,48 @@ Note that this process is completely independent from your Hasura Graphql Engine
 			if err != nil {
 				return err
 			}
+			if offline || rollback {
+				return ec.ValidateWithoutServer()
+			}
 			return ec.Validate()
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if offline && reconcile {
+				return fmt.Errorf("--offline and --reconcile cannot be used together")
+			}
+			if offline && reloadMetadata {
+				return fmt.Errorf("--offline and --reload-metadata cannot be used together")
+			}
+			if offline && smokeTest {
+				return fmt.Errorf("--offline and --smoke-test cannot be used together")
+			}
+			if offline && checkDatabasesHealth {
+				return fmt.Errorf("--offline and --check-databases-health cannot be used together")
+			}
+			if offline && len(emitAPICalls) > 0 {
+				return fmt.Errorf("--offline and --emit-api-calls cannot be used together")
+			}
+			if dryRun && len(emitAPICalls) > 0 {
+				return fmt.Errorf("--dry-run and --emit-api-calls cannot be used together")
+			}
+			if rollback && (reconcile || offline || dryRun) {
+				return fmt.Errorf("--rollback cannot be used with --reconcile, --offline or --dry-run")
+			}
+			if staged && (noRollback || keepBackup) {
+				return fmt.Errorf("--staged cannot be used with --no-rollback or --keep-backup")
+			}
+			if outputFormat == scripts.OutputFormatJSON && !nonInteractive {
+				return fmt.Errorf("--output json requires --non-interactive, so that prompts are not written to the output")
+			}
+			if len(restoreMetadata) > 0 {
+				return scripts.RestoreServerMetadata(ec, restoreMetadata)
+			}
+			if rollback {
+				return scripts.RollbackUpdateProjectV3(afero.NewOsFs(), ec.ExecutionDirectory)
+			}
+			if reconcile {
+				return scripts.ReconcileOfflineUpdate(ec, afero.NewOsFs(), ec.ExecutionDirectory, forceStateCopy)
+			}
 			opts := scripts.UpgradeToMuUpgradeProjectToMultipleSourcesOpts{
 				Fs:                         afero.NewOsFs(),
 				ProjectDirectory:           ec.ExecutionDirectory,
@@ -35,12 +92,85 @@ Note that this process is completely independent from your Hasura Graphql Engine
 				SeedsAbsDirectoryPath:      ec.SeedsDirectory,
 				Logger:                     ec.Logger,
 				EC:                         ec,
+				CompactMigrationState:      compactMigrationState,
+				CheckOnly:                  checkOnly,
+				StateStore:                 stateStore,
+				ConfirmationThreshold:      confirmationThreshold,
+				Offline:                    offline,
+				StrictSeedValidation:       strict,
+				Timeout:                    timeout,
+				NormalizeLineEndings:       normalizeLineEndings,
+				ReloadMetadata:             reloadMetadata,
+				DecisionLogPath:            decisionLogPath,
+				StopIfServerActive:         stopIfServerActive,
+				EmitAPICalls:               emitAPICalls,
+				ExportConcurrency:          exportConcurrency,
+				SmokeTest:                  smokeTest,
+				CheckDatabasesHealth:       checkDatabasesHealth,
+				SeedConflictStrategy:       seedConflicts,
+				MigrationConflictStrategy:  migrationConflicts,
+				Label:                      label,
+				DryRun:                     dryRun,
+				DatabaseMappingPath:        databaseMapping,
+				DatabasePrefixes:           databasePrefixes,
+				NoRollback:                 noRollback,
+				KeepBackup:                 keepBackup,
+				Staged:                     staged,
+				ForceStateCopy:             forceStateCopy,
+				CheckGlobalVersions:        checkGlobalVersions,
+				CheckSeedSchemas:           checkSeedSchemas,
+				ShowStateDiff:              showStateDiff,
+				IncludeLegacyTimestamps:    includeLegacyTimestamps,
+				TargetDatabase:             targetDatabase,
+				NonInteractive:             nonInteractive,
+				OutputFormat:               outputFormat,
+				MetadataSnapshotPath:       metadataSnapshot,
+				SettingsAllowlist:          settingsAllowlist,
+				AllowInconsistentMetadata:  allowInconsistentMetadata,
 			}
 			return scripts.UpdateProjectV3(opts)
 		},
 	}
 
 	f := cmd.Flags()
+	f.StringVar(&targetDatabase, "database-name", "", "name of the database the migrations and seeds of the project belong to, asked for when not set")
+	f.BoolVar(&nonInteractive, "non-interactive", false, "never ask for input: skip confirmations, use the only database on the server when --database-name is not set and fail when a decision has to be made")
+	f.StringVarP(&outputFormat, "output", "o", scripts.OutputFormatText, "output format: text logs the progress of the update, json only writes a summary of the update once it is complete. Allowed values: text, json")
+	f.BoolVar(&compactMigrationState, "compact-migration-state", false, "after copying state, remove versions which no longer have a migration directory (the latest applied version is always kept)")
+	f.BoolVar(&checkOnly, "check-only", false, "only run the checks required before the update and print a report of them as JSON, without making any changes")
+	f.BoolVar(&forceStateCopy, "force-state-copy", false, "copy state to catalog state even when it was already copied by an earlier run of the update or of --reconcile")
+	f.StringVar(&stateStore, "state-store", statestore.StateStoreCatalog, "name of the state store to which migrations and settings state is copied")
+	f.BoolVar(&offline, "offline", false, "only update the project directory and config without contacting the server, the update has to be completed later using --reconcile")
+	f.BoolVar(&reconcile, "reconcile", false, "complete an update done using --offline, copying state and exporting metadata from the server")
+	f.BoolVar(&strict, "strict", false, "abort the update when a seed file looks malformed, instead of only warning about it")
+	f.BoolVar(&normalizeLineEndings, "normalize-line-endings", false, "convert CRLF line endings of SQL migrations and seeds to LF while moving them")
+	f.BoolVar(&reloadMetadata, "reload-metadata", false, "reload metadata on the server after the update and fail if it reports inconsistencies")
+	f.BoolVar(&stopIfServerActive, "stop-if-server-active", false, "abort the update when the server appears to be applying migrations, instead of only warning about it")
+	f.StringVar(&emitAPICalls, "emit-api-calls", "", "print the API calls which would be made to the server to copy state and export metadata as json or curl, without updating the project")
+	f.BoolVar(&allowInconsistentMetadata, "allow-inconsistent-metadata", false, "continue the update when metadata on the server is inconsistent, only warning about the inconsistent objects")
+	f.BoolVar(&includeLegacyTimestamps, "include-legacy-timestamps", false, "also move migration directories named using the 10 digit timestamp of older CLI versions, only 13 digit timestamps are moved otherwise")
+	f.BoolVar(&checkGlobalVersions, "check-global-versions", false, "once migrations are moved, warn about migration versions used by more than one database")
+	f.BoolVar(&checkSeedSchemas, "check-seed-schemas", false, "once seeds are moved, warn about seed files referring to schemas which do not exist in the database they were moved to")
+	f.BoolVar(&showStateDiff, "show-state-diff", false, "log the changes made to catalog state by the state copy, eg: migration versions and settings which were added")
+	f.StringVar(&seedConflicts, "seed-conflicts", scripts.SeedConflictFail, "what to do with seed files having the same name as a file in the target seeds directory: fail, rename or overwrite")
+	f.StringVar(&migrationConflicts, "migration-conflicts", scripts.MigrationConflictPrompt, "what to do with migrations which already exist in the target migrations directory: prompt, overwrite, skip or abort")
+	f.StringVar(&label, "label", "", "label recorded in catalog state along with the state copy, eg: \"updated by CI run #1234\"")
+	f.StringSliceVar(&settingsAllowlist, "settings-allowlist", nil, "names of the CLI settings to copy along with migrations state, all settings are copied when not set")
+	f.StringVar(&metadataSnapshot, "snapshot-metadata", "", "path of a file to which metadata on the server is saved before the update, so that it can be restored using --restore-metadata")
+	f.StringVar(&restoreMetadata, "restore-metadata", "", "replace metadata on the server with a snapshot saved using --snapshot-metadata, overwriting metadata on the server")
+	f.BoolVar(&rollback, "rollback", false, "restore the project directory as it was before an update which did not complete")
+	f.BoolVar(&noRollback, "no-rollback", false, "leave the project directory as it is when the update fails, instead of restoring it from the backup made before the update")
+	f.BoolVar(&keepBackup, "keep-backup", false, "keep the backup of the project directory made before the update in .hasura-backup-<timestamp> once the update is complete")
+	f.BoolVar(&staged, "staged", false, "update a copy of the project directory, made using a copy-on-write clone when the filesystem supports it, and replace the project directory with it once the update is complete")
+	f.StringVar(&databaseMapping, "database-mapping", "", "path of a YAML file assigning migrations and seeds to databases other than the target database by name, eg: [{database: orders, migrations: [\"*_orders_*\"], seeds: [\"orders*.sql\"]}]")
+	f.StringSliceVar(&databasePrefixes, "database-prefix", nil, "assign migrations whose name without the version starts with a prefix and seeds whose name starts with it to a database, eg: --database-prefix default=users_,analytics=events_")
+	f.BoolVar(&dryRun, "dry-run", false, "log the changes which would be made to the project directory and the server without making them")
+	f.BoolVar(&smokeTest, "smoke-test", false, "run an introspection query after the update to check that the server can build a GraphQL schema")
+	f.BoolVar(&checkDatabasesHealth, "check-databases-health", false, "check that the server can run a query on each of the databases after the update, checking several databases concurrently")
+	f.StringVar(&decisionLogPath, "decision-log", "", "path of a file to which the decisions made during the update are written as JSON")
+	f.DurationVar(&timeout, "timeout", 0, "maximum time the update can take once confirmed, eg: 10m (0 for no limit)")
+	f.IntVar(&exportConcurrency, "export-concurrency", 1, "number of metadata objects to export concurrently once the project is updated")
+	f.IntVar(&confirmationThreshold, "confirmation-threshold", 100, "number of migrations above which the name of the database has to be typed to confirm the update (0 to disable)")
 
 	f.String("endpoint", "", "http(s) endpoint for Hasura GraphQL engine")
 	f.String("admin-secret", "", "admin secret for Hasura GraphQL engine")
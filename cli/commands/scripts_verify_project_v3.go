@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/hasura/graphql-engine/cli/internal/scripts"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newScriptsVerifyProjectV3Cmd(ec *cli.ExecutionContext) *cobra.Command {
+	v := viper.New()
+	var outputFormat string
+	cmd := &cobra.Command{
+		Use:   "verify-project-v3",
+		Short: "Verify the layout of a config v3 project without the server",
+		Long: `Verify that the project is using config v3, that migrations and seeds are in the directories
+of the databases of the project (read from the metadata directory) and that the metadata
+directory has no files of config v2 projects. All problems found are listed`,
+		Example: `  # Verify the project after it was updated to config v3
+  hasura scripts verify-project-v3
+
+  # List the problems found as JSON
+  hasura scripts verify-project-v3 --output json`,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			ec.Viper = v
+			err := ec.Prepare()
+			if err != nil {
+				return err
+			}
+			return ec.ValidateWithoutServer()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != scripts.OutputFormatText && outputFormat != scripts.OutputFormatJSON {
+				return fmt.Errorf("unknown output format %q, has to be %s or %s", outputFormat, scripts.OutputFormatText, scripts.OutputFormatJSON)
+			}
+			problems, err := scripts.VerifyProjectV3(afero.NewOsFs(), ec.Config.Version, ec.MigrationDir, ec.SeedsDirectory, ec.MetadataDir)
+			if err != nil {
+				return err
+			}
+			if outputFormat == scripts.OutputFormatJSON {
+				if problems == nil {
+					problems = []scripts.ValidationProblem{}
+				}
+				b, err := json.MarshalIndent(problems, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(b))
+			} else {
+				for _, problem := range problems {
+					ec.Logger.Warn(problem.Message)
+				}
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("%d problem(s) found in the project", len(problems))
+			}
+			ec.Logger.Info("project is a valid config v3 project")
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&outputFormat, "output", "o", scripts.OutputFormatText, "format in which problems are listed, text or json")
+	return cmd
+}
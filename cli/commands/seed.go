@@ -34,7 +34,10 @@ func NewSeedCmd(ec *cli.ExecutionContext) *cobra.Command {
 			}
 			if ec.Config.Version >= cli.V3 {
 				if !cmd.Flags().Changed("database-name") {
-					return errors.New("--database-name flag is required")
+					if len(ec.Config.DefaultSource) == 0 {
+						return errors.New("--database-name flag is required")
+					}
+					ec.Source.Name = ec.Config.DefaultSource
 				}
 				sourceKind, err := metadatautil.GetSourceKind(ec.APIClient.V1Metadata.ExportMetadata, ec.Source.Name)
 				if err != nil {
@@ -65,7 +68,7 @@ func NewSeedCmd(ec *cli.ExecutionContext) *cobra.Command {
 	)
 
 	f := seedCmd.PersistentFlags()
-	f.StringVar(&ec.Source.Name, "database-name", "", "database on which operation should be applied")
+	f.StringVar(&ec.Source.Name, "database-name", "", "database on which operation should be applied (default: default_source from config)")
 
 	f.String("endpoint", "", "http(s) endpoint for Hasura GraphQL engine")
 	f.String("admin-secret", "", "admin secret for Hasura GraphQL engine")
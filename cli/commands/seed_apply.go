@@ -1,10 +1,15 @@
 package commands
 
 import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 
 	"github.com/hasura/graphql-engine/cli"
+	"github.com/hasura/graphql-engine/cli/internal/scripts"
 	"github.com/hasura/graphql-engine/cli/seed"
 )
 
@@ -15,6 +20,8 @@ type SeedApplyOptions struct {
 	// seed file to apply
 	FileNames []string
 	Source    cli.Source
+	// VerifyLockFile when set fails if seed files were changed since the seeds lockfile was written
+	VerifyLockFile bool
 }
 
 func newSeedApplyCmd(ec *cli.ExecutionContext) *cobra.Command {
@@ -28,7 +35,10 @@ func newSeedApplyCmd(ec *cli.ExecutionContext) *cobra.Command {
   hasura seed apply
 
   # Apply only a particular file:
-  hasura seed apply --file seeds/1234_add_some_seed_data.sql`,
+  hasura seed apply --file seeds/1234_add_some_seed_data.sql
+
+  # Apply seeds only if they are unchanged since the seeds lockfile was written:
+  hasura seed apply --verify-lockfile`,
 		SilenceUsage: false,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			return ec.Validate()
@@ -47,10 +57,35 @@ func newSeedApplyCmd(ec *cli.ExecutionContext) *cobra.Command {
 		},
 	}
 	cmd.Flags().StringArrayVarP(&opts.FileNames, "file", "f", []string{}, "seed file to apply")
+	cmd.Flags().BoolVar(&opts.VerifyLockFile, "verify-lockfile", false, "fail if seed files were changed since the seeds lockfile ("+seed.LockFileName+") was written")
 	return cmd
 }
 
 func (o *SeedApplyOptions) Run() error {
 	fs := afero.NewOsFs()
-	return o.Driver.ApplySeedsToDatabase(fs, o.EC.SeedsDirectory, o.FileNames, o.EC.Source)
+	if o.VerifyLockFile {
+		seedsDirectory := filepath.Join(o.EC.SeedsDirectory, o.EC.Source.Name)
+		changed, err := seed.VerifyLockFile(fs, seedsDirectory)
+		if err != nil {
+			return err
+		}
+		if len(changed) > 0 {
+			return fmt.Errorf("seed files changed since the seeds lockfile was written: %s", strings.Join(changed, ", "))
+		}
+	}
+	fileNames := o.FileNames
+	if o.EC.Config.Version.IsAtLeast(cli.V3) && len(fileNames) > 0 {
+		// seed files can be named as they were before the project was updated to config v3
+		info, err := scripts.ReadUpgradeInfo(fs, o.EC.ExecutionDirectory)
+		if err != nil {
+			return err
+		}
+		if info != nil {
+			fileNames, err = info.ResolveSeedFiles(fs, o.EC.SeedsDirectory, fileNames, o.EC.Source.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return o.Driver.ApplySeedsToDatabase(fs, o.EC.SeedsDirectory, fileNames, o.EC.Source)
 }
@@ -33,12 +33,12 @@ func (c *Client) GetIntrospectionSchema() (hasura.IntrospectionSchema, error) {
 		Errors *json.RawMessage `json:"errors"`
 	}
 	response, err := c.send(query, responseBody)
-	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf(" %s: %d \n%s", opName, response.StatusCode, responseBody.String())
-	}
 	if err != nil {
 		return nil, err
 	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(" %s: %d \n%s", opName, response.StatusCode, responseBody.String())
+	}
 	err = json.NewDecoder(responseBody).Decode(&respBody)
 	if err != nil {
 		return nil, err
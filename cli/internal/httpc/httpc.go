@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -22,9 +24,43 @@ type Client struct {
 	BaseURL   *url.URL
 	UserAgent string
 	headers   map[string]string
+	retry     RetryOptions
 }
 
-func New(httpClient *http.Client, baseUrl string, headers map[string]string) (*Client, error) {
+// RetryOptions configures the retries of requests which fail with a
+// connection error or a retryable status code. The delay before each retry is
+// chosen at random (jitter) up to BaseDelay * 2^attempt, capped by MaxDelay
+type RetryOptions struct {
+	// MaxRetries is the number of times a request is retried, 0 disables retries
+	MaxRetries int
+	BaseDelay  time.Duration
+	// MaxDelay caps the delay before a retry, no cap when 0
+	MaxDelay time.Duration
+	// StatusCodes are the retryable status codes, DefaultRetryableStatusCodes when empty
+	StatusCodes []int
+	// Methods are the HTTP methods of the requests which are retried, idempotent
+	// methods when empty. POST requests of APIs known to be idempotent, eg: exporting
+	// metadata, can be retried by adding POST
+	Methods []string
+}
+
+// DefaultRetryableStatusCodes are the status codes of responses retried by default
+var DefaultRetryableStatusCodes = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+var idempotentMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete}
+
+// Option configures a Client created using New
+type Option func(*Client)
+
+// WithRetries makes the client retry requests as configured by opts,
+// clients do not retry requests by default
+func WithRetries(opts RetryOptions) Option {
+	return func(c *Client) {
+		c.retry = opts
+	}
+}
+
+func New(httpClient *http.Client, baseUrl string, headers map[string]string, opts ...Option) (*Client, error) {
 	u, err := url.ParseRequestURI(baseUrl)
 	if err != nil {
 		return nil, err
@@ -38,6 +74,9 @@ func New(httpClient *http.Client, baseUrl string, headers map[string]string) (*C
 		UserAgent: "hasura-cli",
 		headers:   headers,
 	}
+	for _, opt := range opts {
+		opt(client)
+	}
 	return client, nil
 }
 
@@ -81,7 +120,7 @@ func (c *Client) BareDo(ctx context.Context, req *http.Request) (*Response, erro
 	}
 	req = req.WithContext(ctx)
 
-	resp, err := c.client.Do(req)
+	resp, err := c.doWithRetries(ctx, req)
 	if err != nil {
 		// If we got an error, and the context has been canceled,
 		// the context's error is probably more useful.
@@ -98,6 +137,83 @@ func (c *Client) BareDo(ctx context.Context, req *http.Request) (*Response, erro
 	return response, err
 }
 
+// doWithRetries sends req, retrying it as configured by the retry options of
+// the client. Requests with a body are only retried when the body can be
+// read again (req.GetBody is set, as done by NewRequest)
+func (c *Client) doWithRetries(ctx context.Context, req *http.Request) (*http.Response, error) {
+	retries := c.retry.MaxRetries
+	if !c.isRetryableRequest(req) {
+		retries = 0
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err := c.client.Do(req)
+		if attempt >= retries || ctx.Err() != nil || !c.isRetryableResponse(resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			// the response is discarded, so that the connection can be reused
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.retryDelay(attempt)):
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+func (c *Client) isRetryableRequest(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	methods := c.retry.Methods
+	if len(methods) == 0 {
+		methods = idempotentMethods
+	}
+	for _, method := range methods {
+		if strings.EqualFold(method, req.Method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) isRetryableResponse(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	statusCodes := c.retry.StatusCodes
+	if len(statusCodes) == 0 {
+		statusCodes = DefaultRetryableStatusCodes
+	}
+	for _, code := range statusCodes {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// retryDelay returns a random delay of up to BaseDelay * 2^attempt, capped by MaxDelay
+func (c *Client) retryDelay(attempt int) time.Duration {
+	delay := c.retry.BaseDelay << uint(attempt)
+	if delay <= 0 || (c.retry.MaxDelay > 0 && delay > c.retry.MaxDelay) {
+		delay = c.retry.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay)) + 1)
+}
+
 type Response struct {
 	*http.Response
 }
@@ -0,0 +1,74 @@
+package httpc
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_retries(t *testing.T) {
+	var requests int
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		opts         []Option
+		method       string
+		wantStatus   int
+		wantRequests int
+	}{
+		{"does not retry by default", nil, http.MethodGet, http.StatusServiceUnavailable, 1},
+		{"retries idempotent requests", []Option{WithRetries(RetryOptions{MaxRetries: 3, BaseDelay: time.Millisecond})}, http.MethodGet, http.StatusOK, 3},
+		{"gives up after max retries", []Option{WithRetries(RetryOptions{MaxRetries: 1, BaseDelay: time.Millisecond})}, http.MethodGet, http.StatusServiceUnavailable, 2},
+		{"does not retry post requests by default", []Option{WithRetries(RetryOptions{MaxRetries: 3})}, http.MethodPost, http.StatusServiceUnavailable, 1},
+		{"retries post requests when allowed, replaying the body", []Option{WithRetries(RetryOptions{MaxRetries: 3, Methods: []string{http.MethodPost}})}, http.MethodPost, http.StatusOK, 3},
+		{"retries only the configured status codes", []Option{WithRetries(RetryOptions{MaxRetries: 3, StatusCodes: []int{http.StatusTooManyRequests}})}, http.MethodGet, http.StatusServiceUnavailable, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests, bodies = 0, nil
+			c, err := New(nil, server.URL+"/", nil, tt.opts...)
+			assert.NoError(t, err)
+			var body interface{}
+			if tt.method == http.MethodPost {
+				body = map[string]string{"type": "export_metadata"}
+			}
+			req, err := c.NewRequest(tt.method, "v1/metadata", body)
+			assert.NoError(t, err)
+			resp, err := c.BareDo(context.Background(), req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Equal(t, tt.wantRequests, requests)
+			if body != nil {
+				for _, b := range bodies {
+					assert.JSONEq(t, `{"type": "export_metadata"}`, b)
+				}
+			}
+		})
+	}
+}
+
+func TestClient_retryDelay(t *testing.T) {
+	c := &Client{retry: RetryOptions{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}}
+	for attempt := 0; attempt < 10; attempt++ {
+		delay := c.retryDelay(attempt)
+		assert.True(t, delay > 0 && delay <= 50*time.Millisecond, "attempt %d: %s", attempt, delay)
+	}
+	assert.Equal(t, time.Duration(0), (&Client{}).retryDelay(0))
+}
@@ -5,8 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/hasura/graphql-engine/cli"
 
@@ -26,10 +30,15 @@ type Handler struct {
 	v2MetadataOps hasura.V2CommonMetadataOperations
 
 	logger *logrus.Logger
+	// filesystem to which metadata files are written
+	fs afero.Fs
+	// number of objects exported concurrently, objects are
+	// exported one after the other when <= 1
+	exportConcurrency int
 }
 
 func NewHandler(objects Objects, v1MetadataOps hasura.CommonMetadataOperations, v2MetadataOps hasura.V2CommonMetadataOperations, logger *logrus.Logger) *Handler {
-	return &Handler{objects, v1MetadataOps, v2MetadataOps, logger}
+	return &Handler{objects: objects, v1MetadataOps: v1MetadataOps, v2MetadataOps: v2MetadataOps, logger: logger, fs: afero.NewOsFs()}
 }
 
 func NewHandlerFromEC(ec *cli.ExecutionContext) *Handler {
@@ -41,23 +50,77 @@ func (h *Handler) SetMetadataObjects(objects Objects) {
 	h.objects = objects
 }
 
-// WriteMetadata writes the files in the metadata folder
+// SetFs sets the filesystem to which WriteMetadata writes files, defaults to the OS filesystem
+func (h *Handler) SetFs(fs afero.Fs) {
+	h.fs = fs
+}
+
+// SetExportConcurrency sets the number of metadata objects exported concurrently.
+// Metadata is fetched from the server once, so this only parallelizes the
+// conversion of metadata to files
+func (h *Handler) SetExportConcurrency(n int) {
+	h.exportConcurrency = n
+}
+
+// TempFileSuffix is the suffix of the temporary files written by WriteMetadata,
+// which are renamed to the metadata files once they are written
+const TempFileSuffix = ".hasura-tmp"
+
+// WriteMetadata writes the files in the metadata folder. Each file is written
+// to a temporary file which is then renamed, so that an interrupted write does
+// not leave a partially written metadata file
 func (h *Handler) WriteMetadata(files map[string][]byte) error {
 	for name, content := range files {
-		fs := afero.NewOsFs()
-		if err := fs.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+		if err := h.fs.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
 			return err
 		}
-		err := afero.WriteFile(fs, name, content, 0644)
+		tmp := name + TempFileSuffix
+		err := afero.WriteFile(h.fs, tmp, content, 0644)
+		if err == nil {
+			err = h.fs.Rename(tmp, name)
+		}
 		if err != nil {
+			_ = h.fs.Remove(tmp)
 			return errors.Wrapf(err, "creating metadata file %s failed", name)
 		}
 	}
 	return nil
 }
 
+// VerifyMetadataWrite removes the temporary files left in dir by an interrupted
+// WriteMetadata, returning their paths, and checks that each of files exists
+func (h *Handler) VerifyMetadataWrite(dir string, files map[string][]byte) ([]string, error) {
+	var removed []string
+	err := afero.Walk(h.fs, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, TempFileSuffix) {
+			return nil
+		}
+		if err := h.fs.Remove(path); err != nil {
+			return errors.Wrapf(err, "removing temporary metadata file %s", path)
+		}
+		removed = append(removed, path)
+		return nil
+	})
+	if err != nil {
+		return removed, err
+	}
+	var missing []string
+	for name := range files {
+		if _, err := h.fs.Stat(name); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return removed, fmt.Errorf("metadata files are missing: %s", strings.Join(missing, ", "))
+	}
+	return removed, nil
+}
+
 func (h *Handler) ExportMetadata() (map[string][]byte, error) {
-	metadataFiles := make(map[string][]byte)
 	var resp io.Reader
 	var err error
 	resp, err = h.v1MetadataOps.ExportMetadata()
@@ -69,18 +132,200 @@ func (h *Handler) ExportMetadata() (map[string][]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	for _, object := range h.objects {
+	sortSources(c)
+	return h.exportObjects(func(object Object) (map[string][]byte, error) {
 		files, err := object.Export(c)
 		if err != nil {
 			return nil, errors.Wrap(err, fmt.Sprintf("cannot export %s from metadata", object.Name()))
 		}
-		for fileName, content := range files {
+		return files, nil
+	})
+}
+
+// ExportErrors are the errors of the objects which failed to export
+type ExportErrors []error
+
+func (e ExportErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// exportObjects calls export for each of the metadata objects, using up to
+// exportConcurrency goroutines, and merges the files returned in the order of
+// the objects. When export fails for any of the objects, the errors of all
+// failed objects are returned as ExportErrors
+func (h *Handler) exportObjects(export func(object Object) (map[string][]byte, error)) (map[string][]byte, error) {
+	workers := h.exportConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]map[string][]byte, len(h.objects))
+	errs := make([]error, len(h.objects))
+	semaphore := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for idx, object := range h.objects {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(idx int, object Object) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			results[idx], errs[idx] = export(object)
+		}(idx, object)
+	}
+	wg.Wait()
+
+	var exportErrs ExportErrors
+	metadataFiles := make(map[string][]byte)
+	for idx := range h.objects {
+		if errs[idx] != nil {
+			exportErrs = append(exportErrs, errs[idx])
+			continue
+		}
+		for fileName, content := range results[idx] {
 			metadataFiles[fileName] = content
 		}
 	}
+	if len(exportErrs) > 0 {
+		return nil, exportErrs
+	}
 	return metadataFiles, nil
 }
 
+// ExportStagingDirectory is the directory, relative to the project directory, in
+// which files of a metadata export are staged until they are written to the project
+const ExportStagingDirectory = ".metadata-export"
+
+// ExportMetadataResumable works like ExportMetadata, but stages the metadata fetched
+// from the server and the files of each exported object in stagingDir. When resume
+// is set and stagingDir has files of an earlier export which did not complete,
+// metadata is not fetched again and objects which were already exported are skipped.
+// stagingDir should be removed once the returned files are written.
+func (h *Handler) ExportMetadataResumable(fs afero.Fs, stagingDir string, resume bool) (map[string][]byte, error) {
+	if !resume {
+		if err := fs.RemoveAll(stagingDir); err != nil {
+			return nil, errors.Wrap(err, "removing staged metadata export")
+		}
+	}
+	if err := fs.MkdirAll(stagingDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	stagedMetadata := filepath.Join(stagingDir, "metadata.json")
+	metadata, err := afero.ReadFile(fs, stagedMetadata)
+	if err == nil && resume {
+		h.logger.Debug("resuming metadata export using staged metadata")
+	} else {
+		resp, err := h.v1MetadataOps.ExportMetadata()
+		if err != nil {
+			return nil, err
+		}
+		metadata, err = ioutil.ReadAll(resp)
+		if err != nil {
+			return nil, err
+		}
+		if err := afero.WriteFile(fs, stagedMetadata, metadata, 0644); err != nil {
+			return nil, errors.Wrap(err, "staging exported metadata")
+		}
+	}
+	var c yaml.MapSlice
+	err = yaml.NewDecoder(bytes.NewReader(metadata)).Decode(&c)
+	if err != nil {
+		return nil, err
+	}
+	sortSources(c)
+
+	return h.exportObjects(func(object Object) (map[string][]byte, error) {
+		stagedObject := filepath.Join(stagingDir, object.Name()+".json")
+		var files map[string][]byte
+		if b, err := afero.ReadFile(fs, stagedObject); err == nil {
+			if err := json.Unmarshal(b, &files); err != nil {
+				return nil, errors.Wrapf(err, "reading staged export of %s", object.Name())
+			}
+			h.logger.Debugf("skipping export of %s, found staged export", object.Name())
+			return files, nil
+		}
+		files, err := object.Export(c)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("cannot export %s from metadata", object.Name()))
+		}
+		b, err := json.Marshal(files)
+		if err != nil {
+			return nil, err
+		}
+		if err := afero.WriteFile(fs, stagedObject, b, 0644); err != nil {
+			return nil, errors.Wrapf(err, "staging export of %s", object.Name())
+		}
+		return files, nil
+	})
+}
+
+// sortSources sorts sources in the metadata by name and the tables and functions
+// of each source by their qualified name, so that exports of the same metadata
+// are identical irrespective of the order in which the server lists them
+func sortSources(metadata yaml.MapSlice) {
+	sources, ok := mapSliceValue(metadata, "sources").([]interface{})
+	if !ok {
+		return
+	}
+	sortByKey(sources, func(source yaml.MapSlice) string {
+		return fmt.Sprint(mapSliceValue(source, "name"))
+	})
+	for _, source := range sources {
+		source, ok := source.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+		if tables, ok := mapSliceValue(source, "tables").([]interface{}); ok {
+			sortByKey(tables, func(table yaml.MapSlice) string {
+				return qualifiedName(mapSliceValue(table, "table"))
+			})
+		}
+		if functions, ok := mapSliceValue(source, "functions").([]interface{}); ok {
+			sortByKey(functions, func(function yaml.MapSlice) string {
+				return qualifiedName(mapSliceValue(function, "function"))
+			})
+		}
+	}
+}
+
+func sortByKey(items []interface{}, key func(yaml.MapSlice) string) {
+	keyOf := func(item interface{}) string {
+		if m, ok := item.(yaml.MapSlice); ok {
+			return key(m)
+		}
+		return ""
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return keyOf(items[i]) < keyOf(items[j])
+	})
+}
+
+// qualifiedName returns <schema>.<name> of a table or function, where the schema
+// is called dataset on some kinds of sources. name can also be a plain string
+func qualifiedName(name interface{}) string {
+	m, ok := name.(yaml.MapSlice)
+	if !ok {
+		return fmt.Sprint(name)
+	}
+	namespace := mapSliceValue(m, "schema")
+	if namespace == nil {
+		namespace = mapSliceValue(m, "dataset")
+	}
+	return fmt.Sprintf("%v.%v", namespace, mapSliceValue(m, "name"))
+}
+
+func mapSliceValue(m yaml.MapSlice, key string) interface{} {
+	for _, item := range m {
+		if k, ok := item.Key.(string); ok && k == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
 func (h *Handler) ResetMetadata() error {
 	var err error
 	_, err = h.v1MetadataOps.ClearMetadata()
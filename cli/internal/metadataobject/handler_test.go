@@ -2,7 +2,17 @@ package metadataobject
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
 	"testing"
+
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
 )
 
 func Test_inconsistentObject_GetName(t *testing.T) {
@@ -346,3 +356,140 @@ func Test_inconsistentObject_GetReason(t *testing.T) {
 		})
 	}
 }
+
+type countingMetadataOps struct {
+	hasura.CommonMetadataOperations
+	exports int
+}
+
+func (c *countingMetadataOps) ExportMetadata() (io.Reader, error) {
+	c.exports++
+	return strings.NewReader(`{"version": 3}`), nil
+}
+
+type flakyObject struct {
+	name     string
+	failures int
+	exports  int
+}
+
+func (f *flakyObject) Build(metadata *yaml.MapSlice) error { return nil }
+func (f *flakyObject) CreateFiles() error                  { return nil }
+func (f *flakyObject) Name() string                        { return f.name }
+func (f *flakyObject) Export(metadata yaml.MapSlice) (map[string][]byte, error) {
+	f.exports++
+	if f.failures > 0 {
+		f.failures--
+		return nil, fmt.Errorf("transient failure")
+	}
+	return map[string][]byte{f.name + ".yaml": []byte(f.name)}, nil
+}
+
+func TestHandler_ExportMetadataResumable(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	ops := &countingMetadataOps{}
+	first := &flakyObject{name: "first"}
+	second := &flakyObject{name: "second", failures: 1}
+	h := NewHandler(Objects{first, second}, ops, nil, logrus.New())
+
+	_, err := h.ExportMetadataResumable(fs, "staging", false)
+	assert.Error(t, err)
+
+	files, err := h.ExportMetadataResumable(fs, "staging", true)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"first.yaml": []byte("first"), "second.yaml": []byte("second")}, files)
+	assert.Equal(t, 1, ops.exports)
+	assert.Equal(t, 1, first.exports)
+	assert.Equal(t, 2, second.exports)
+
+	// without resume the export starts over
+	_, err = h.ExportMetadataResumable(fs, "staging", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, ops.exports)
+	assert.Equal(t, 2, first.exports)
+}
+
+func Test_sortSources(t *testing.T) {
+	var metadata yaml.MapSlice
+	assert.NoError(t, yaml.Unmarshal([]byte(`
+version: 3
+sources:
+- name: s2
+  tables:
+  - table: {schema: public, name: b}
+  - table: {schema: public, name: a}
+  - table: {dataset: analytics, name: c}
+- name: s1
+  tables: []
+  functions:
+  - function: {schema: public, name: z}
+  - function: {schema: app, name: y}
+`), &metadata))
+	sortSources(metadata)
+
+	var want yaml.MapSlice
+	assert.NoError(t, yaml.Unmarshal([]byte(`
+version: 3
+sources:
+- name: s1
+  tables: []
+  functions:
+  - function: {schema: app, name: y}
+  - function: {schema: public, name: z}
+- name: s2
+  tables:
+  - table: {dataset: analytics, name: c}
+  - table: {schema: public, name: a}
+  - table: {schema: public, name: b}
+`), &want))
+	assert.Equal(t, want, metadata)
+}
+
+func TestHandler_exportObjectsConcurrently(t *testing.T) {
+	objects := Objects{
+		&flakyObject{name: "a"},
+		&flakyObject{name: "b", failures: 1},
+		&flakyObject{name: "c"},
+		&flakyObject{name: "d", failures: 1},
+	}
+	h := NewHandler(objects, &countingMetadataOps{}, nil, logrus.New())
+	h.SetExportConcurrency(4)
+
+	_, err := h.ExportMetadata()
+	var exportErrs ExportErrors
+	assert.True(t, errors.As(err, &exportErrs))
+	assert.Len(t, exportErrs, 2)
+
+	files, err := h.ExportMetadata()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"a.yaml": []byte("a"),
+		"b.yaml": []byte("b"),
+		"c.yaml": []byte("c"),
+		"d.yaml": []byte("d"),
+	}, files)
+}
+
+func TestHandler_VerifyMetadataWrite(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	h := NewHandler(nil, nil, nil, logrus.New())
+	h.SetFs(fs)
+	files := map[string][]byte{
+		"metadata/version.yaml":             []byte("version: 3"),
+		"metadata/databases/databases.yaml": []byte("[]"),
+	}
+	assert.NoError(t, h.WriteMetadata(files))
+	// a temporary file left by an interrupted write
+	assert.NoError(t, afero.WriteFile(fs, "metadata/actions.yaml"+TempFileSuffix, []byte("actions: []"), 0644))
+
+	removed, err := h.VerifyMetadataWrite("metadata", files)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"metadata/actions.yaml" + TempFileSuffix}, removed)
+	exists, err := afero.Exists(fs, "metadata/actions.yaml"+TempFileSuffix)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	files["metadata/actions.yaml"] = []byte("actions: []")
+	_, err = h.VerifyMetadataWrite("metadata", files)
+	assert.EqualError(t, err, "metadata files are missing: metadata/actions.yaml")
+}
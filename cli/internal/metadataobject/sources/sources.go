@@ -279,6 +279,11 @@ func (t *SourceConfig) Export(metadata goyaml.MapSlice) (map[string][]byte, erro
 	return files, nil
 }
 
+// SourceDirectory returns the directory in which the metadata files of a source are stored
+func SourceDirectory(metadataDir, sourceName string) string {
+	return filepath.Join(metadataDir, sourcesDirectory, sourceName)
+}
+
 func (t *SourceConfig) Name() string {
 	return "sources"
 }
@@ -0,0 +1,208 @@
+package metadatautil
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeRemoved  ChangeType = "removed"
+	ChangeModified ChangeType = "modified"
+)
+
+// Change describes a single metadata object which differs between two
+// sets of metadata, eg: Object: "source default > table public.users > select permission user"
+type Change struct {
+	Type   ChangeType
+	Object string
+}
+
+// list of table fields which are compared item wise, mapped to the key
+// identifying an item and a human readable name of the item
+var tableListFields = []struct {
+	field, key, name string
+}{
+	{"object_relationships", "name", "object relationship"},
+	{"array_relationships", "name", "array relationship"},
+	{"remote_relationships", "name", "remote relationship"},
+	{"computed_fields", "name", "computed field"},
+	{"insert_permissions", "role", "insert permission"},
+	{"select_permissions", "role", "select permission"},
+	{"update_permissions", "role", "update permission"},
+	{"delete_permissions", "role", "delete permission"},
+	{"event_triggers", "name", "event trigger"},
+}
+
+// SemanticDiff compares two metadata objects (as decoded from JSON) and returns
+// the list of changed objects. Sources, tables and the permissions / relationships
+// on tables are compared individually, other top level objects are compared by name.
+func SemanticDiff(before, after map[string]interface{}) []Change {
+	var changes []Change
+	for _, key := range sortedKeys(before, after) {
+		b, a := before[key], after[key]
+		switch key {
+		case "version":
+			if !reflect.DeepEqual(b, a) {
+				changes = append(changes, Change{ChangeModified, "metadata version"})
+			}
+		case "sources":
+			changes = append(changes, diffList(asList(b), asList(a), nameKey("name"), "source", diffSource)...)
+		case "tables":
+			changes = append(changes, diffList(asList(b), asList(a), tableKey, "table", diffTable)...)
+		case "functions":
+			changes = append(changes, diffList(asList(b), asList(a), functionKey, "function", nil)...)
+		default:
+			name := strings.TrimSuffix(strings.ReplaceAll(key, "_", " "), "s")
+			if _, ok := b.([]interface{}); !ok {
+				if _, ok := a.([]interface{}); !ok {
+					if !reflect.DeepEqual(b, a) {
+						changes = append(changes, Change{ChangeModified, name})
+					}
+					continue
+				}
+			}
+			changes = append(changes, diffList(asList(b), asList(a), nameKey("name"), name, nil)...)
+		}
+	}
+	return changes
+}
+
+func diffSource(before, after map[string]interface{}) []Change {
+	var changes []Change
+	changes = append(changes, diffList(asList(before["tables"]), asList(after["tables"]), tableKey, "table", diffTable)...)
+	changes = append(changes, diffList(asList(before["functions"]), asList(after["functions"]), functionKey, "function", nil)...)
+	if !reflect.DeepEqual(before["configuration"], after["configuration"]) || !reflect.DeepEqual(before["kind"], after["kind"]) {
+		changes = append(changes, Change{ChangeModified, "configuration"})
+	}
+	return changes
+}
+
+func diffTable(before, after map[string]interface{}) []Change {
+	var changes []Change
+	before, after = copyMap(before), copyMap(after)
+	for _, f := range tableListFields {
+		changes = append(changes, diffList(asList(before[f.field]), asList(after[f.field]), nameKey(f.key), f.name, nil)...)
+		delete(before, f.field)
+		delete(after, f.field)
+	}
+	if !reflect.DeepEqual(before, after) {
+		changes = append(changes, Change{ChangeModified, "configuration"})
+	}
+	return changes
+}
+
+// diffList compares two lists of objects identified by keyFn. When nested is
+// not nil it is used to find changes within objects present in both lists,
+// otherwise such objects are reported as modified when they are not equal.
+func diffList(before, after []interface{}, keyFn func(interface{}) string, name string, nested func(before, after map[string]interface{}) []Change) []Change {
+	beforeByKey, afterByKey := map[string]interface{}{}, map[string]interface{}{}
+	for _, item := range before {
+		beforeByKey[keyFn(item)] = item
+	}
+	for _, item := range after {
+		afterByKey[keyFn(item)] = item
+	}
+	var changes []Change
+	for _, key := range sortedKeys(beforeByKey, afterByKey) {
+		object := fmt.Sprintf("%s %s", name, key)
+		b, inBefore := beforeByKey[key]
+		a, inAfter := afterByKey[key]
+		switch {
+		case !inBefore:
+			changes = append(changes, Change{ChangeAdded, object})
+		case !inAfter:
+			changes = append(changes, Change{ChangeRemoved, object})
+		case reflect.DeepEqual(a, b):
+		case nested != nil:
+			bm, bok := b.(map[string]interface{})
+			am, aok := a.(map[string]interface{})
+			if !bok || !aok {
+				changes = append(changes, Change{ChangeModified, object})
+				continue
+			}
+			for _, c := range nested(bm, am) {
+				c.Object = fmt.Sprintf("%s > %s", object, c.Object)
+				changes = append(changes, c)
+			}
+		default:
+			changes = append(changes, Change{ChangeModified, object})
+		}
+	}
+	return changes
+}
+
+func nameKey(field string) func(interface{}) string {
+	return func(item interface{}) string {
+		if m, ok := item.(map[string]interface{}); ok {
+			if v, ok := m[field]; ok {
+				return fmt.Sprintf("%v", v)
+			}
+		}
+		b, _ := json.Marshal(item)
+		return string(b)
+	}
+}
+
+// tableKey returns <schema>.<name> (or <dataset>.<name>) of a table object
+func tableKey(item interface{}) string {
+	return qualifiedName(item, "table")
+}
+
+func functionKey(item interface{}) string {
+	return qualifiedName(item, "function")
+}
+
+func qualifiedName(item interface{}, field string) string {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return nameKey(field)(item)
+	}
+	switch v := m[field].(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		for _, namespace := range []string{"schema", "dataset"} {
+			if ns, ok := v[namespace]; ok {
+				return fmt.Sprintf("%v.%v", ns, v["name"])
+			}
+		}
+		return fmt.Sprintf("%v", v["name"])
+	}
+	return nameKey(field)(item)
+}
+
+func asList(v interface{}) []interface{} {
+	if l, ok := v.([]interface{}); ok {
+		return l
+	}
+	return nil
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func sortedKeys(maps ...map[string]interface{}) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, m := range maps {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,95 @@
+package metadatautil
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSemanticDiff(t *testing.T) {
+	decode := func(s string) map[string]interface{} {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(s), &m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   []Change
+	}{
+		{
+			"can find added tables and changed permissions",
+			`
+{
+	"version": 3,
+	"sources": [
+		{
+			"name": "default",
+			"kind": "postgres",
+			"tables": [
+				{
+					"table": {"schema": "public", "name": "users"},
+					"select_permissions": [{"role": "user", "permission": {"columns": ["id"]}}]
+				}
+			]
+		}
+	]
+}`,
+			`
+{
+	"version": 3,
+	"sources": [
+		{
+			"name": "default",
+			"kind": "postgres",
+			"tables": [
+				{
+					"table": {"schema": "public", "name": "users"},
+					"select_permissions": [{"role": "user", "permission": {"columns": ["id", "name"]}}]
+				},
+				{
+					"table": {"schema": "public", "name": "articles"}
+				}
+			]
+		},
+		{
+			"name": "analytics",
+			"kind": "postgres",
+			"tables": []
+		}
+	],
+	"remote_schemas": [{"name": "countries"}]
+}`,
+			[]Change{
+				{ChangeAdded, "remote schema countries"},
+				{ChangeAdded, "source analytics"},
+				{ChangeAdded, "source default > table public.articles"},
+				{ChangeModified, "source default > table public.users > select permission user"},
+			},
+		},
+		{
+			"returns nothing for equal metadata",
+			`{"version": 2, "tables": [{"table": {"schema": "public", "name": "users"}}]}`,
+			`{"version": 2, "tables": [{"table": {"schema": "public", "name": "users"}}]}`,
+			nil,
+		},
+		{
+			"can find removed tables in metadata without sources",
+			`{"version": 2, "tables": [{"table": {"schema": "public", "name": "users"}}]}`,
+			`{"version": 2, "tables": []}`,
+			[]Change{
+				{ChangeRemoved, "table public.users"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SemanticDiff(decode(tt.before), decode(tt.after))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
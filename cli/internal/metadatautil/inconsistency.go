@@ -0,0 +1,201 @@
+package metadatautil
+
+import "fmt"
+
+// fields of a table which hold objects reported as inconsistent, keyed by the
+// type of the inconsistent object
+var inconsistentTableObjects = map[string]struct{ field, key string }{
+	"object_relation":     {"object_relationships", "name"},
+	"array_relation":      {"array_relationships", "name"},
+	"remote_relationship": {"remote_relationships", "name"},
+	"computed_field":      {"computed_fields", "name"},
+	"insert_permission":   {"insert_permissions", "role"},
+	"select_permission":   {"select_permissions", "role"},
+	"update_permission":   {"update_permissions", "role"},
+	"delete_permission":   {"delete_permissions", "role"},
+	"event_trigger":       {"event_triggers", "name"},
+}
+
+// top level metadata fields which hold objects reported as inconsistent,
+// keyed by the type of the inconsistent object
+var inconsistentTopLevelObjects = map[string]string{
+	"remote_schema":    "remote_schemas",
+	"action":           "actions",
+	"cron_trigger":     "cron_triggers",
+	"inherited_role":   "inherited_roles",
+	"rest_endpoint":    "rest_endpoints",
+	"query_collection": "query_collections",
+}
+
+// InconsistentObjectsMetadata cross references the inconsistent objects reported
+// by the server (the inconsistent_objects of get_inconsistent_metadata) with
+// metadata (an export of the server metadata) and returns metadata containing
+// only the definitions of inconsistent objects. Objects which cannot be found
+// in metadata are returned as unmatched.
+func InconsistentObjectsMetadata(metadata map[string]interface{}, inconsistentObjects []interface{}) (map[string]interface{}, []interface{}) {
+	filtered := map[string]interface{}{}
+	if version, ok := metadata["version"]; ok {
+		filtered["version"] = version
+	}
+	var unmatched []interface{}
+	for _, object := range inconsistentObjects {
+		if !addInconsistentObject(metadata, filtered, object) {
+			unmatched = append(unmatched, object)
+		}
+	}
+	return filtered, unmatched
+}
+
+func addInconsistentObject(metadata, filtered map[string]interface{}, object interface{}) bool {
+	o, ok := object.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	objectType, _ := o["type"].(string)
+	definition := o["definition"]
+	definitionMap, _ := definition.(map[string]interface{})
+
+	switch objectType {
+	case "source":
+		source := findByKey(asList(metadata["sources"]), nameKey("name"), nameOf(definition))
+		if source == nil {
+			return false
+		}
+		addToList(filtered, "sources", nameKey("name"), copyMap(source))
+		return true
+	case "table", "function":
+		src, dst := containers(metadata, filtered, definitionMap)
+		if src == nil {
+			return false
+		}
+		if v, ok := definitionMap[objectType]; ok {
+			definition = v
+		}
+		field, keyFn := "tables", normalizedKey("table")
+		if objectType == "function" {
+			field, keyFn = "functions", normalizedKey("function")
+		}
+		item := findByKey(asList(src[field]), keyFn, normalizedName(definition))
+		if item == nil {
+			return false
+		}
+		addToList(dst, field, keyFn, copyMap(item))
+		return true
+	case "custom_types":
+		customTypes, ok := metadata["custom_types"]
+		if !ok {
+			return false
+		}
+		filtered["custom_types"] = customTypes
+		return true
+	}
+
+	if tableObject, ok := inconsistentTableObjects[objectType]; ok {
+		src, dst := containers(metadata, filtered, definitionMap)
+		if src == nil {
+			return false
+		}
+		table := findByKey(asList(src["tables"]), normalizedKey("table"), normalizedName(definitionMap["table"]))
+		if table == nil {
+			return false
+		}
+		item := findByKey(asList(table[tableObject.field]), nameKey(tableObject.key), fmt.Sprintf("%v", definitionMap[tableObject.key]))
+		if item == nil {
+			return false
+		}
+		dstTable := findByKey(asList(dst["tables"]), normalizedKey("table"), normalizedName(definitionMap["table"]))
+		if dstTable == nil {
+			dstTable = map[string]interface{}{"table": table["table"]}
+			addToList(dst, "tables", normalizedKey("table"), dstTable)
+		}
+		addToList(dstTable, tableObject.field, nameKey(tableObject.key), item)
+		return true
+	}
+	if field, ok := inconsistentTopLevelObjects[objectType]; ok {
+		item := findByKey(asList(metadata[field]), nameKey("name"), nameOf(definition))
+		if item == nil {
+			return false
+		}
+		addToList(filtered, field, nameKey("name"), item)
+		return true
+	}
+	return false
+}
+
+// containers returns the objects holding tables and functions in metadata and
+// filtered metadata. For metadata with sources these are the source the
+// inconsistent object belongs to, otherwise it is the metadata itself.
+func containers(metadata, filtered, definition map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	if _, ok := metadata["sources"]; !ok {
+		return metadata, filtered
+	}
+	sourceName := "default"
+	if v, ok := definition["source"].(string); ok {
+		sourceName = v
+	}
+	source := findByKey(asList(metadata["sources"]), nameKey("name"), sourceName)
+	if source == nil {
+		return nil, nil
+	}
+	dst := findByKey(asList(filtered["sources"]), nameKey("name"), sourceName)
+	if dst == nil {
+		dst = copyMap(source)
+		delete(dst, "tables")
+		delete(dst, "functions")
+		addToList(filtered, "sources", nameKey("name"), dst)
+	}
+	return source, dst
+}
+
+func findByKey(list []interface{}, keyFn func(interface{}) string, key string) map[string]interface{} {
+	for _, item := range list {
+		if keyFn(item) == key {
+			if m, ok := item.(map[string]interface{}); ok {
+				return m
+			}
+		}
+	}
+	return nil
+}
+
+// addToList appends item to the list at field of m, unless an item with the same key exists
+func addToList(m map[string]interface{}, field string, keyFn func(interface{}) string, item map[string]interface{}) {
+	list := asList(m[field])
+	if findByKey(list, keyFn, keyFn(item)) != nil {
+		return
+	}
+	m[field] = append(append([]interface{}{}, list...), item)
+}
+
+func nameOf(definition interface{}) string {
+	if m, ok := definition.(map[string]interface{}); ok {
+		return fmt.Sprintf("%v", m["name"])
+	}
+	return fmt.Sprintf("%v", definition)
+}
+
+func normalizedKey(field string) func(interface{}) string {
+	return func(item interface{}) string {
+		if m, ok := item.(map[string]interface{}); ok {
+			return normalizedName(m[field])
+		}
+		return ""
+	}
+}
+
+// normalizedName returns <schema>.<name> (or <dataset>.<name>) of a table or
+// function, defaulting to the public schema when only a name is given
+func normalizedName(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return "public." + t
+	case map[string]interface{}:
+		for _, namespace := range []string{"schema", "dataset"} {
+			if ns, ok := t[namespace]; ok {
+				return fmt.Sprintf("%v.%v", ns, t["name"])
+			}
+		}
+		return fmt.Sprintf("public.%v", t["name"])
+	}
+	return fmt.Sprintf("%v", v)
+}
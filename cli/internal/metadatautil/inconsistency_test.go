@@ -0,0 +1,101 @@
+package metadatautil
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInconsistentObjectsMetadata(t *testing.T) {
+	decode := func(s string) interface{} {
+		var v interface{}
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tests := []struct {
+		name                string
+		metadata            string
+		inconsistentObjects string
+		want                string
+		wantUnmatched       string
+	}{
+		{
+			"can filter tables and relationships of a source",
+			`
+{
+	"version": 3,
+	"sources": [
+		{
+			"name": "default",
+			"kind": "postgres",
+			"configuration": {"connection_info": {"database_url": "postgres://"}},
+			"tables": [
+				{"table": {"schema": "public", "name": "article"}},
+				{
+					"table": {"schema": "public", "name": "author"},
+					"array_relationships": [
+						{"name": "articles", "using": {"foreign_key_constraint_on": {"column": "author_id", "table": "article"}}},
+						{"name": "books", "using": {"foreign_key_constraint_on": {"column": "author_id", "table": "book"}}}
+					]
+				},
+				{"table": {"schema": "public", "name": "consistent"}}
+			]
+		}
+	],
+	"remote_schemas": [{"name": "countries"}, {"name": "weather"}]
+}`,
+			`
+[
+	{"definition": {"source": "default", "table": {"schema": "public", "name": "author"}, "name": "articles"}, "reason": "table \"article\" does not exist", "type": "array_relation"},
+	{"definition": {"source": "default", "table": {"schema": "public", "name": "article"}}, "reason": "no such table/view exists", "type": "table"},
+	{"definition": {"name": "weather"}, "reason": "remote schema is unreachable", "type": "remote_schema"},
+	{"definition": {"name": "missing"}, "reason": "unknown", "type": "action"}
+]`,
+			`
+{
+	"version": 3,
+	"sources": [
+		{
+			"name": "default",
+			"kind": "postgres",
+			"configuration": {"connection_info": {"database_url": "postgres://"}},
+			"tables": [
+				{
+					"table": {"schema": "public", "name": "author"},
+					"array_relationships": [
+						{"name": "articles", "using": {"foreign_key_constraint_on": {"column": "author_id", "table": "article"}}}
+					]
+				},
+				{"table": {"schema": "public", "name": "article"}}
+			]
+		}
+	],
+	"remote_schemas": [{"name": "weather"}]
+}`,
+			`[{"definition": {"name": "missing"}, "reason": "unknown", "type": "action"}]`,
+		},
+		{
+			"can filter tables of metadata without sources",
+			`{"version": 2, "tables": [{"table": {"schema": "public", "name": "article"}}, {"table": {"schema": "public", "name": "author"}}]}`,
+			`[{"definition": "article", "reason": "no such table/view exists", "type": "table"}]`,
+			`{"version": 2, "tables": [{"table": {"schema": "public", "name": "article"}}]}`,
+			`null`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata := decode(tt.metadata).(map[string]interface{})
+			inconsistentObjects := decode(tt.inconsistentObjects).([]interface{})
+			got, gotUnmatched := InconsistentObjectsMetadata(metadata, inconsistentObjects)
+			assert.Equal(t, decode(tt.want), got)
+			if tt.wantUnmatched == "null" {
+				assert.Nil(t, gotUnmatched)
+			} else {
+				assert.Equal(t, decode(tt.wantUnmatched), gotUnmatched)
+			}
+		})
+	}
+}
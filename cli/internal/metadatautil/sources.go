@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"sort"
 
 	"github.com/goccy/go-yaml"
 	"github.com/goccy/go-yaml/parser"
@@ -81,10 +82,12 @@ func GetSources(exportMetadata func() (io.Reader, error)) ([]string, error) {
 }
 
 type Source struct {
-	Name string            `yaml: "name"`
+	Name string            `yaml:"name"`
 	Kind hasura.SourceKind `yaml:"kind"`
 }
 
+// GetSourcesAndKind returns the name and kind of the sources in metadata,
+// sources without a kind (added by older servers) are postgres sources
 func GetSourcesAndKind(exportMetadata func() (io.Reader, error)) ([]Source, error) {
 	metadata, err := getMetadataAsYaml(exportMetadata)
 	if err != nil {
@@ -105,5 +108,67 @@ func GetSourcesAndKind(exportMetadata func() (io.Reader, error)) ([]Source, erro
 	if err := path.Read(ast.Docs[0], &sources); err != nil {
 		return nil, err
 	}
+	for idx := range sources {
+		if len(sources[idx].Kind) == 0 {
+			sources[idx].Kind = hasura.SourceKindPG
+		}
+	}
 	return sources, nil
 }
+
+// GetSourcesEnvVars returns the names of environment variables referenced
+// (using from_env) in the configuration of sources, keyed by source name.
+// Sources which do not reference any environment variables are not included.
+func GetSourcesEnvVars(exportMetadata func() (io.Reader, error)) (map[string][]string, error) {
+	metadata, err := getMetadataAsYaml(exportMetadata)
+	if err != nil {
+		return nil, err
+	}
+	ast, err := parser.ParseBytes(metadata, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(ast.Docs) <= 0 {
+		return nil, fmt.Errorf("failed listing sources from metadata")
+	}
+	path, err := yaml.PathString("$.sources")
+	if err != nil {
+		return nil, err
+	}
+	var sources []struct {
+		Name          string      `yaml:"name"`
+		Configuration interface{} `yaml:"configuration"`
+	}
+	if err := path.Read(ast.Docs[0], &sources); err != nil {
+		return nil, err
+	}
+	envVars := map[string][]string{}
+	for _, source := range sources {
+		names := map[string]struct{}{}
+		collectFromEnv(source.Configuration, names)
+		if len(names) == 0 {
+			continue
+		}
+		for name := range names {
+			envVars[source.Name] = append(envVars[source.Name], name)
+		}
+		sort.Strings(envVars[source.Name])
+	}
+	return envVars, nil
+}
+
+func collectFromEnv(v interface{}, names map[string]struct{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if name, ok := t["from_env"].(string); ok {
+			names[name] = struct{}{}
+		}
+		for _, value := range t {
+			collectFromEnv(value, names)
+		}
+	case []interface{}:
+		for _, value := range t {
+			collectFromEnv(value, names)
+		}
+	}
+}
@@ -144,6 +144,29 @@ func TestGetSourcesAndKind(t *testing.T) {
 			[]Source{{"test1", hasura.SourceKindPG}, {"test2", hasura.SourceKindMSSQL}},
 			false,
 		},
+		{
+			"defaults kind to postgres",
+			args{
+				func() (io.Reader, error) {
+					return strings.NewReader(
+						`
+{
+	"sources": [
+		{
+			"name": "test1"
+		},
+		{
+			"name": "test2",
+			"kind": "citus"
+		}
+	]
+}
+`), nil
+				},
+			},
+			[]Source{{"test1", hasura.SourceKindPG}, {"test2", "citus"}},
+			false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -158,3 +181,38 @@ func TestGetSourcesAndKind(t *testing.T) {
 		})
 	}
 }
+
+func TestGetSourcesEnvVars(t *testing.T) {
+	exportMetadata := func() (io.Reader, error) {
+		return strings.NewReader(`
+{
+	"sources": [
+		{
+			"name": "pg",
+			"kind": "postgres",
+			"configuration": {
+				"connection_info": {
+					"database_url": {"from_env": "PG_URL"}
+				},
+				"read_replicas": [
+					{"database_url": {"from_env": "PG_REPLICA_URL"}}
+				]
+			}
+		},
+		{
+			"name": "mssql",
+			"kind": "mssql",
+			"configuration": {
+				"connection_info": {
+					"connection_string": "Server=localhost"
+				}
+			}
+		}
+	]
+}
+`), nil
+	}
+	got, err := GetSourcesEnvVars(exportMetadata)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"pg": {"PG_REPLICA_URL", "PG_URL"}}, got)
+}
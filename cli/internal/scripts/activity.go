@@ -0,0 +1,66 @@
+package scripts
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/internal/statestore/migrations"
+	"github.com/pkg/errors"
+)
+
+// migrations with a version (creation timestamp) newer than this
+// are considered to be recently applied
+const recentMigrationWindow = 15 * time.Minute
+
+// serverActivity returns the reasons for which the server appears to be applying
+// migrations, by looking at the migrations state table in hdb_catalog: dirty
+// versions, versions created within recentMigrationWindow of now and other
+// sessions running queries against the table
+func serverActivity(client hasura.PGSourceOps, now time.Time) ([]string, error) {
+	table := fmt.Sprintf("%s.%s", migrations.DefaultSchema, migrations.DefaultMigrationsTable)
+	query := hasura.PGRunSQLInput{
+		SQL: fmt.Sprintf(`SELECT
+  (SELECT count(1) FROM %[1]s WHERE dirty),
+  (SELECT coalesce(max(version), 0) FROM %[1]s),
+  (SELECT count(1) FROM pg_stat_activity WHERE state = 'active' AND pid <> pg_backend_pid() AND query ILIKE '%%%[2]s%%')`,
+			table, migrations.DefaultMigrationsTable),
+		ReadOnly: true,
+	}
+	resp, err := client.PGRunSQL(query)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying migrations state")
+	}
+	if resp.ResultType != hasura.TuplesOK || len(resp.Result) < 2 || len(resp.Result[1]) < 3 {
+		return nil, fmt.Errorf("unexpected result when querying migrations state")
+	}
+	return parseServerActivity(resp.Result[1], now)
+}
+
+func parseServerActivity(row []string, now time.Time) ([]string, error) {
+	var values [3]uint64
+	for i := range values {
+		v, err := strconv.ParseUint(row[i], 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing migrations state")
+		}
+		values[i] = v
+	}
+	dirty, latest, sessions := values[0], values[1], values[2]
+
+	var reasons []string
+	if dirty > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d migration(s) are marked dirty, a migration might be in progress", dirty))
+	}
+	if latest > 0 {
+		created := time.Unix(0, int64(latest)*int64(time.Millisecond))
+		if age := now.Sub(created); age >= 0 && age < recentMigrationWindow {
+			reasons = append(reasons, fmt.Sprintf("migration %d was created %s ago", latest, age.Round(time.Second)))
+		}
+	}
+	if sessions > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d other session(s) are querying the migrations state", sessions))
+	}
+	return reasons, nil
+}
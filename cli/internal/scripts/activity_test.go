@@ -0,0 +1,37 @@
+package scripts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseServerActivity(t *testing.T) {
+	now := time.Unix(1604855964, 0)
+	tests := []struct {
+		name        string
+		row         []string
+		wantReasons int
+		wantErr     bool
+	}{
+		{"no activity", []string{"0", "1604855000000", "0"}, 0, false},
+		{"empty state", []string{"0", "0", "0"}, 0, false},
+		{"dirty migration", []string{"1", "1604855000000", "0"}, 1, false},
+		{"recent migration", []string{"0", "1604855900000", "0"}, 1, false},
+		{"active sessions", []string{"0", "1604855000000", "2"}, 1, false},
+		{"all of them", []string{"1", "1604855900000", "1"}, 3, false},
+		{"malformed result", []string{"x", "0", "0"}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseServerActivity(tt.row, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, got, tt.wantReasons)
+		})
+	}
+}
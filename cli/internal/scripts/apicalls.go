@@ -0,0 +1,128 @@
+package scripts
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/internal/statestore"
+	"github.com/pkg/errors"
+)
+
+// formats in which the server API calls of an update can be emitted
+const (
+	APICallsFormatJSON = "json"
+	APICallsFormatCurl = "curl"
+)
+
+// APICall is a request made to the server during UpdateProjectV3
+type APICall struct {
+	Method   string             `json:"method"`
+	Endpoint string             `json:"endpoint"`
+	Body     hasura.RequestBody `json:"body"`
+}
+
+// planServerAPICalls returns the calls which modify the server, made by
+// UpdateProjectV3 when updating a project with targetDatabase as the target.
+// The state to be copied is read from the server to build the calls, but
+// nothing is written to it. Only settings in settingsAllowlist are copied when it is not empty.
+func planServerAPICalls(ec *cli.ExecutionContext, targetDatabase, label string, settingsAllowlist []string) ([]APICall, error) {
+	storeOpts := stateStoreOptions(ec)
+	migrationsStore, err := statestore.NewMigrationsStateStore(statestore.StateStoreHdbTable, storeOpts)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := migrationsStore.GetVersions("")
+	if err != nil {
+		return nil, errors.Wrap(err, "reading migrations state")
+	}
+	settingsStore, err := statestore.NewSettingsStateStore(statestore.StateStoreHdbTable, storeOpts)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := settingsStore.GetAllSettings()
+	if err != nil {
+		return nil, errors.Wrap(err, "reading settings state")
+	}
+	for k := range settings {
+		if !statestore.SettingAllowed(k, settingsAllowlist) {
+			delete(settings, k)
+		}
+	}
+	state, err := statestore.NewCLICatalogState(ec.APIClient.V1Metadata).Get()
+	if err != nil {
+		return nil, errors.Wrap(err, "getting catalog state")
+	}
+	state = copiedCatalogState(state, versions, settings, targetDatabase)
+	if len(label) > 0 {
+		state.StateCopyLabel = label
+	}
+
+	endpoint := ec.Config.GetV1MetadataEndpoint()
+	return []APICall{
+		{
+			Method:   http.MethodPost,
+			Endpoint: endpoint,
+			Body: hasura.RequestBody{
+				Type: "set_catalog_state",
+				Args: map[string]interface{}{"type": "cli", "state": state},
+			},
+		},
+		{
+			Method:   http.MethodPost,
+			Endpoint: endpoint,
+			Body:     hasura.RequestBody{Type: "export_metadata", Args: map[string]string{}},
+		},
+	}, nil
+}
+
+// copiedCatalogState returns state as it would be after copying versions and
+// settings from hdb_catalog tables to it, with versions recorded for database
+func copiedCatalogState(state *statestore.CLIState, versions map[uint64]bool, settings map[string]string, database string) *statestore.CLIState {
+	if state == nil {
+		state = &statestore.CLIState{}
+	}
+	state.Init()
+	for version, dirty := range versions {
+		state.SetMigration(database, fmt.Sprintf("%d", version), dirty)
+	}
+	for k, v := range settings {
+		state.SetSetting(k, v)
+	}
+	state.IsStateCopyCompleted = true
+	state.IsStateCopyInProgress = false
+	return state
+}
+
+// writeAPICalls writes calls to w either as a JSON array or as a shell script of
+// curl commands, which read the admin secret from HASURA_GRAPHQL_ADMIN_SECRET
+func writeAPICalls(w io.Writer, calls []APICall, format string) error {
+	switch format {
+	case APICallsFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(calls)
+	case APICallsFormatCurl:
+		if _, err := fmt.Fprintln(w, "#!/bin/sh\nset -e"); err != nil {
+			return err
+		}
+		for _, call := range calls {
+			body, err := json.Marshal(call.Body)
+			if err != nil {
+				return err
+			}
+			// single quotes in the body are escaped for the shell
+			quoted := strings.ReplaceAll(string(body), "'", `'\''`)
+			_, err = fmt.Fprintf(w, "\n# %s\ncurl -sSf -X %s '%s' \\\n  -H 'Content-Type: application/json' \\\n  -H \"X-Hasura-Admin-Secret: $HASURA_GRAPHQL_ADMIN_SECRET\" \\\n  -d '%s'\n", call.Body.Type, call.Method, call.Endpoint, quoted)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown format %q, should be one of %s, %s", format, APICallsFormatJSON, APICallsFormatCurl)
+}
@@ -0,0 +1,50 @@
+package scripts
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/internal/statestore"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_copiedCatalogState(t *testing.T) {
+	state := &statestore.CLIState{Migrations: statestore.MigrationsState{"other": {"1": false}}}
+	got := copiedCatalogState(state, map[uint64]bool{1604855964903: true}, map[string]string{"migration_mode": "true"}, "default")
+	assert.Equal(t, &statestore.CLIState{
+		Migrations: statestore.MigrationsState{
+			"other":   {"1": false},
+			"default": {"1604855964903": true},
+		},
+		Settings:             map[string]string{"migration_mode": "true"},
+		IsStateCopyCompleted: true,
+	}, got)
+}
+
+func Test_writeAPICalls(t *testing.T) {
+	calls := []APICall{
+		{
+			Method:   "POST",
+			Endpoint: "http://localhost:8080/v1/metadata",
+			Body:     hasura.RequestBody{Type: "export_metadata", Args: map[string]string{"comment": "it's"}},
+		},
+	}
+
+	var b bytes.Buffer
+	assert.NoError(t, writeAPICalls(&b, calls, APICallsFormatJSON))
+	var got []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &got))
+	assert.Len(t, got, 1)
+
+	b.Reset()
+	assert.NoError(t, writeAPICalls(&b, calls, APICallsFormatCurl))
+	script := b.String()
+	assert.True(t, strings.HasPrefix(script, "#!/bin/sh"))
+	assert.Contains(t, script, "curl -sSf -X POST 'http://localhost:8080/v1/metadata'")
+	assert.Contains(t, script, `it'\''s`)
+
+	assert.Error(t, writeAPICalls(&b, calls, "yaml"))
+}
@@ -0,0 +1,102 @@
+package scripts
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/version"
+	"github.com/pkg/errors"
+)
+
+// Capabilities describes what the server the CLI is talking to supports,
+// for diagnosing compatibility issues with the update to config v3
+type Capabilities struct {
+	ServerVersion string `json:"serverVersion"`
+	// MetadataVersion is the version of metadata used by the server, 3 for
+	// servers supporting multiple databases and 2 otherwise
+	MetadataVersion      int                 `json:"metadataVersion"`
+	ExperimentalFeatures []string            `json:"experimentalFeatures"`
+	SourceKinds          []hasura.SourceKind `json:"sourceKinds"`
+	HasActions           bool                `json:"hasActions"`
+	HasCronTriggers      bool                `json:"hasCronTriggers"`
+}
+
+func (c Capabilities) String() string {
+	return fmt.Sprintf("server %s, metadata version %d, source kinds %v, experimental features %v", c.ServerVersion, c.MetadataVersion, c.SourceKinds, c.ExperimentalFeatures)
+}
+
+// ServerCapabilities returns the capabilities of the server ec is configured
+// to talk to, using the version and the config reported by the server when
+// the execution context was validated. The kinds of sources are listed by the
+// server, falling back to the kinds known to be supported by its metadata
+// version when the server cannot list them
+func ServerCapabilities(ec *cli.ExecutionContext) (Capabilities, error) {
+	if ec.Version == nil || ec.Config == nil {
+		return Capabilities{}, fmt.Errorf("server version is not known, execution context has to be validated first")
+	}
+	var sourceKinds []hasura.SourceKind
+	if ec.HasMetadataV3 && ec.APIClient != nil && ec.APIClient.V1Metadata != nil {
+		kinds, err := listSourceKinds(ec.APIClient.V1Metadata)
+		if err != nil {
+			ec.Logger.Debugf("server could not list source kinds, using the kinds known to be supported: %v", err)
+		}
+		sourceKinds = kinds
+	}
+	return serverCapabilities(ec.Version, ec.HasMetadataV3, ec.Config.ServerConfig.HasuraServerInternalConfig, sourceKinds), nil
+}
+
+// serverCapabilities uses sourceKinds as the kinds of sources supported by
+// the server, the kinds supported by the metadata version are used when it is empty
+func serverCapabilities(v *version.Version, hasMetadataV3 bool, config cli.HasuraServerInternalConfig, sourceKinds []hasura.SourceKind) Capabilities {
+	capabilities := Capabilities{
+		ServerVersion:        v.GetServerVersion(),
+		MetadataVersion:      2,
+		ExperimentalFeatures: append([]string{}, config.ExperimentalFeatures...),
+		SourceKinds:          []hasura.SourceKind{hasura.SourceKindPG},
+	}
+	sort.Strings(capabilities.ExperimentalFeatures)
+	if hasMetadataV3 {
+		capabilities.MetadataVersion = 3
+		// sources other than postgres were introduced along with metadata v3
+		capabilities.SourceKinds = append(capabilities.SourceKinds, hasura.SourceKindMSSQL)
+	}
+	if len(sourceKinds) > 0 {
+		capabilities.SourceKinds = append([]hasura.SourceKind{}, sourceKinds...)
+	}
+	if v.ServerFeatureFlags != nil {
+		capabilities.HasActions = v.ServerFeatureFlags.HasAction
+		capabilities.HasCronTriggers = v.ServerFeatureFlags.HasCronTriggers
+	}
+	return capabilities
+}
+
+// listSourceKinds returns the kinds of sources supported by the server using
+// the list_source_kinds metadata API, which older servers do not have
+func listSourceKinds(client hasura.V1Metadata) ([]hasura.SourceKind, error) {
+	resp, body, err := client.Send(hasura.RequestBody{Type: "list_source_kinds", Args: map[string]string{}})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing source kinds")
+	}
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(body)
+		return nil, fmt.Errorf("listing source kinds: %s", b)
+	}
+	var response struct {
+		Sources []struct {
+			Kind hasura.SourceKind `json:"kind"`
+		} `json:"sources"`
+	}
+	if err := json.NewDecoder(body).Decode(&response); err != nil {
+		return nil, errors.Wrap(err, "decoding source kinds")
+	}
+	kinds := make([]hasura.SourceKind, 0, len(response.Sources))
+	for _, source := range response.Sources {
+		kinds = append(kinds, source.Kind)
+	}
+	return kinds, nil
+}
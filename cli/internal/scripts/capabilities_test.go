@@ -0,0 +1,37 @@
+package scripts
+
+import (
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_serverCapabilities(t *testing.T) {
+	v := version.New()
+	v.SetServerVersion("v2.0.0-alpha.5")
+	assert.NoError(t, v.GetServerFeatureFlags())
+
+	got := serverCapabilities(v, true, cli.HasuraServerInternalConfig{ExperimentalFeatures: []string{"inherited_roles", "graphql_optimizations"}}, nil)
+	assert.Equal(t, Capabilities{
+		ServerVersion:        "v2.0.0-alpha.5",
+		MetadataVersion:      3,
+		ExperimentalFeatures: []string{"graphql_optimizations", "inherited_roles"},
+		SourceKinds:          []hasura.SourceKind{hasura.SourceKindPG, hasura.SourceKindMSSQL},
+		HasActions:           true,
+		HasCronTriggers:      true,
+	}, got)
+
+	v.SetServerVersion("v1.3.3")
+	got = serverCapabilities(v, false, cli.HasuraServerInternalConfig{}, nil)
+	assert.Equal(t, 2, got.MetadataVersion)
+	assert.Equal(t, []hasura.SourceKind{hasura.SourceKindPG}, got.SourceKinds)
+	assert.Equal(t, []string{}, got.ExperimentalFeatures)
+
+	// kinds listed by the server are used when there are any
+	v.SetServerVersion("v2.10.0")
+	got = serverCapabilities(v, true, cli.HasuraServerInternalConfig{}, []hasura.SourceKind{hasura.SourceKindPG, "citus", "bigquery"})
+	assert.Equal(t, []hasura.SourceKind{hasura.SourceKindPG, "citus", "bigquery"}, got.SourceKinds)
+}
@@ -0,0 +1,62 @@
+package scripts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// verifyCopy compares the SHA256 checksum of each file in src (a file or a
+// directory) with the checksum of the corresponding file in dst, returning an
+// error naming the first file which does not match, eg: a copy truncated on
+// a full disk. It is used before the originals of copied files are removed.
+// The checksum of each file is logged at debug level when logger is not nil
+func verifyCopy(fs afero.Fs, src, dst string, logger *logrus.Logger) error {
+	return afero.Walk(fs, src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		srcSum, err := fileChecksum(fs, path)
+		if err != nil {
+			return err
+		}
+		dstSum, err := fileChecksum(fs, target)
+		if err != nil {
+			return fmt.Errorf("verifying copy of %s: %w", path, err)
+		}
+		if logger != nil {
+			logger.Debugf("sha256 of %s: %x, of its copy %s: %x", path, srcSum, target, dstSum)
+		}
+		if !bytes.Equal(srcSum, dstSum) {
+			return fmt.Errorf("copy of %s to %s does not match the original (checksum %x, expected %x)", path, target, dstSum, srcSum)
+		}
+		return nil
+	})
+}
+
+func fileChecksum(fs afero.Fs, path string) ([]byte, error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,40 @@
+package scripts
+
+import (
+	"os"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_verifyCopy(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for file, content := range map[string]string{
+		"migrations/1604855964903_test/up.sql":           "CREATE TABLE t (id int);",
+		"migrations/1604855964903_test/down.sql":         "DROP TABLE t;",
+		"migrations/default/1604855964903_test/up.sql":   "CREATE TABLE t (id int);",
+		"migrations/default/1604855964903_test/down.sql": "DROP TABLE",
+		"seeds/users.sql":         "INSERT INTO users VALUES (1);",
+		"seeds/default/users.sql": "INSERT INTO users VALUES (1);",
+	} {
+		if err := afero.WriteFile(fs, file, []byte(content), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	assert.NoError(t, verifyCopy(fs, "seeds/users.sql", "seeds/default/users.sql", logger))
+	if assert.Len(t, hook.AllEntries(), 1) {
+		assert.Contains(t, hook.LastEntry().Message, "seeds/default/users.sql")
+	}
+
+	err := verifyCopy(fs, "migrations/1604855964903_test", "migrations/default/1604855964903_test", nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "migrations/default/1604855964903_test/down.sql")
+	}
+
+	assert.Error(t, verifyCopy(fs, "seeds/users.sql", "seeds/orders/users.sql", nil))
+}
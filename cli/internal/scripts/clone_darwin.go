@@ -0,0 +1,31 @@
+package scripts
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+// cloneDirectory makes dst a copy-on-write clone of the directory src. It
+// returns errCloneNotSupported when the filesystem is not APFS
+func cloneDirectory(src, dst string) error {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(src, &stat); err != nil {
+		return err
+	}
+	var fsType []byte
+	for _, c := range stat.Fstypename {
+		if c == 0 {
+			break
+		}
+		fsType = append(fsType, byte(c))
+	}
+	if string(fsType) != "apfs" {
+		return errCloneNotSupported
+	}
+	// cp -c clones files using clonefile(2)
+	if out, err := exec.Command("cp", "-c", "-R", src, dst).CombinedOutput(); err != nil {
+		return fmt.Errorf("cloning %s: %w: %s", src, err, out)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package scripts
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// ficlone is the FICLONE ioctl, which makes the destination file
+// a copy-on-write clone of the source file (Btrfs, XFS)
+const ficlone = 0x40049409
+
+// cloneDirectory makes dst a copy-on-write clone of the directory src. It
+// returns errCloneNotSupported when the filesystem does not support clones
+func cloneDirectory(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case info.IsDir():
+			return os.Mkdir(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			return cloneFile(path, target, info.Mode().Perm())
+		}
+	})
+}
+
+func cloneFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, out.Fd(), ficlone, in.Fd()); errno != 0 {
+		switch errno {
+		case syscall.EOPNOTSUPP, syscall.EXDEV, syscall.EINVAL, syscall.ENOTTY:
+			return errCloneNotSupported
+		}
+		return &os.PathError{Op: "clone", Path: dst, Err: errno}
+	}
+	return nil
+}
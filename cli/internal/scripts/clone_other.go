@@ -0,0 +1,9 @@
+// +build !linux,!darwin
+
+package scripts
+
+// cloneDirectory is not supported on this platform, the
+// project directory is staged using a regular copy
+func cloneDirectory(src, dst string) error {
+	return errCloneNotSupported
+}
@@ -0,0 +1,36 @@
+package scripts
+
+import (
+	"fmt"
+
+	"github.com/hasura/graphql-engine/cli"
+)
+
+// ValidateConfigSchema checks if the config has the fields expected by
+// the config version it claims to be
+func ValidateConfigSchema(cfg *cli.Config) error {
+	if cfg == nil {
+		return fmt.Errorf("config is empty")
+	}
+	if !cfg.Version.IsValid() {
+		return cli.ErrInvalidConfigVersion
+	}
+	if len(cfg.Endpoint) == 0 {
+		return fmt.Errorf("config v%d: endpoint is required", cfg.Version)
+	}
+	switch cfg.Version {
+	case cli.V1:
+		// config v1 stores metadata along with migrations
+		if len(cfg.MetadataDirectory) != 0 {
+			return fmt.Errorf("config v1: metadata_directory is not supported, found %q", cfg.MetadataDirectory)
+		}
+	case cli.V2, cli.V3:
+		if len(cfg.MetadataDirectory) == 0 {
+			return fmt.Errorf("config v%d: metadata_directory is required", cfg.Version)
+		}
+		if len(cfg.MigrationsDirectory) == 0 {
+			return fmt.Errorf("config v%d: migrations_directory is required", cfg.Version)
+		}
+	}
+	return nil
+}
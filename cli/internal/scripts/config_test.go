@@ -0,0 +1,64 @@
+package scripts
+
+import (
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateConfigSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *cli.Config
+		wantErr bool
+	}{
+		{
+			"can validate a config v2",
+			&cli.Config{
+				Version:             cli.V2,
+				ServerConfig:        cli.ServerConfig{Endpoint: "http://localhost:8080"},
+				MetadataDirectory:   "metadata",
+				MigrationsDirectory: "migrations",
+			},
+			false,
+		},
+		{
+			"fails when config v2 has no metadata directory",
+			&cli.Config{
+				Version:             cli.V2,
+				ServerConfig:        cli.ServerConfig{Endpoint: "http://localhost:8080"},
+				MigrationsDirectory: "migrations",
+			},
+			true,
+		},
+		{
+			"fails when config v1 has a metadata directory",
+			&cli.Config{
+				Version:             cli.V1,
+				ServerConfig:        cli.ServerConfig{Endpoint: "http://localhost:8080"},
+				MetadataDirectory:   "metadata",
+				MigrationsDirectory: "migrations",
+			},
+			true,
+		},
+		{
+			"fails on an invalid version",
+			&cli.Config{
+				Version:      cli.ConfigVersion(0),
+				ServerConfig: cli.ServerConfig{Endpoint: "http://localhost:8080"},
+			},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfigSchema(tt.cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package scripts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/hasura/graphql-engine/cli/internal/metadatautil"
+	"github.com/hasura/graphql-engine/cli/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// FindDanglingSourceDirs returns the per source migrations and seeds directories
+// of the project which do not belong to any of the sources in the server metadata,
+// eg: directories left behind after a source is removed.
+// The project is expected to be in config V3
+func FindDanglingSourceDirs(ec *cli.ExecutionContext, fs afero.Fs) ([]string, error) {
+	if ec.Config.Version < cli.V3 {
+		return nil, fmt.Errorf("per database directories are only used with config V3")
+	}
+	sources, err := metadatautil.GetSources(ec.APIClient.V1Metadata.ExportMetadata)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting list of databases")
+	}
+	return findDanglingSourceDirs(fs, sources, ec.MigrationDir, ec.SeedsDirectory)
+}
+
+func findDanglingSourceDirs(fs afero.Fs, sources []string, parentDirs ...string) ([]string, error) {
+	known := make(map[string]struct{}, len(sources))
+	for _, source := range sources {
+		known[source] = struct{}{}
+	}
+	var dangling []string
+	for _, parentDir := range parentDirs {
+		infos, err := afero.ReadDir(fs, parentDir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "reading %s", parentDir)
+		}
+		for _, info := range infos {
+			if !info.IsDir() {
+				continue
+			}
+			// migrations which were not moved to a database directory,
+			// including those with a legacy timestamp, are not source directories
+			isMigration, err := isMigrationWithLegacyTimestamp(info.Name())
+			if err != nil {
+				return nil, err
+			}
+			if isMigration {
+				continue
+			}
+			if _, ok := known[info.Name()]; !ok {
+				dangling = append(dangling, filepath.Join(parentDir, info.Name()))
+			}
+		}
+	}
+	sort.Strings(dangling)
+	return dangling, nil
+}
+
+// CleanupDanglingSourceDirs removes dirs, as returned by FindDanglingSourceDirs.
+// When archiveDir is set the directories are moved into it instead, keeping
+// their path relative to projectDirectory
+func CleanupDanglingSourceDirs(fs afero.Fs, projectDirectory string, dirs []string, archiveDir string) error {
+	for _, dir := range dirs {
+		if len(archiveDir) > 0 {
+			rel, err := filepath.Rel(projectDirectory, dir)
+			if err != nil {
+				return err
+			}
+			dst := filepath.Join(archiveDir, rel)
+			if err := fs.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+				return err
+			}
+			if err := util.CopyDirAfero(fs, dir, dst); err != nil {
+				return errors.Wrapf(err, "archiving %s to %s", dir, dst)
+			}
+		}
+		if err := fs.RemoveAll(dir); err != nil {
+			return errors.Wrapf(err, "removing %s", dir)
+		}
+	}
+	return nil
+}
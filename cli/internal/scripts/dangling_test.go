@@ -0,0 +1,44 @@
+package scripts
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDanglingSourceDirs(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for _, file := range []string{
+		"project/migrations/default/1604855964903_test/up.sql",
+		"project/migrations/removed/1604855964904_test/up.sql",
+		"project/migrations/1604855964905_not_moved/up.sql",
+		"project/migrations/1604855964_legacy/up.sql",
+		"project/seeds/default/seed.sql",
+		"project/seeds/removed/seed.sql",
+		"project/seeds/seed.sql",
+	} {
+		if err := afero.WriteFile(fs, file, []byte("SELECT 1;"), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := findDanglingSourceDirs(fs, []string{"default"}, "project/migrations", "project/seeds", "project/missing")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"project/migrations/removed", "project/seeds/removed"}, got)
+
+	assert.NoError(t, CleanupDanglingSourceDirs(fs, "project", got, "archive"))
+	for _, dir := range got {
+		_, err := fs.Stat(dir)
+		assert.True(t, os.IsNotExist(err))
+	}
+	for _, file := range []string{
+		"archive/migrations/removed/1604855964904_test/up.sql",
+		"archive/seeds/removed/seed.sql",
+	} {
+		_, err := fs.Stat(file)
+		assert.NoError(t, err)
+	}
+	_, err = fs.Stat("project/migrations/default")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,198 @@
+package scripts
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hasura/graphql-engine/cli/internal/statestore"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v2"
+)
+
+// DatabaseMappingRule assigns the migrations and seed files of a project
+// whose name matches one of the patterns (using filepath.Match syntax,
+// eg: "*_create_orders_table") to Database. A database mapping file is a
+// YAML list of rules, eg:
+//
+//   - database: orders
+//     migrations: ["*_orders_*"]
+//     seeds: ["orders*.sql"]
+//   - database: analytics
+//     prefixes: ["analytics_"]
+type DatabaseMappingRule struct {
+	Database   string   `yaml:"database"`
+	Migrations []string `yaml:"migrations,omitempty"`
+	Seeds      []string `yaml:"seeds,omitempty"`
+	// Prefixes match migrations whose name without the version (eg:
+	// analytics_events of 1604855964903_analytics_events) and seed
+	// files whose name starts with one of them
+	Prefixes []string `yaml:"prefixes,omitempty"`
+}
+
+// databaseRoutes decides the database each migration and seed file is moved
+// to, using the first rule which matches its name, or defaultDatabase
+// when no rule matches
+type databaseRoutes struct {
+	defaultDatabase string
+	rules           []DatabaseMappingRule
+}
+
+// ParseDatabasePrefixes parses rules of the form <database>=<prefix>, eg:
+// default=users_ or analytics=events_, into a DatabaseMappingRule per
+// database, keeping the order of the databases
+func ParseDatabasePrefixes(values []string) ([]DatabaseMappingRule, error) {
+	var rules []DatabaseMappingRule
+	indexes := map[string]int{}
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			return nil, fmt.Errorf("invalid database prefix %q, has to be of the form <database>=<prefix>", value)
+		}
+		database, prefix := parts[0], parts[1]
+		idx, ok := indexes[database]
+		if !ok {
+			idx = len(rules)
+			indexes[database] = idx
+			rules = append(rules, DatabaseMappingRule{Database: database})
+		}
+		rules[idx].Prefixes = append(rules[idx].Prefixes, prefix)
+	}
+	return rules, nil
+}
+
+func readDatabaseMapping(fs afero.Fs, path string) ([]DatabaseMappingRule, error) {
+	b, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading database mapping")
+	}
+	var rules []DatabaseMappingRule
+	if err := yaml.UnmarshalStrict(b, &rules); err != nil {
+		return nil, errors.Wrapf(err, "parsing database mapping %s", path)
+	}
+	return rules, nil
+}
+
+// newDatabaseRoutes validates rules, every database a rule refers to has to be one of sources
+func newDatabaseRoutes(rules []DatabaseMappingRule, defaultDatabase string, sources []string) (*databaseRoutes, error) {
+	if len(rules) > 0 && len(sources) == 0 {
+		return nil, fmt.Errorf("databases could not be listed from the server, which is required to use a database mapping")
+	}
+	known := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		known[source] = true
+	}
+	for idx, rule := range rules {
+		if !known[rule.Database] {
+			return nil, fmt.Errorf("database mapping rule %d: database %q is not one of the databases on the server %v", idx+1, rule.Database, sources)
+		}
+		if err := ValidateSourceDirectoryName(rule.Database); err != nil {
+			return nil, err
+		}
+		for _, pattern := range append(append([]string{}, rule.Migrations...), rule.Seeds...) {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("database mapping rule %d: invalid pattern %q: %w", idx+1, pattern, err)
+			}
+		}
+	}
+	return &databaseRoutes{defaultDatabase: defaultDatabase, rules: rules}, nil
+}
+
+// route returns the database of the first rule with a pattern matching
+// name or a prefix of prefixed, which is name without its version for migrations
+func (r *databaseRoutes) route(name, prefixed string, patterns func(DatabaseMappingRule) []string) string {
+	for _, rule := range r.rules {
+		for _, pattern := range patterns(rule) {
+			if ok, _ := filepath.Match(pattern, name); ok {
+				return rule.Database
+			}
+		}
+		for _, prefix := range rule.Prefixes {
+			if strings.HasPrefix(prefixed, prefix) {
+				return rule.Database
+			}
+		}
+	}
+	return r.defaultDatabase
+}
+
+// migrationDatabase returns the database migration directory name is moved to
+func (r *databaseRoutes) migrationDatabase(name string) string {
+	prefixed := name
+	if parts := strings.SplitN(name, "_", 2); len(parts) == 2 {
+		prefixed = parts[1]
+	}
+	return r.route(name, prefixed, func(rule DatabaseMappingRule) []string { return rule.Migrations })
+}
+
+// seedDatabase returns the database seed file name is moved to
+func (r *databaseRoutes) seedDatabase(name string) string {
+	return r.route(name, name, func(rule DatabaseMappingRule) []string { return rule.Seeds })
+}
+
+// databases returns the databases migrations and seeds can be moved
+// to, starting with the default database
+func (r *databaseRoutes) databases() []string {
+	databases := []string{r.defaultDatabase}
+	seen := map[string]bool{r.defaultDatabase: true}
+	for _, rule := range r.rules {
+		if !seen[rule.Database] {
+			seen[rule.Database] = true
+			databases = append(databases, rule.Database)
+		}
+	}
+	return databases
+}
+
+// groupByDatabase groups names by the database returned for them by route,
+// keeping the order of names
+func groupByDatabase(names []string, route func(string) string) map[string][]string {
+	groups := map[string][]string{}
+	for _, name := range names {
+		database := route(name)
+		groups[database] = append(groups[database], name)
+	}
+	return groups
+}
+
+// pruneRoutedVersions removes the versions in the state of each of databases
+// which are not routed to it, since state is copied to all of databases, which
+// are the databases of routes holding migrations state. Versions are routed
+// using the name of their migration directory in migrations, versions without
+// a migration directory are kept only in the state of the default database
+func pruneRoutedVersions(store statestore.MigrationsStateStore, routes *databaseRoutes, databases, migrations []string) error {
+	names := make(map[uint64]string, len(migrations))
+	for _, name := range migrations {
+		version, err := getMigrationVersion(name)
+		if err != nil {
+			return errors.Wrapf(err, "parsing version of migration %s", name)
+		}
+		names[version] = name
+	}
+	for _, database := range databases {
+		state, err := store.GetVersions(database)
+		if err != nil {
+			return err
+		}
+		versions := make([]uint64, 0, len(state))
+		for version := range state {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+		for _, version := range versions {
+			target := routes.defaultDatabase
+			if name, ok := names[version]; ok {
+				target = routes.migrationDatabase(name)
+			}
+			if target == database {
+				continue
+			}
+			if err := store.RemoveVersion(database, int64(version)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package scripts
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_databaseRoutes(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	mapping := `
+- database: orders
+  migrations: ["*_orders_*"]
+  seeds: ["orders*.sql"]
+- database: analytics
+  migrations: ["*_events"]
+`
+	assert.NoError(t, afero.WriteFile(fs, "mapping.yaml", []byte(mapping), 0644))
+	rules, err := readDatabaseMapping(fs, "mapping.yaml")
+	assert.NoError(t, err)
+
+	routes, err := newDatabaseRoutes(rules, "default", []string{"default", "orders", "analytics"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"default", "orders", "analytics"}, routes.databases())
+	assert.Equal(t, "orders", routes.migrationDatabase("1604855964903_create_orders_table"))
+	assert.Equal(t, "analytics", routes.migrationDatabase("1604855964904_track_events"))
+	assert.Equal(t, "default", routes.migrationDatabase("1604855964905_create_users"))
+	assert.Equal(t, "orders", routes.seedDatabase("orders_2020.sql"))
+	assert.Equal(t, "default", routes.seedDatabase("users.sql"))
+
+	_, err = newDatabaseRoutes(rules, "default", []string{"default", "orders"})
+	assert.Error(t, err, "analytics is not a database on the server")
+	_, err = newDatabaseRoutes(rules, "default", nil)
+	assert.Error(t, err)
+	_, err = newDatabaseRoutes([]DatabaseMappingRule{{Database: "default", Seeds: []string{"["}}}, "default", []string{"default"})
+	assert.Error(t, err)
+}
+
+func Test_pruneRoutedVersions(t *testing.T) {
+	routes := &databaseRoutes{
+		defaultDatabase: "default",
+		rules:           []DatabaseMappingRule{{Database: "orders", Migrations: []string{"*_orders"}}},
+	}
+	// 1604855964902 has no migration directory, it is kept in the default database
+	store := fakeMigrationsStateStore{
+		"default": {1604855964902: false, 1604855964903: false, 1604855964904: false, 1604855964905: false},
+		"orders":  {1604855964902: false, 1604855964903: false, 1604855964904: false, 1604855964905: false},
+	}
+	assert.NoError(t, pruneRoutedVersions(store, routes, routes.databases(), []string{"1604855964903_users", "1604855964904_orders", "1604855964905_old_orders"}))
+	assert.Equal(t, fakeMigrationsStateStore{
+		"default": {1604855964902: false, 1604855964903: false},
+		"orders":  {1604855964904: false, 1604855964905: false},
+	}, store)
+}
+
+func TestParseDatabasePrefixes(t *testing.T) {
+	rules, err := ParseDatabasePrefixes([]string{"analytics=events_", "orders=orders_", "analytics=tracking_"})
+	assert.NoError(t, err)
+	assert.Equal(t, []DatabaseMappingRule{
+		{Database: "analytics", Prefixes: []string{"events_", "tracking_"}},
+		{Database: "orders", Prefixes: []string{"orders_"}},
+	}, rules)
+
+	routes, err := newDatabaseRoutes(rules, "default", []string{"default", "orders", "analytics"})
+	assert.NoError(t, err)
+	assert.Equal(t, "analytics", routes.migrationDatabase("1604855964903_events_table"))
+	assert.Equal(t, "orders", routes.migrationDatabase("1604855964904_orders_table"))
+	assert.Equal(t, "default", routes.migrationDatabase("1604855964905_create_orders_table"))
+	assert.Equal(t, "analytics", routes.seedDatabase("tracking_2020.sql"))
+	assert.Equal(t, "default", routes.seedDatabase("users.sql"))
+
+	for _, value := range []string{"analytics", "=events_", "analytics="} {
+		_, err := ParseDatabasePrefixes([]string{value})
+		assert.Error(t, err, value)
+	}
+}
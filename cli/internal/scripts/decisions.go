@@ -0,0 +1,76 @@
+package scripts
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// Decision is an entry of the decision log of UpdateProjectV3,
+// recording a choice made during the update and the reason for it
+type Decision struct {
+	Step     string   `json:"step"`
+	Decision string   `json:"decision"`
+	Reason   string   `json:"reason,omitempty"`
+	Items    []string `json:"items,omitempty"`
+}
+
+// decisionLog collects decisions in the order in which they are made,
+// a nil *decisionLog discards them
+type decisionLog struct {
+	decisions []Decision
+}
+
+func newDecisionLog(enabled bool) *decisionLog {
+	if !enabled {
+		return nil
+	}
+	return &decisionLog{decisions: []Decision{}}
+}
+
+func (l *decisionLog) record(step, decision, reason string, items ...string) {
+	if l == nil {
+		return
+	}
+	l.decisions = append(l.decisions, Decision{Step: step, Decision: decision, Reason: reason, Items: items})
+}
+
+// write writes the decisions as a JSON array to path
+func (l *decisionLog) write(fs afero.Fs, path string) error {
+	if l == nil {
+		return nil
+	}
+	b, err := json.MarshalIndent(l.decisions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := fs.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	if err := afero.WriteFile(fs, path, b, 0644); err != nil {
+		return errors.Wrap(err, "writing decision log")
+	}
+	return nil
+}
+
+// skippedEntries returns names of the entries of dir which are not in selected
+func skippedEntries(fs afero.Fs, dir string, selected []string) ([]string, error) {
+	infos, err := afero.ReadDir(fs, dir)
+	if err != nil {
+		return nil, err
+	}
+	isSelected := make(map[string]bool, len(selected))
+	for _, name := range selected {
+		isSelected[name] = true
+	}
+	var skipped []string
+	for _, info := range infos {
+		if !isSelected[info.Name()] {
+			skipped = append(skipped, info.Name())
+		}
+	}
+	return skipped, nil
+}
@@ -0,0 +1,39 @@
+package scripts
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_decisionLog(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, fs.MkdirAll("migrations/1604855964903_test", 0755))
+	assert.NoError(t, fs.MkdirAll("migrations/randomdir", 0755))
+
+	log := newDecisionLog(true)
+	log.record("target_database", "default", "chosen by the user from the databases found", "default", "other")
+	skipped, err := skippedEntries(fs, "migrations", []string{"1604855964903_test"})
+	assert.NoError(t, err)
+	log.record("migrations", "skip", "name does not match <timestamp>_<name>", skipped...)
+	assert.NoError(t, log.write(fs, "logs/decisions.json"))
+
+	b, err := afero.ReadFile(fs, "logs/decisions.json")
+	assert.NoError(t, err)
+	var got []Decision
+	assert.NoError(t, json.Unmarshal(b, &got))
+	assert.Equal(t, []Decision{
+		{Step: "target_database", Decision: "default", Reason: "chosen by the user from the databases found", Items: []string{"default", "other"}},
+		{Step: "migrations", Decision: "skip", Reason: "name does not match <timestamp>_<name>", Items: []string{"randomdir"}},
+	}, got)
+
+	// a disabled log discards decisions
+	disabled := newDecisionLog(false)
+	disabled.record("migrations", "move", "")
+	assert.NoError(t, disabled.write(fs, "disabled.json"))
+	exists, err := afero.Exists(fs, "disabled.json")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
@@ -0,0 +1,15 @@
+// +build !windows
+
+package scripts
+
+import "syscall"
+
+// availableDiskSpace returns the number of bytes available to the user
+// on the filesystem containing path
+func availableDiskSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,20 @@
+package scripts
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// availableDiskSpace returns the number of bytes available to the user
+// on the filesystem containing path
+func availableDiskSpace(path string) (uint64, error) {
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	getDiskFreeSpaceEx := kernel32.NewProc("GetDiskFreeSpaceExW")
+
+	var available uint64
+	r1, _, err := getDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr(path))), uintptr(unsafe.Pointer(&available)), 0, 0)
+	if r1 == 0 {
+		return 0, err
+	}
+	return available, nil
+}
@@ -0,0 +1,188 @@
+package scripts
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/hasura/graphql-engine/cli/internal/metadataobject"
+	"github.com/hasura/graphql-engine/cli/internal/statestore"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// dryRunUpdate logs the operations UpdateProjectV3 would run to move the project
+// to the databases of routes without running them. State stores are only read from, to
+// check that state can be copied, and metadata is exported to memory to list
+// the files which would be written to the project
+func dryRunUpdate(opts UpgradeToMuUpgradeProjectToMultipleSourcesOpts, sources []string, routes *databaseRoutes, migrationDirs []string, decisions *decisionLog) error {
+	targetDatabase := routes.defaultDatabase
+	log := func(format string, args ...interface{}) {
+		opts.Logger.Infof("[dry-run] "+format, args...)
+	}
+	switch {
+	case opts.Offline:
+		log("state would not be copied, update is offline")
+	case len(sources) == 0:
+		log("state would not be copied, no databases were found")
+	default:
+		stateStore := opts.StateStore
+		if len(stateStore) == 0 {
+			stateStore = statestore.StateStoreCatalog
+		}
+		var copied bool
+		var method string
+		var err error
+		if stateStore == statestore.StateStoreCatalog && !opts.ForceStateCopy {
+			copied, method, err = DetectPriorStateCopy(opts.EC, targetDatabase)
+			if err != nil {
+				return err
+			}
+		}
+		if copied {
+			log("state would not be copied, it was already copied (detected using %s)", method)
+		} else {
+			src := projectStateStore(opts.EC)
+			if err := probeStateStores(stateStoreOptions(opts.EC), src); err != nil {
+				return errors.Wrapf(err, "reading state from state store %s", src)
+			}
+			for _, database := range routes.databases() {
+				log("state would be copied from state store %s to %s for database %s", src, stateStore, database)
+			}
+		}
+	}
+
+	seedFiles, err := getSeedFiles(opts.Fs, opts.SeedsAbsDirectoryPath)
+	if err != nil {
+		return errors.Wrap(err, "getting list of seed files to move")
+	}
+	// names of the seed files in their target directories, in the order of seedFiles
+	seedDestinationNames := map[string]string{}
+	for database, files := range groupByDatabase(seedFiles, routes.seedDatabase) {
+		names, err := seedDestinations(opts.Fs, files, filepath.Join(opts.SeedsAbsDirectoryPath, database), opts.SeedConflictStrategy)
+		if err != nil {
+			return err
+		}
+		for idx, file := range files {
+			seedDestinationNames[file] = names[idx]
+		}
+	}
+	var metadataDir string
+	if !opts.Offline {
+		metadataDir = opts.EC.MetadataDir
+	}
+	operations := planProjectChanges(opts.Fs, projectChanges{
+		routes:              routes,
+		migrationsDirectory: opts.MigrationsAbsDirectoryPath,
+		migrations:          migrationDirs,
+		seedsDirectory:      opts.SeedsAbsDirectoryPath,
+		seeds:               seedFiles,
+		seedDestinations:    seedDestinationNames,
+		metadataDirectory:   metadataDir,
+		configFile:          opts.EC.ConfigFile,
+		upgradeInfoFile:     filepath.Join(opts.ProjectDirectory, UpgradeInfoFile),
+	})
+	for _, operation := range operations {
+		log(operation)
+	}
+
+	if opts.Offline {
+		log("metadata would not be exported, update is offline")
+	} else {
+		mdHandler := opts.MetadataHandler
+		if mdHandler == nil {
+			handler := metadataobject.NewHandlerFromEC(opts.EC)
+			handler.SetExportConcurrency(opts.ExportConcurrency)
+			mdHandler = handler
+		}
+		// the export is staged in memory, so that nothing is written to the project
+		stagingDir := filepath.Join(opts.ProjectDirectory, metadataobject.ExportStagingDirectory)
+		files, err := mdHandler.ExportMetadataResumable(afero.NewMemMapFs(), stagingDir, false)
+		if err != nil {
+			return errors.Wrap(err, "exporting metadata")
+		}
+		names := make([]string, 0, len(files))
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			log("write metadata file %s", name)
+		}
+	}
+	decisions.record("dry_run", "stop", "--dry-run was set, project was not updated")
+	opts.Logger.Info("dry run complete, project was not updated")
+	return nil
+}
+
+// probeStateStores checks that migrations state and settings can be read from
+// the state stores registered as name, without preparing or writing to them
+func probeStateStores(storeOpts statestore.StateStoreOptions, name string) error {
+	migrationsStore, err := statestore.NewMigrationsStateStore(name, storeOpts)
+	if err != nil {
+		return err
+	}
+	if _, err := migrationsStore.GetVersions(""); err != nil {
+		return errors.Wrap(err, "reading migrations state")
+	}
+	settingsStore, err := statestore.NewSettingsStateStore(name, storeOpts)
+	if err != nil {
+		return err
+	}
+	if _, err := settingsStore.GetAllSettings(); err != nil {
+		return errors.Wrap(err, "reading settings state")
+	}
+	return nil
+}
+
+// projectChanges describes the changes made to the project directory by UpdateProjectV3
+type projectChanges struct {
+	// routes decides the database directory each migration and seed file is moved to
+	routes *databaseRoutes
+
+	migrationsDirectory string
+	migrations          []string
+
+	seedsDirectory string
+	seeds          []string
+	// seedDestinations are the names of seeds in their target directory,
+	// keyed by the name of the seed
+	seedDestinations map[string]string
+
+	// metadataDirectory when not empty is the directory from which
+	// metadata files no longer used in config v3 are deleted
+	metadataDirectory string
+	configFile        string
+	// upgradeInfoFile when not empty is the path UpgradeInfo is written to
+	upgradeInfoFile string
+}
+
+// planProjectChanges returns a description of each file operation
+// done on the project directory to make changes, in the order they are done.
+// The paths are the same as the ones used by UpdateProjectV3 to copy files
+func planProjectChanges(fs afero.Fs, changes projectChanges) []string {
+	var operations []string
+	for _, dir := range changes.migrations {
+		operations = append(operations, fmt.Sprintf("move migration %s to %s",
+			filepath.Join(changes.migrationsDirectory, dir), filepath.Join(changes.migrationsDirectory, changes.routes.migrationDatabase(dir), dir)))
+	}
+	for _, file := range changes.seeds {
+		operations = append(operations, fmt.Sprintf("move seed file %s to %s",
+			filepath.Join(changes.seedsDirectory, file), filepath.Join(changes.seedsDirectory, changes.routes.seedDatabase(file), changes.seedDestinations[file])))
+	}
+	for _, field := range configV3Fields(changes.routes.defaultDatabase) {
+		operations = append(operations, fmt.Sprintf("set %v to %v in %s", field.Key, field.Value, changes.configFile))
+	}
+	if len(changes.upgradeInfoFile) > 0 {
+		operations = append(operations, fmt.Sprintf("write the database each migration and seed file was moved to in %s", changes.upgradeInfoFile))
+	}
+	if len(changes.metadataDirectory) > 0 {
+		for _, file := range []string{"functions.yaml", "tables.yaml"} {
+			path := filepath.Join(changes.metadataDirectory, file)
+			if ok, _ := afero.Exists(fs, path); ok {
+				operations = append(operations, fmt.Sprintf("delete %s", path))
+			}
+		}
+	}
+	return operations
+}
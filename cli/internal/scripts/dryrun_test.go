@@ -0,0 +1,41 @@
+package scripts
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_planProjectChanges(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(fs, "metadata/tables.yaml", []byte("[]"), 0644))
+	got := planProjectChanges(fs, projectChanges{
+		routes: &databaseRoutes{
+			defaultDatabase: "default",
+			rules:           []DatabaseMappingRule{{Database: "orders", Migrations: []string{"*_orders"}, Seeds: []string{"orders*"}}},
+		},
+		migrationsDirectory: "migrations",
+		migrations:          []string{"1604855964903_test", "1604855964904_orders"},
+		seedsDirectory:      "seeds",
+		seeds:               []string{"users.sql", "orders.sql"},
+		seedDestinations:    map[string]string{"users.sql": "users_1.sql", "orders.sql": "orders.sql"},
+		metadataDirectory:   "metadata",
+		configFile:          "config.yaml",
+		upgradeInfoFile:     UpgradeInfoFile,
+	})
+	assert.Equal(t, []string{
+		"move migration migrations/1604855964903_test to migrations/default/1604855964903_test",
+		"move migration migrations/1604855964904_orders to migrations/orders/1604855964904_orders",
+		"move seed file seeds/users.sql to seeds/default/users_1.sql",
+		"move seed file seeds/orders.sql to seeds/orders/orders.sql",
+		"set version to 3 in config.yaml",
+		"set default_source to default in config.yaml",
+		"write the database each migration and seed file was moved to in " + UpgradeInfoFile,
+		"delete metadata/tables.yaml",
+	}, got)
+	// nothing is changed
+	ok, err := afero.Exists(fs, "metadata/tables.yaml")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
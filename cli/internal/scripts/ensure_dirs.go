@@ -0,0 +1,46 @@
+package scripts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/hasura/graphql-engine/cli/internal/metadatautil"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// EnsureSourceDirectories creates the migrations and seeds directories of
+// every source in the server metadata which does not have them yet, eg: after
+// a new source is added. The project is expected to be in config V3
+func EnsureSourceDirectories(ec *cli.ExecutionContext, fs afero.Fs) error {
+	if ec.Config.Version < cli.V3 {
+		return fmt.Errorf("per database directories are only used with config V3")
+	}
+	sources, err := metadatautil.GetSources(ec.APIClient.V1Metadata.ExportMetadata)
+	if err != nil {
+		return errors.Wrap(err, "getting list of databases")
+	}
+	return ensureSourceDirectories(fs, sources, ec.MigrationDir, ec.SeedsDirectory)
+}
+
+func ensureSourceDirectories(fs afero.Fs, sources []string, parentDirs ...string) error {
+	for _, parentDir := range parentDirs {
+		for _, source := range sources {
+			if err := ValidateSourceDirectoryName(source); err != nil {
+				return err
+			}
+			dir := filepath.Join(parentDir, source)
+			if _, err := fs.Stat(dir); err == nil {
+				continue
+			} else if !os.IsNotExist(err) {
+				return err
+			}
+			if err := fs.MkdirAll(dir, 0755); err != nil {
+				return errors.Wrapf(err, "creating %s", dir)
+			}
+		}
+	}
+	return nil
+}
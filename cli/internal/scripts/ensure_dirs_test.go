@@ -0,0 +1,26 @@
+package scripts
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ensureSourceDirectories(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	if err := afero.WriteFile(fs, "migrations/default/1604855964903_test/up.sql", []byte("SELECT 1;"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, ensureSourceDirectories(fs, []string{"default", "new"}, "migrations", "seeds"))
+	for _, dir := range []string{"migrations/default", "migrations/new", "seeds/default", "seeds/new"} {
+		info, err := fs.Stat(dir)
+		if assert.NoError(t, err) {
+			assert.True(t, info.IsDir())
+		}
+	}
+	// existing directories are left as they are
+	_, err := fs.Stat("migrations/default/1604855964903_test/up.sql")
+	assert.NoError(t, err)
+}
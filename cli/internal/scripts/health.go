@@ -0,0 +1,103 @@
+package scripts
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/internal/hasura/v2query"
+	"github.com/hasura/graphql-engine/cli/internal/httpc"
+	"github.com/hasura/graphql-engine/cli/internal/metadatautil"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// CheckSourceHealth checks if the server is able to run a trivial query on the source.
+// Sources of kinds on which queries cannot be run by the CLI are assumed to be healthy
+func CheckSourceHealth(ec *cli.ExecutionContext, source metadatautil.Source) error {
+	return checkSourceHealth(ec.APIClient.V2Query, ec.Logger, source)
+}
+
+// healthCheckConcurrency is the number of sources checked concurrently
+// by CheckAllSourcesHealthy, so that the server is not flooded with
+// requests on deployments with many sources
+const healthCheckConcurrency = 8
+
+// CheckAllSourcesHealthy checks the health of all sources on the server concurrently
+// and returns the result of the check keyed by source name, a nil error meaning healthy
+func CheckAllSourcesHealthy(ec *cli.ExecutionContext) (map[string]error, error) {
+	sources, err := metadatautil.GetSourcesAndKind(ec.APIClient.V1Metadata.ExportMetadata)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing databases")
+	}
+	return checkSourcesHealth(sources, healthCheckConcurrency, func(source metadatautil.Source) error {
+		// requests made using the same httpc.Client are serialized,
+		// so each check uses a client of its own
+		client, err := newV2QueryClient(ec)
+		if err != nil {
+			return err
+		}
+		return checkSourceHealth(client, ec.Logger, source)
+	}), nil
+}
+
+// checkSourcesHealth calls check for each of sources, using up to
+// workers goroutines, and returns the results keyed by source name
+func checkSourcesHealth(sources []metadatautil.Source, workers int, check func(source metadatautil.Source) error) map[string]error {
+	if workers < 1 {
+		workers = 1
+	}
+	results := make(map[string]error, len(sources))
+	var mu sync.Mutex
+	semaphore := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for _, source := range sources {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(source metadatautil.Source) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			err := check(source)
+			mu.Lock()
+			defer mu.Unlock()
+			results[source.Name] = err
+		}(source)
+	}
+	wg.Wait()
+	return results
+}
+
+func checkSourceHealth(client hasura.V2Query, logger *logrus.Logger, source metadatautil.Source) error {
+	const query = "SELECT 1"
+	var err error
+	switch source.Kind {
+	case hasura.SourceKindPG, "citus":
+		_, err = client.PGRunSQL(hasura.PGRunSQLInput{SQL: query, Source: source.Name, ReadOnly: true})
+	case hasura.SourceKindMSSQL:
+		_, err = client.MSSQLRunSQL(hasura.MSSQLRunSQLInput{SQL: query, Source: source.Name})
+	default:
+		logger.Debugf("skipping health check of source %s of kind %s", source.Name, source.Kind)
+	}
+	if err != nil {
+		return fmt.Errorf("source %s is not reachable: %w", source.Name, err)
+	}
+	return nil
+}
+
+func newV2QueryClient(ec *cli.ExecutionContext) (hasura.V2Query, error) {
+	httpClient, err := httpc.New(
+		&http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: ec.Config.TLSConfig,
+			},
+		},
+		ec.Config.Endpoint,
+		ec.HGEHeaders,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return v2query.New(httpClient, ec.Config.GetV2QueryEndpoint()), nil
+}
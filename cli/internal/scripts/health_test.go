@@ -0,0 +1,47 @@
+package scripts
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hasura/graphql-engine/cli/internal/metadatautil"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_checkSourcesHealth(t *testing.T) {
+	var sources []metadatautil.Source
+	for idx := 0; idx < 10; idx++ {
+		sources = append(sources, metadatautil.Source{Name: fmt.Sprintf("db%d", idx)})
+	}
+	var mu sync.Mutex
+	var running, maxRunning int
+	results := checkSourcesHealth(sources, 3, func(source metadatautil.Source) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if source.Name == "db4" {
+			return errors.New("source db4 is not reachable")
+		}
+		return nil
+	})
+	// no more than workers sources are checked at a time
+	assert.LessOrEqual(t, maxRunning, 3)
+	assert.Len(t, results, len(sources))
+	for _, source := range sources {
+		if source.Name == "db4" {
+			assert.Error(t, results[source.Name])
+			continue
+		}
+		assert.NoError(t, results[source.Name], source.Name)
+	}
+}
@@ -0,0 +1,168 @@
+package scripts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hasura/graphql-engine/cli/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// UpdateJournalFile is created in the project directory by UpdateProjectV3,
+// recording the changes made to the project directory and where the files
+// changed were backed up, so that they can be restored. It is removed once
+// the update completes
+const UpdateJournalFile = ".update-project-v3-journal.json"
+
+// operations recorded in the update journal
+const (
+	// Path was created by the update
+	journalCreate = "create"
+	// Path was changed or removed by the update, Backup holds the original
+	journalReplace = "replace"
+)
+
+type journalEntry struct {
+	Op     string `json:"op"`
+	Path   string `json:"path"`
+	Backup string `json:"backup,omitempty"`
+}
+
+// updateJournal records the changes made to the project directory by
+// UpdateProjectV3, backing up files to BackupDirectory (.hasura-backup-<timestamp>
+// in the project directory) before they are changed. The journal is written to disk
+// after each change, so that an update which did not complete can be rolled back later
+type updateJournal struct {
+	fs               afero.Fs
+	projectDirectory string
+	BackupDirectory  string         `json:"backupDirectory"`
+	Entries          []journalEntry `json:"entries"`
+}
+
+func newUpdateJournal(fs afero.Fs, projectDirectory string, now time.Time) *updateJournal {
+	return &updateJournal{
+		fs:               fs,
+		projectDirectory: projectDirectory,
+		BackupDirectory:  filepath.Join(projectDirectory, fmt.Sprintf(".hasura-backup-%d", now.UnixNano()/int64(time.Millisecond))),
+		Entries:          []journalEntry{},
+	}
+}
+
+func readUpdateJournal(fs afero.Fs, projectDirectory string) (*updateJournal, error) {
+	b, err := afero.ReadFile(fs, filepath.Join(projectDirectory, UpdateJournalFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no update of the project to roll back")
+		}
+		return nil, err
+	}
+	journal := newUpdateJournal(fs, projectDirectory, time.Now())
+	if err := json.Unmarshal(b, journal); err != nil {
+		return nil, errors.Wrapf(err, "reading %s", UpdateJournalFile)
+	}
+	return journal, nil
+}
+
+func (j *updateJournal) save(entry journalEntry) error {
+	j.Entries = append(j.Entries, entry)
+	b, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	return afero.WriteFile(j.fs, filepath.Join(j.projectDirectory, UpdateJournalFile), b, 0644)
+}
+
+func (j *updateJournal) backup(path string) (string, error) {
+	backup := filepath.Join(j.BackupDirectory, fmt.Sprintf("%d_%s", len(j.Entries), filepath.Base(path)))
+	if err := copyPath(j.fs, path, backup); err != nil {
+		return "", errors.Wrapf(err, "backing up %s", path)
+	}
+	return backup, nil
+}
+
+// changed records that path is created, changed or removed, backing it up
+// when it exists. It has to be called before path is changed, it does nothing
+// on a nil journal
+func (j *updateJournal) changed(path string) error {
+	if j == nil {
+		// nothing is recorded when the update is staged
+		return nil
+	}
+	ok, err := afero.Exists(j.fs, path)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return j.save(journalEntry{Op: journalCreate, Path: path})
+	}
+	backup, err := j.backup(path)
+	if err != nil {
+		return err
+	}
+	return j.save(journalEntry{Op: journalReplace, Path: path, Backup: backup})
+}
+
+// restoreFromBackup undoes the changes recorded in journal in reverse order,
+// restoring changed files from their backup. The journal and the backup are
+// removed once done
+func restoreFromBackup(journal *updateJournal) error {
+	for idx := len(journal.Entries) - 1; idx >= 0; idx-- {
+		entry := journal.Entries[idx]
+		switch entry.Op {
+		case journalCreate, journalReplace:
+			if err := journal.fs.RemoveAll(entry.Path); err != nil {
+				return errors.Wrapf(err, "removing %s", entry.Path)
+			}
+			if len(entry.Backup) > 0 {
+				if err := copyPath(journal.fs, entry.Backup, entry.Path); err != nil {
+					return errors.Wrapf(err, "restoring %s", entry.Path)
+				}
+			}
+		default:
+			return fmt.Errorf("unknown operation %q in %s", entry.Op, UpdateJournalFile)
+		}
+	}
+	return journal.remove(false)
+}
+
+// remove removes the journal and unless keepBackup is set the backup,
+// once they are no longer needed
+func (j *updateJournal) remove(keepBackup bool) error {
+	if !keepBackup {
+		if err := j.fs.RemoveAll(j.BackupDirectory); err != nil {
+			return err
+		}
+	}
+	return j.fs.RemoveAll(filepath.Join(j.projectDirectory, UpdateJournalFile))
+}
+
+// RollbackUpdateProjectV3 restores the project directory as it was before an
+// update to config v3 which did not complete, using the journal and the backup
+// recorded by UpdateProjectV3. State copied to the server is not rolled back, it is
+// detected and not copied again when the update is run again
+func RollbackUpdateProjectV3(fs afero.Fs, projectDirectory string) error {
+	journal, err := readUpdateJournal(fs, projectDirectory)
+	if err != nil {
+		return err
+	}
+	return restoreFromBackup(journal)
+}
+
+// copyPath copies the file or directory at src to dst
+func copyPath(fs afero.Fs, src, dst string) error {
+	info, err := fs.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := fs.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return util.CopyDirAfero(fs, src, dst)
+	}
+	return util.CopyFileAfero(fs, src, dst)
+}
@@ -0,0 +1,79 @@
+package scripts
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollbackUpdateProjectV3(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for _, dir := range []string{"project/migrations/1604855964903_test", "project/metadata"} {
+		if err := fs.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, content := range map[string]string{
+		"project/config.yaml":                          "version: 2\n",
+		"project/metadata/tables.yaml":                 "[]",
+		"project/migrations/1604855964903_test/up.sql": "create table test();",
+		"project/seeds/users.sql":                      "insert into users values (1);",
+		"project/seeds/default/users.sql":              "insert into users values (2);",
+	} {
+		if err := afero.WriteFile(fs, name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// replay the changes made by an update
+	journal := newUpdateJournal(fs, "project", time.Unix(1604855964, 0))
+	for _, path := range []string{"project/migrations", "project/seeds", "project/config.yaml", "project/metadata", "project/" + OfflineUpdateMarkerFile} {
+		assert.NoError(t, journal.changed(path))
+	}
+	assert.Equal(t, "project/.hasura-backup-1604855964000", journal.BackupDirectory)
+	assert.NoError(t, copyMigration(fs, "1604855964903_test", "project/migrations", "project/migrations/default", nil))
+	assert.NoError(t, copyPath(fs, "project/seeds/users.sql", "project/seeds/default/users.sql"))
+	assert.NoError(t, afero.WriteFile(fs, "project/config.yaml", []byte("version: 3\n"), 0644))
+	assert.NoError(t, writeOfflineUpdateMarker(fs, "project", "default"))
+	assert.NoError(t, removeDirectories(fs, "project/migrations", []string{"1604855964903_test"}))
+	assert.NoError(t, removeDirectories(fs, "project/seeds", []string{"users.sql"}))
+	assert.NoError(t, removeDirectories(fs, "project/metadata", []string{"tables.yaml"}))
+
+	assert.NoError(t, RollbackUpdateProjectV3(fs, "project"))
+	for name, want := range map[string]string{
+		"project/config.yaml":                          "version: 2\n",
+		"project/metadata/tables.yaml":                 "[]",
+		"project/migrations/1604855964903_test/up.sql": "create table test();",
+		"project/seeds/users.sql":                      "insert into users values (1);",
+		"project/seeds/default/users.sql":              "insert into users values (2);",
+	} {
+		got, err := afero.ReadFile(fs, name)
+		assert.NoError(t, err)
+		assert.Equal(t, want, string(got), name)
+	}
+	for _, name := range []string{"project/migrations/default", "project/" + UpdateJournalFile, "project/" + OfflineUpdateMarkerFile, journal.BackupDirectory} {
+		ok, err := afero.Exists(fs, name)
+		assert.NoError(t, err)
+		assert.False(t, ok, name)
+	}
+
+	assert.Error(t, RollbackUpdateProjectV3(fs, "project"))
+}
+
+func Test_updateJournal_remove(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(fs, "project/config.yaml", []byte("version: 2\n"), 0644))
+	journal := newUpdateJournal(fs, "project", time.Now())
+	assert.NoError(t, journal.changed("project/config.yaml"))
+
+	assert.NoError(t, journal.remove(true))
+	ok, err := afero.Exists(fs, journal.BackupDirectory)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = afero.Exists(fs, "project/"+UpdateJournalFile)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,43 @@
+package scripts
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// normalizeLineEndings converts CRLF line endings of SQL files in dir to LF.
+// Files with both CRLF and LF line endings are reported, since they were
+// probably edited on different platforms
+func normalizeLineEndings(fs afero.Fs, dir string, logger *logrus.Logger) error {
+	return afero.Walk(fs, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.EqualFold(filepath.Ext(path), ".sql") {
+			return nil
+		}
+		b, err := afero.ReadFile(fs, path)
+		if err != nil {
+			return err
+		}
+		crlf := bytes.Count(b, []byte("\r\n"))
+		if crlf == 0 {
+			return nil
+		}
+		if bytes.Count(b, []byte("\n")) > crlf {
+			logger.Warnf("%s has mixed line endings", path)
+		}
+		logger.Debugf("converting CRLF line endings of %s to LF", path)
+		normalized := bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+		if err := afero.WriteFile(fs, path, normalized, info.Mode()); err != nil {
+			return errors.Wrapf(err, "normalizing line endings of %s", path)
+		}
+		return nil
+	})
+}
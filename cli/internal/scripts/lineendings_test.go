@@ -0,0 +1,47 @@
+package scripts
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_normalizeLineEndings(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"migrations/default/1604855964903_crlf/up.sql":  "SELECT 1;\r\nSELECT 2;\r\n",
+		"migrations/default/1604855964904_mixed/up.sql": "SELECT 1;\r\nSELECT 2;\n",
+		"migrations/default/1604855964905_lf/up.sql":    "SELECT 1;\nSELECT 2;\n",
+		"migrations/default/1604855964906_yaml/up.yaml": "- type: run_sql\r\n",
+	}
+	for name, content := range files {
+		if err := afero.WriteFile(fs, name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	assert.NoError(t, normalizeLineEndings(fs, "migrations/default", logger))
+
+	want := map[string]string{
+		"migrations/default/1604855964903_crlf/up.sql":  "SELECT 1;\nSELECT 2;\n",
+		"migrations/default/1604855964904_mixed/up.sql": "SELECT 1;\nSELECT 2;\n",
+		"migrations/default/1604855964905_lf/up.sql":    "SELECT 1;\nSELECT 2;\n",
+		"migrations/default/1604855964906_yaml/up.yaml": "- type: run_sql\r\n",
+	}
+	for name, content := range want {
+		b, err := afero.ReadFile(fs, name)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(b))
+	}
+	var warnings []string
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == logrus.WarnLevel {
+			warnings = append(warnings, entry.Message)
+		}
+	}
+	assert.Equal(t, []string{"migrations/default/1604855964904_mixed/up.sql has mixed line endings"}, warnings)
+}
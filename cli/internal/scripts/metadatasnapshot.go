@@ -0,0 +1,75 @@
+package scripts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// SnapshotServerMetadata writes the metadata on the server to snapshotPath
+// as JSON, so that it can be restored later using RestoreServerMetadata
+func SnapshotServerMetadata(ec *cli.ExecutionContext, fs afero.Fs, snapshotPath string) error {
+	return snapshotServerMetadata(cli.GetCommonMetadataOps(ec), fs, snapshotPath)
+}
+
+func snapshotServerMetadata(ops hasura.CommonMetadataOperations, fs afero.Fs, snapshotPath string) error {
+	r, err := ops.ExportMetadata()
+	if err != nil {
+		return errors.Wrap(err, "exporting metadata from the server")
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if _, err := parseMetadataSnapshot(b); err != nil {
+		return err
+	}
+	if err := afero.WriteFile(fs, snapshotPath, b, 0644); err != nil {
+		return errors.Wrap(err, "writing metadata snapshot")
+	}
+	return nil
+}
+
+// RestoreServerMetadata replaces the metadata on the server with a snapshot
+// written by SnapshotServerMetadata, eg: before the project was updated to
+// config v3. Metadata on the server is overwritten
+func RestoreServerMetadata(ec *cli.ExecutionContext, snapshotPath string) error {
+	return restoreServerMetadata(cli.GetCommonMetadataOps(ec), afero.NewOsFs(), snapshotPath, ec.Logger)
+}
+
+func restoreServerMetadata(ops hasura.CommonMetadataOperations, fs afero.Fs, snapshotPath string, logger *logrus.Logger) error {
+	b, err := afero.ReadFile(fs, snapshotPath)
+	if err != nil {
+		return errors.Wrap(err, "reading metadata snapshot")
+	}
+	version, err := parseMetadataSnapshot(b)
+	if err != nil {
+		return errors.Wrapf(err, "metadata snapshot %s", snapshotPath)
+	}
+	logger.Warnf("metadata on the server is being replaced with the snapshot %s (metadata version %v), changes made to metadata since the snapshot was taken are lost", snapshotPath, version)
+	if _, err := ops.ReplaceMetadata(bytes.NewReader(b)); err != nil {
+		return errors.Wrap(err, "replacing metadata on the server")
+	}
+	logger.Infof("metadata on the server was restored from %s", snapshotPath)
+	return nil
+}
+
+// parseMetadataSnapshot checks that b is a metadata object, returning its version
+func parseMetadataSnapshot(b []byte) (interface{}, error) {
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(b, &metadata); err != nil {
+		return nil, errors.Wrap(err, "parsing metadata")
+	}
+	version, ok := metadata["version"]
+	if !ok {
+		return nil, fmt.Errorf("metadata has no version, it is not a metadata snapshot")
+	}
+	return version, nil
+}
@@ -0,0 +1,47 @@
+package scripts
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeCommonMetadataOps struct {
+	hasura.CommonMetadataOperations
+	metadata string
+}
+
+func (o *fakeCommonMetadataOps) ExportMetadata() (io.Reader, error) {
+	return strings.NewReader(o.metadata), nil
+}
+
+func (o *fakeCommonMetadataOps) ReplaceMetadata(metadata io.Reader) (io.Reader, error) {
+	b, err := ioutil.ReadAll(metadata)
+	if err != nil {
+		return nil, err
+	}
+	o.metadata = string(b)
+	return bytes.NewReader(nil), nil
+}
+
+func TestRestoreServerMetadata(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	ops := &fakeCommonMetadataOps{metadata: `{"version":3,"sources":[]}`}
+	assert.NoError(t, snapshotServerMetadata(ops, fs, "snapshot.json"))
+
+	ops.metadata = `{"version":3,"sources":[{"name":"default"}]}`
+	assert.NoError(t, restoreServerMetadata(ops, fs, "snapshot.json", logrus.New()))
+	assert.Equal(t, `{"version":3,"sources":[]}`, ops.metadata)
+
+	assert.NoError(t, afero.WriteFile(fs, "invalid.json", []byte(`{"sources":[]}`), 0644))
+	assert.Error(t, restoreServerMetadata(ops, fs, "invalid.json", logrus.New()))
+	assert.Error(t, restoreServerMetadata(ops, fs, "missing.json", logrus.New()))
+	assert.Equal(t, `{"version":3,"sources":[]}`, ops.metadata)
+}
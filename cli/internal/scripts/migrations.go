@@ -0,0 +1,368 @@
+package scripts
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/hasura/graphql-engine/cli/internal/statestore"
+	"github.com/hasura/graphql-engine/cli/internal/statestore/migrations"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// PendingMigrations returns the versions of migrations of a source which are
+// present in the project directory but are not recorded as applied in the
+// migrations state store
+func PendingMigrations(ec *cli.ExecutionContext, fs afero.Fs, source string) ([]uint64, error) {
+	migrationsDir := ec.MigrationDir
+	if ec.Config.Version >= cli.V3 {
+		migrationsDir = filepath.Join(ec.MigrationDir, source)
+	}
+	return pendingMigrations(cli.GetMigrationsStateStore(ec), fs, migrationsDir, source)
+}
+
+func pendingMigrations(store statestore.MigrationsStateStore, fs afero.Fs, migrationsDir, source string) ([]uint64, error) {
+	applied, err := store.GetVersions(source)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading applied migrations")
+	}
+	dirs, err := getMigrationDirectoryNames(fs, migrationsDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading migrations directory")
+	}
+	var pending []uint64
+	for _, dir := range dirs {
+		version, err := getMigrationVersion(dir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing version of migration %s", dir)
+		}
+		if _, ok := applied[version]; !ok {
+			pending = append(pending, version)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i] < pending[j] })
+	return pending, nil
+}
+
+// MigrationStatus returns the status of each migration of a source, applied on
+// the server, present in the project directory or both, using the migrations state
+// store of the project, so that it works both before and after the update to config v3
+func MigrationStatus(ec *cli.ExecutionContext, fs afero.Fs, source string) ([]statestore.MigrationStatus, error) {
+	migrationsDir := ec.MigrationDir
+	if ec.Config.Version >= cli.V3 {
+		migrationsDir = filepath.Join(ec.MigrationDir, source)
+	}
+	return migrationStatus(cli.GetMigrationsStateStore(ec), fs, migrationsDir, source)
+}
+
+func migrationStatus(store statestore.MigrationsStateStore, fs afero.Fs, migrationsDir, source string) ([]statestore.MigrationStatus, error) {
+	dirs, err := getMigrationDirectoryNames(fs, migrationsDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading migrations directory")
+	}
+	local := make([]uint64, 0, len(dirs))
+	for _, dir := range dirs {
+		version, err := getMigrationVersion(dir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing version of migration %s", dir)
+		}
+		local = append(local, version)
+	}
+	statuses, err := statestore.GetMigrationStatus(store, source, local)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading applied migrations")
+	}
+	return statuses, nil
+}
+
+// MigrationVersionFileMap returns the path of each migration directory in
+// sourceDir (eg: migrations/<source>) keyed by the version of the migration.
+// It is an error for two directories to have the same version
+func MigrationVersionFileMap(fs afero.Fs, sourceDir string) (map[uint64]string, error) {
+	dirs, err := getMigrationDirectoryNames(fs, sourceDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading migrations directory")
+	}
+	versions := make(map[uint64]string, len(dirs))
+	for _, dir := range dirs {
+		version, err := getMigrationVersion(dir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing version of migration %s", dir)
+		}
+		path := filepath.Join(sourceDir, dir)
+		if existing, ok := versions[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s have the same version %d", existing, path, version)
+		}
+		versions[version] = path
+	}
+	return versions, nil
+}
+
+// ExportMigrationStateAsSQL writes the migration versions recorded for source in
+// the migrations state store of the project to w, as INSERT statements into the
+// hdb_catalog.schema_migrations table, in ascending order of version
+func ExportMigrationStateAsSQL(ec *cli.ExecutionContext, source string, w io.Writer) error {
+	return exportMigrationStateAsSQL(cli.GetMigrationsStateStore(ec), source, w)
+}
+
+func exportMigrationStateAsSQL(store statestore.MigrationsStateStore, source string, w io.Writer) error {
+	versions, err := store.GetVersions(source)
+	if err != nil {
+		return errors.Wrap(err, "reading migrations state")
+	}
+	sorted := make([]uint64, 0, len(versions))
+	for version := range versions {
+		sorted = append(sorted, version)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	table := fmt.Sprintf("%s.%s", migrations.DefaultSchema, migrations.DefaultMigrationsTable)
+	if _, err := fmt.Fprintf(w, "-- migrations state of database %s\n", source); err != nil {
+		return err
+	}
+	for _, version := range sorted {
+		dirty := versions[version]
+		_, err := fmt.Fprintf(w, "INSERT INTO %s (version, dirty) VALUES (%d, %t) ON CONFLICT (version) DO UPDATE SET dirty = %t;\n", table, version, dirty, dirty)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnpairedMigration is a migration directory which has an up migration
+// without a down migration or vice versa
+type UnpairedMigration struct {
+	Directory string
+	// Missing is either "up" or "down"
+	Missing string
+}
+
+func (m UnpairedMigration) String() string {
+	return fmt.Sprintf("%s has no %s migration", m.Directory, m.Missing)
+}
+
+// UnpairedMigrations returns the migration directories in migrationsDir which
+// have only one of an up or a down migration (in either .sql or .yaml)
+func UnpairedMigrations(fs afero.Fs, migrationsDir string) ([]UnpairedMigration, error) {
+	dirs, err := getMigrationDirectoryNames(fs, migrationsDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading migrations directory")
+	}
+	var unpaired []UnpairedMigration
+	for _, dir := range dirs {
+		info, err := fs.Stat(filepath.Join(migrationsDir, dir))
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			continue
+		}
+		files, err := afero.ReadDir(fs, filepath.Join(migrationsDir, dir))
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading migration %s", dir)
+		}
+		var hasUp, hasDown bool
+		for _, f := range files {
+			switch strings.TrimSuffix(f.Name(), filepath.Ext(f.Name())) {
+			case "up":
+				hasUp = true
+			case "down":
+				hasDown = true
+			}
+		}
+		if hasUp && !hasDown {
+			unpaired = append(unpaired, UnpairedMigration{Directory: dir, Missing: "down"})
+		} else if hasDown && !hasUp {
+			unpaired = append(unpaired, UnpairedMigration{Directory: dir, Missing: "up"})
+		}
+	}
+	return unpaired, nil
+}
+
+// number of directory entries read at a time by WalkMigrationDirectories
+const walkBatchSize = 1024
+
+// WalkMigrationDirectories calls fn with the name of each migration directory
+// (of the form <timestamp>_<name>) in dir. Unlike getMigrationDirectoryNames,
+// entries of dir are read walkBatchSize at a time and names are not sorted.
+// Walking stops at the first error returned by fn
+func WalkMigrationDirectories(fs afero.Fs, dir string, fn func(name string) error) error {
+	return walkMigrationDirectories(fs, dir, isHasuraCLIGeneratedMigration, fn)
+}
+
+// walkMigrationDirectories is WalkMigrationDirectories, using
+// matcher to find out if an entry of dir is a migration
+func walkMigrationDirectories(fs afero.Fs, dir string, matcher func(string) (bool, error), fn func(name string) error) error {
+	f, err := fs.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	for {
+		names, err := f.Readdirnames(walkBatchSize)
+		for _, name := range names {
+			ok, matchErr := matcher(name)
+			if matchErr != nil {
+				return matchErr
+			}
+			if !ok {
+				continue
+			}
+			if err := fn(name); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF || (err == nil && len(names) == 0) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// strategies for migration directories which already exist in the target
+// directory, eg: when an earlier update was interrupted. Unlike seed files,
+// migrations cannot be copied under another name, since the copy would
+// have the same version as the existing migration
+const (
+	// ask what has to be done for each conflicting migration
+	MigrationConflictPrompt = "prompt"
+	// replace the files of the migration in the target directory
+	MigrationConflictOverwrite = "overwrite"
+	// keep the migration in the target directory, the conflicting
+	// migration is left where it is instead of being removed
+	MigrationConflictSkip = "skip"
+	// abort the update
+	MigrationConflictAbort = "abort"
+)
+
+// migrationConflictResolver decides what is done with migrations which
+// already exist in the target directory, according to a strategy
+type migrationConflictResolver struct {
+	fs       afero.Fs
+	strategy string
+	prompt   func(message string, options []string) (string, error)
+	// skipped are the migrations which were not copied
+	skipped []string
+}
+
+func newMigrationConflictResolver(fs afero.Fs, strategy string, prompt func(message string, options []string) (string, error)) (*migrationConflictResolver, error) {
+	switch strategy {
+	case "":
+		strategy = MigrationConflictPrompt
+	case MigrationConflictPrompt, MigrationConflictOverwrite, MigrationConflictSkip, MigrationConflictAbort:
+	default:
+		return nil, fmt.Errorf("unknown migration conflict strategy %q, should be one of %s, %s, %s, %s", strategy, MigrationConflictPrompt, MigrationConflictOverwrite, MigrationConflictSkip, MigrationConflictAbort)
+	}
+	return &migrationConflictResolver{fs: fs, strategy: strategy, prompt: prompt}, nil
+}
+
+// resolve returns MigrationConflictOverwrite when migration name has to be
+// copied to targetDir and MigrationConflictSkip when it has to be left as it
+// is. An error is returned when the update has to be aborted
+func (r *migrationConflictResolver) resolve(name, targetDir string) (string, error) {
+	exists, err := afero.Exists(r.fs, filepath.Join(targetDir, name))
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return MigrationConflictOverwrite, nil
+	}
+	action := r.strategy
+	if action == MigrationConflictPrompt {
+		action, err = r.prompt(
+			fmt.Sprintf("migration %s already exists in %s, what should be done?", name, targetDir),
+			[]string{MigrationConflictOverwrite, MigrationConflictSkip, MigrationConflictAbort},
+		)
+		if err != nil {
+			return "", err
+		}
+	}
+	switch action {
+	case MigrationConflictOverwrite:
+		return action, nil
+	case MigrationConflictSkip:
+		r.skipped = append(r.skipped, name)
+		return action, nil
+	default:
+		return "", fmt.Errorf("migration %s already exists in %s, aborting", name, targetDir)
+	}
+}
+
+// MigrationsRootStragglers returns the entries of the root migrations directory
+// which are not a directory named after one of databases, as they are expected
+// to be once migrations were moved to config v3. Entries named like a migration
+// (<timestamp>_<name>) are returned as unmoved, other entries as stragglers
+func MigrationsRootStragglers(fs afero.Fs, rootMigrationsDir string, databases []string) (unmoved, stragglers []string, err error) {
+	infos, err := afero.ReadDir(fs, rootMigrationsDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	known := make(map[string]bool, len(databases))
+	for _, database := range databases {
+		known[database] = true
+	}
+	for _, info := range infos {
+		if info.IsDir() && known[info.Name()] {
+			continue
+		}
+		ok, err := isHasuraCLIGeneratedMigration(info.Name())
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			unmoved = append(unmoved, info.Name())
+		} else {
+			stragglers = append(stragglers, info.Name())
+		}
+	}
+	return unmoved, stragglers, nil
+}
+
+// VersionsReusedAcrossDatabases returns the migration versions used in more than
+// one of the database directories in rootMigrationsDir, along with the databases
+// using them. Versions are only required to be unique within a database, but some
+// workflows rely on migrations of all of the databases being ordered by version
+func VersionsReusedAcrossDatabases(fs afero.Fs, rootMigrationsDir string, databases []string) (map[uint64][]string, error) {
+	usedBy := map[uint64][]string{}
+	checked := map[string]bool{}
+	for _, database := range databases {
+		dir := filepath.Join(rootMigrationsDir, database)
+		if checked[database] {
+			continue
+		}
+		checked[database] = true
+		// databases without migrations have no directory
+		if ok, err := afero.DirExists(fs, dir); err != nil || !ok {
+			continue
+		}
+		dirs, err := getMigrationDirectoryNames(fs, dir)
+		if err != nil {
+			return nil, err
+		}
+		seen := map[uint64]bool{}
+		for _, dir := range dirs {
+			version, err := getMigrationVersion(dir)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parsing version of migration %s", dir)
+			}
+			if !seen[version] {
+				seen[version] = true
+				usedBy[version] = append(usedBy[version], database)
+			}
+		}
+	}
+	reused := map[uint64][]string{}
+	for version, databases := range usedBy {
+		if len(databases) > 1 {
+			reused[version] = databases
+		}
+	}
+	return reused, nil
+}
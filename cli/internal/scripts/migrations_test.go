@@ -0,0 +1,316 @@
+package scripts
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/internal/statestore"
+	"github.com/hasura/graphql-engine/cli/internal/testutil"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMigrationsStateStore is an in memory statestore.MigrationsStateStore
+type fakeMigrationsStateStore map[string]map[uint64]bool
+
+func (s fakeMigrationsStateStore) InsertVersion(database string, version int64) error {
+	return s.SetVersion(database, version, false)
+}
+
+func (s fakeMigrationsStateStore) RemoveVersion(database string, version int64) error {
+	delete(s[database], uint64(version))
+	return nil
+}
+
+func (s fakeMigrationsStateStore) SetVersion(database string, version int64, dirty bool) error {
+	if s[database] == nil {
+		s[database] = map[uint64]bool{}
+	}
+	s[database][uint64(version)] = dirty
+	return nil
+}
+
+func (s fakeMigrationsStateStore) GetVersions(database string) (map[uint64]bool, error) {
+	return s[database], nil
+}
+
+func (s fakeMigrationsStateStore) PrepareMigrationsStateStore() error {
+	return nil
+}
+
+func Test_pendingMigrations(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for _, dir := range []string{
+		"migrations/default/1604855964903_test",
+		"migrations/default/1604855964904_test2",
+		"migrations/default/1604855964905_test3",
+	} {
+		if err := fs.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := fakeMigrationsStateStore{
+		"default": {1604855964903: false},
+	}
+	got, err := pendingMigrations(store, fs, "migrations/default", "default")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{1604855964904, 1604855964905}, got)
+}
+
+func Test_migrationStatus(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for _, dir := range []string{
+		"migrations/default/1604855964903_test",
+		"migrations/default/1604855964904_test2",
+	} {
+		if err := fs.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := fakeMigrationsStateStore{
+		"default": {1604855964902: false, 1604855964903: false},
+	}
+	got, err := migrationStatus(store, fs, "migrations/default", "default")
+	assert.NoError(t, err)
+	assert.Equal(t, []statestore.MigrationStatus{
+		{Version: 1604855964902, AppliedOnServer: true},
+		{Version: 1604855964903, AppliedOnServer: true, PresentLocally: true},
+		{Version: 1604855964904, PresentLocally: true},
+	}, got)
+	assert.True(t, got[0].Drifted())
+	assert.False(t, got[1].Drifted())
+}
+
+func TestMigrationVersionFileMap(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for _, dir := range []string{
+		"migrations/default/1604855964903_test",
+		"migrations/default/1604855964904_test2",
+		"migrations/default/randomdir",
+	} {
+		if err := fs.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := MigrationVersionFileMap(fs, "migrations/default")
+	assert.NoError(t, err)
+	assert.Equal(t, map[uint64]string{
+		1604855964903: "migrations/default/1604855964903_test",
+		1604855964904: "migrations/default/1604855964904_test2",
+	}, got)
+
+	if err := fs.MkdirAll("migrations/default/1604855964904_duplicate", os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	_, err = MigrationVersionFileMap(fs, "migrations/default")
+	assert.Error(t, err)
+}
+
+func TestMigrationsRootStragglers(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for _, dir := range []string{
+		"migrations/default/1604855964903_test",
+		"migrations/orders",
+		"migrations/1604855964904_test2",
+		"migrations/old",
+	} {
+		if err := fs.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := afero.WriteFile(fs, "migrations/default.sql", []byte("select 1;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	unmoved, stragglers, err := MigrationsRootStragglers(fs, "migrations", []string{"default", "orders"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1604855964904_test2"}, unmoved)
+	assert.Equal(t, []string{"default.sql", "old"}, stragglers)
+}
+
+func TestVersionsReusedAcrossDatabases(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for _, dir := range []string{
+		"migrations/default/1604855964903_test",
+		"migrations/default/1604855964904_test2",
+		"migrations/orders/1604855964903_orders",
+		"migrations/users/1604855964904_users",
+		"migrations/users/1604855964905_users",
+	} {
+		if err := fs.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := VersionsReusedAcrossDatabases(fs, "migrations", []string{"default", "orders", "users", "default", "empty"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[uint64][]string{
+		1604855964903: {"default", "orders"},
+		1604855964904: {"default", "users"},
+	}, got)
+}
+
+func Test_exportMigrationStateAsSQL(t *testing.T) {
+	store := fakeMigrationsStateStore{"default": {1604855964903: false, 1604255964903: true}}
+	var b strings.Builder
+	assert.NoError(t, exportMigrationStateAsSQL(store, "default", &b))
+	assert.Equal(t, `-- migrations state of database default
+INSERT INTO hdb_catalog.schema_migrations (version, dirty) VALUES (1604255964903, true) ON CONFLICT (version) DO UPDATE SET dirty = true;
+INSERT INTO hdb_catalog.schema_migrations (version, dirty) VALUES (1604855964903, false) ON CONFLICT (version) DO UPDATE SET dirty = false;
+`, b.String())
+}
+
+func TestUnpairedMigrations(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for _, file := range []string{
+		"migrations/default/1604855964903_paired/up.sql",
+		"migrations/default/1604855964903_paired/down.sql",
+		"migrations/default/1604855964904_no_down/up.sql",
+		"migrations/default/1604855964905_no_up/down.yaml",
+		"migrations/default/1604855964906_mixed/up.yaml",
+		"migrations/default/1604855964906_mixed/down.sql",
+	} {
+		if err := afero.WriteFile(fs, file, []byte("SELECT 1;"), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := UnpairedMigrations(fs, "migrations/default")
+	assert.NoError(t, err)
+	assert.Equal(t, []UnpairedMigration{
+		{Directory: "1604855964904_no_down", Missing: "down"},
+		{Directory: "1604855964905_no_up", Missing: "up"},
+	}, got)
+}
+
+func TestWalkMigrationDirectories(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	var want []string
+	// more entries than are read in a batch
+	for i := 0; i < walkBatchSize+10; i++ {
+		name := fmt.Sprintf("%d_m%d", 1604855964903+i, i)
+		assert.NoError(t, fs.MkdirAll("migrations/"+name, os.ModePerm))
+		want = append(want, name)
+	}
+	assert.NoError(t, fs.MkdirAll("migrations/default", os.ModePerm))
+
+	var got []string
+	assert.NoError(t, WalkMigrationDirectories(fs, "migrations", func(name string) error {
+		got = append(got, name)
+		return nil
+	}))
+	sort.Strings(got)
+	assert.Equal(t, want, got)
+
+	calls := 0
+	err := WalkMigrationDirectories(fs, "migrations", func(name string) error {
+		calls++
+		return fmt.Errorf("failed")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+const benchmarkMigrationsCount = 5000
+
+func generatedMigrationsFs(b *testing.B) afero.Fs {
+	fs := afero.NewMemMapFs()
+	if err := testutil.GenerateMigrations(fs, "migrations", benchmarkMigrationsCount); err != nil {
+		b.Fatal(err)
+	}
+	return fs
+}
+
+func BenchmarkGetMigrationDirectoryNames(b *testing.B) {
+	fs := generatedMigrationsFs(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := getMigrationDirectoryNames(fs, "migrations"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCopyMigrations(b *testing.B) {
+	fs := generatedMigrationsFs(b)
+	dirs, err := getMigrationDirectoryNames(fs, "migrations")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, dir := range dirs {
+			if err := copyMigration(fs, dir, "migrations", "default", nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkCopyMigrationState(b *testing.B) {
+	fs := generatedMigrationsFs(b)
+	dirs, err := getMigrationDirectoryNames(fs, "migrations")
+	if err != nil {
+		b.Fatal(err)
+	}
+	src := fakeMigrationsStateStore{}
+	for _, dir := range dirs {
+		version, err := getMigrationVersion(dir)
+		if err != nil {
+			b.Fatal(err)
+		}
+		src.SetVersion("", int64(version), false)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := statestore.CopyMigrationState(src, fakeMigrationsStateStore{}, "", "default"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Test_migrationConflictResolver(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	if err := fs.MkdirAll("migrations/default/1604855964903_test", os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		strategy  string
+		prompted  string
+		migration string
+		want      string
+		wantErr   bool
+	}{
+		{"no conflict is copied", MigrationConflictAbort, "", "1604855964904_test2", MigrationConflictOverwrite, false},
+		{"can overwrite", MigrationConflictOverwrite, "", "1604855964903_test", MigrationConflictOverwrite, false},
+		{"can skip", MigrationConflictSkip, "", "1604855964903_test", MigrationConflictSkip, false},
+		{"can abort", MigrationConflictAbort, "", "1604855964903_test", "", true},
+		{"asks by default", "", MigrationConflictSkip, "1604855964903_test", MigrationConflictSkip, false},
+		{"can abort when asked", MigrationConflictPrompt, MigrationConflictAbort, "1604855964903_test", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var asked bool
+			r, err := newMigrationConflictResolver(fs, tt.strategy, func(string, []string) (string, error) {
+				asked = true
+				return tt.prompted, nil
+			})
+			assert.NoError(t, err)
+			got, err := r.resolve(tt.migration, "migrations/default")
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, len(tt.prompted) > 0, asked)
+			if got == MigrationConflictSkip {
+				assert.Equal(t, []string{tt.migration}, r.skipped)
+			}
+		})
+	}
+
+	_, err := newMigrationConflictResolver(fs, "rename", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,79 @@
+package scripts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/hasura/graphql-engine/cli/internal/metadatautil"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// Warning is an issue found in the project which does not stop the CLI from working
+type Warning struct {
+	Source    string
+	Directory string
+	Message   string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s", w.Directory, w.Message)
+}
+
+// ValidateDirectoryNamingConsistency reports the per source migrations and seeds
+// directories of the project whose name does not exactly match the name of the
+// source they seem to belong to, eg: "My-DB" for source "my_db". Such directories
+// are not picked up by the CLI, since directory names are expected to be the
+// same as source names. The project is expected to be in config V3
+func ValidateDirectoryNamingConsistency(ec *cli.ExecutionContext, fs afero.Fs) ([]Warning, error) {
+	if ec.Config.Version.Before(cli.V3) {
+		return nil, fmt.Errorf("per database directories are only used with config V3")
+	}
+	sources, err := metadatautil.GetSources(ec.APIClient.V1Metadata.ExportMetadata)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting list of databases")
+	}
+	return validateDirectoryNaming(fs, sources, ec.MigrationDir, ec.SeedsDirectory)
+}
+
+func validateDirectoryNaming(fs afero.Fs, sources []string, parentDirs ...string) ([]Warning, error) {
+	byNormalizedName := make(map[string]string, len(sources))
+	for _, source := range sources {
+		byNormalizedName[normalizeDirectoryName(source)] = source
+	}
+	var warnings []Warning
+	for _, parentDir := range parentDirs {
+		infos, err := afero.ReadDir(fs, parentDir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "reading %s", parentDir)
+		}
+		for _, info := range infos {
+			if !info.IsDir() {
+				continue
+			}
+			source, ok := byNormalizedName[normalizeDirectoryName(info.Name())]
+			if !ok || source == info.Name() {
+				continue
+			}
+			warnings = append(warnings, Warning{
+				Source:    source,
+				Directory: filepath.Join(parentDir, info.Name()),
+				Message:   fmt.Sprintf("directory name does not match the name of database %s, rename it to %s for it to be used", source, source),
+			})
+		}
+	}
+	sort.Slice(warnings, func(i, j int) bool { return warnings[i].Directory < warnings[j].Directory })
+	return warnings, nil
+}
+
+// normalizeDirectoryName ignores differences in case and separators
+func normalizeDirectoryName(name string) string {
+	return strings.NewReplacer("-", "_", " ", "_").Replace(strings.ToLower(name))
+}
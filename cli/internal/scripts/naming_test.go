@@ -0,0 +1,22 @@
+package scripts
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_validateDirectoryNaming(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for _, dir := range []string{"migrations/default", "migrations/My-DB", "migrations/unknown", "seeds/Default", "seeds/my_db"} {
+		assert.NoError(t, fs.MkdirAll(dir, os.ModePerm))
+	}
+	got, err := validateDirectoryNaming(fs, []string{"default", "my_db"}, "migrations", "seeds", "missing")
+	assert.NoError(t, err)
+	assert.Equal(t, []Warning{
+		{Source: "my_db", Directory: "migrations/My-DB", Message: "directory name does not match the name of database my_db, rename it to my_db for it to be used"},
+		{Source: "default", Directory: "seeds/Default", Message: "directory name does not match the name of database default, rename it to default for it to be used"},
+	}, got)
+}
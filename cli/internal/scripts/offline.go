@@ -0,0 +1,110 @@
+package scripts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/hasura/graphql-engine/cli/internal/metadataobject"
+	"github.com/hasura/graphql-engine/cli/internal/statestore"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// OfflineUpdateMarkerFile is created in the project directory by an offline
+// update to config v3, and removed once the update is reconciled with the server
+const OfflineUpdateMarkerFile = ".update-project-v3-pending"
+
+type offlineUpdate struct {
+	Database string `json:"database"`
+}
+
+func writeOfflineUpdateMarker(fs afero.Fs, projectDirectory, database string) error {
+	b, err := json.Marshal(offlineUpdate{Database: database})
+	if err != nil {
+		return err
+	}
+	return afero.WriteFile(fs, filepath.Join(projectDirectory, OfflineUpdateMarkerFile), b, 0644)
+}
+
+func readOfflineUpdateMarker(fs afero.Fs, projectDirectory string) (*offlineUpdate, error) {
+	b, err := afero.ReadFile(fs, filepath.Join(projectDirectory, OfflineUpdateMarkerFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no offline update of the project is pending reconciliation")
+		}
+		return nil, err
+	}
+	var update offlineUpdate
+	if err := json.Unmarshal(b, &update); err != nil {
+		return nil, errors.Wrapf(err, "reading %s", OfflineUpdateMarkerFile)
+	}
+	return &update, nil
+}
+
+// IsOfflineUpdatePending reports whether the project was updated to config v3
+// offline and is yet to be reconciled with the server
+func IsOfflineUpdatePending(fs afero.Fs, projectDirectory string) bool {
+	_, err := fs.Stat(filepath.Join(projectDirectory, OfflineUpdateMarkerFile))
+	return err == nil
+}
+
+// ReconcileOfflineUpdate completes an update to config v3 done in offline mode,
+// once the server is reachable. It copies the state of the project to catalog
+// state, marks the state copy as completed and replaces project metadata with
+// metadata on the server. As with an online update, state already copied is
+// only copied again when forceStateCopy is set
+func ReconcileOfflineUpdate(ec *cli.ExecutionContext, fs afero.Fs, projectDirectory string, forceStateCopy bool) error {
+	update, err := readOfflineUpdateMarker(fs, projectDirectory)
+	if err != nil {
+		return err
+	}
+	if !ec.HasMetadataV3 {
+		return fmt.Errorf("unsupported server version %v, config V3 is supported only on server with metadata version >= 3", ec.Version.Server)
+	}
+	// state copied by a reconciliation which failed later on is not copied again
+	var copied bool
+	var method string
+	if forceStateCopy {
+		ec.Logger.Info("--force-state-copy was set, state is copied even when it was already copied")
+	} else {
+		copied, method, err = DetectPriorStateCopy(ec, update.Database)
+		if err != nil {
+			return err
+		}
+	}
+	if copied {
+		ec.Logger.Infof("state was already copied to catalog state (detected using %s), skipping state copy", method)
+	} else {
+		if err := AssertStateCopySupported(ec, update.Database); err != nil {
+			return err
+		}
+		if err := markStateCopyStarted(ec); err != nil {
+			return err
+		}
+		// the project already uses config v3, so state is copied from the
+		// hdb_table state stores explicitly instead of the ones used by the project
+		if err := copyStateBetweenStores(stateStoreOptions(ec), ec.Logger, statestore.StateStoreHdbTable, statestore.StateStoreCatalog, update.Database, nil); err != nil {
+			return errors.Wrap(err, "copying state")
+		}
+		if err := markStateCopyCompleted(ec, ""); err != nil {
+			return err
+		}
+	}
+
+	if err := removeDirectories(fs, ec.MetadataDir, []string{"functions.yaml", "tables.yaml"}); err != nil {
+		return err
+	}
+	mdHandler := metadataobject.NewHandlerFromEC(ec)
+	mdHandler.SetFs(fs)
+	files, err := mdHandler.ExportMetadata()
+	if err != nil {
+		return errors.Wrap(err, "exporting metadata")
+	}
+	if err := mdHandler.WriteMetadata(files); err != nil {
+		return err
+	}
+	return fs.Remove(filepath.Join(projectDirectory, OfflineUpdateMarkerFile))
+}
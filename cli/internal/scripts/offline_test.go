@@ -0,0 +1,21 @@
+package scripts
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOfflineUpdateMarker(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	assert.False(t, IsOfflineUpdatePending(fs, "project"))
+	_, err := readOfflineUpdateMarker(fs, "project")
+	assert.EqualError(t, err, "no offline update of the project is pending reconciliation")
+
+	assert.NoError(t, writeOfflineUpdateMarker(fs, "project", "default"))
+	assert.True(t, IsOfflineUpdatePending(fs, "project"))
+	got, err := readOfflineUpdateMarker(fs, "project")
+	assert.NoError(t, err)
+	assert.Equal(t, &offlineUpdate{Database: "default"}, got)
+}
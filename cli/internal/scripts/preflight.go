@@ -0,0 +1,277 @@
+package scripts
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/internal/metadataobject"
+	"github.com/hasura/graphql-engine/cli/internal/metadatautil"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/afero"
+)
+
+const (
+	PreflightCheckConfigVersion       = "config_version"
+	PreflightCheckConfigSchema        = "config_schema"
+	PreflightCheckServerMetadataV3    = "server_metadata_v3"
+	PreflightCheckMetadataConsistent  = "metadata_consistent"
+	PreflightCheckSourcesFound        = "sources_found"
+	PreflightCheckDirectoriesWritable = "directories_writable"
+	PreflightCheckDiskSpace           = "disk_space"
+	PreflightCheckServerInactive      = "server_inactive"
+)
+
+// PreflightCheck is the result of a single check run before updating a project
+type PreflightCheck struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+
+	err error
+}
+
+// PreflightReport is the result of all checks run before updating a project
+// to config v3. Passed is true only when all checks have passed. Warnings do
+// not fail the checks, but should be shown to the user before continuing.
+type PreflightReport struct {
+	Passed   bool             `json:"passed"`
+	Sources  []string         `json:"sources"`
+	Checks   []PreflightCheck `json:"checks"`
+	Warnings []string         `json:"warnings,omitempty"`
+	// SourceKinds are the kinds of sources, keyed by name. It is empty
+	// when the sources were provided by the caller or the update is offline
+	SourceKinds map[string]hasura.SourceKind `json:"source_kinds,omitempty"`
+}
+
+func (r *PreflightReport) add(name string, err error) {
+	check := PreflightCheck{Name: name, Passed: err == nil, err: err}
+	if err != nil {
+		check.Message = err.Error()
+	}
+	r.Checks = append(r.Checks, check)
+	r.Passed = r.Passed && check.Passed
+}
+
+// Err returns the error of the first failed check, if any
+func (r *PreflightReport) Err() error {
+	for _, check := range r.Checks {
+		if check.err != nil {
+			return check.err
+		}
+	}
+	return nil
+}
+
+// Write writes the report as JSON to w
+func (r *PreflightReport) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// RunPreflightChecks runs all checks required to be passed before a project
+// can be updated to config v3. Unlike UpdateProjectV3, it does not stop at
+// the first failed check and does not modify the project or the server.
+// When opts.Offline is set, checks which require the server are skipped.
+func RunPreflightChecks(opts UpgradeToMuUpgradeProjectToMultipleSourcesOpts) *PreflightReport {
+	report := &PreflightReport{Passed: true, Sources: []string{}}
+	ec := opts.EC
+
+	var err error
+	if ec.Config.Version != cli.V2 {
+		err = fmt.Errorf("project should be using config V2 to be able to update to V3")
+	}
+	report.add(PreflightCheckConfigVersion, err)
+
+	err = ValidateConfigSchema(ec.Config)
+	if err != nil {
+		err = fmt.Errorf("validating config: %w", err)
+	}
+	report.add(PreflightCheckConfigSchema, err)
+
+	if !opts.Offline {
+		runServerPreflightChecks(opts, report)
+	} else {
+		report.Sources = append(report.Sources, opts.Sources...)
+	}
+
+	directories := []string{opts.MigrationsAbsDirectoryPath, opts.SeedsAbsDirectoryPath, ec.MetadataDir}
+	report.add(PreflightCheckDirectoriesWritable, checkDirectoriesWritable(opts.Fs, directories))
+
+	report.add(PreflightCheckDiskSpace, checkDiskSpace(opts.Fs, opts.ProjectDirectory, opts.MigrationsAbsDirectoryPath, opts.SeedsAbsDirectoryPath))
+
+	return report
+}
+
+func runServerPreflightChecks(opts UpgradeToMuUpgradeProjectToMultipleSourcesOpts, report *PreflightReport) {
+	ec := opts.EC
+	var err error
+	if !ec.HasMetadataV3 {
+		err = fmt.Errorf("unsupported server version %v, config V3 is supported only on server with metadata version >= 3", ec.Version.Server)
+	}
+	report.add(PreflightCheckServerMetadataV3, err)
+
+	err = nil
+	if r, rerr := ec.APIClient.V1Metadata.GetInconsistentMetadata(); rerr != nil {
+		err = fmt.Errorf("determing server metadata inconsistency: %w", rerr)
+	} else if !r.IsConsistent {
+		if opts.AllowInconsistentMetadata {
+			report.Warnings = append(report.Warnings, inconsistentMetadataWarnings(r.InconsistentObjects)...)
+		} else {
+			err = fmt.Errorf("cannot continue: metadata is inconsistent on the server, use --allow-inconsistent-metadata to update the project regardless")
+		}
+	}
+	report.add(PreflightCheckMetadataConsistent, err)
+
+	// sources provided by the caller are used as is, without querying the server
+	sources, err := opts.Sources, nil
+	if len(sources) == 0 {
+		var sourcesWithKind []metadatautil.Source
+		sourcesWithKind, err = metadatautil.GetSourcesAndKind(ec.APIClient.V1Metadata.ExportMetadata)
+		report.SourceKinds = make(map[string]hasura.SourceKind, len(sourcesWithKind))
+		for _, source := range sourcesWithKind {
+			sources = append(sources, source.Name)
+			report.SourceKinds[source.Name] = source.Kind
+		}
+	}
+	if err != nil {
+		err = fmt.Errorf("getting list of databases: %w", err)
+	} else {
+		report.Sources = append(report.Sources, sources...)
+	}
+	report.add(PreflightCheckSourcesFound, err)
+
+	if envVars, err := metadatautil.GetSourcesEnvVars(ec.APIClient.V1Metadata.ExportMetadata); err == nil {
+		report.Warnings = append(report.Warnings, envVarWarnings(envVars, os.LookupEnv)...)
+	}
+
+	// copying state while migrations are being applied can copy a partial state,
+	// so activity is warned about or, when asked for, fails the checks
+	// when the check is asked for, activity which cannot be checked fails it
+	reasons, err := serverActivity(cli.GetPGSourceOps(ec), time.Now())
+	if err != nil {
+		if opts.StopIfServerActive {
+			report.add(PreflightCheckServerInactive, fmt.Errorf("checking server activity: %w", err))
+			return
+		}
+		ec.Logger.Debugf("skipping server activity check: %v", err)
+		reasons = nil
+	}
+	if len(reasons) > 0 {
+		advice := "the server appears to be applying migrations (" + strings.Join(reasons, ", ") + "), consider putting it in maintenance mode before continuing"
+		if opts.StopIfServerActive {
+			report.add(PreflightCheckServerInactive, fmt.Errorf("%s", advice))
+		} else {
+			report.Warnings = append(report.Warnings, advice)
+		}
+	} else if opts.StopIfServerActive {
+		report.add(PreflightCheckServerInactive, nil)
+	}
+}
+
+// inconsistentMetadataWarnings warns about each of the inconsistent
+// objects, as returned by the server, which the update continues with
+func inconsistentMetadataWarnings(inconsistentObjects []interface{}) []string {
+	var objects []metadataobject.InconsistentMetadataObject
+	if err := mapstructure.Decode(inconsistentObjects, &objects); err != nil {
+		return []string{"metadata is inconsistent on the server, continuing as --allow-inconsistent-metadata is set"}
+	}
+	warnings := []string{fmt.Sprintf("metadata is inconsistent on the server (%d objects), continuing as --allow-inconsistent-metadata is set, the inconsistent objects will be exported as they are", len(objects))}
+	for _, object := range objects {
+		warnings = append(warnings, fmt.Sprintf("inconsistent %s %s: %s", object.GetType(), object.GetName(), object.GetReason()))
+	}
+	return warnings
+}
+
+// envVarWarnings warns about sources whose connection is configured using
+// environment variables, since these have to be set in the environment of the
+// CLI as well for the state of the sources to be copied
+func envVarWarnings(envVars map[string][]string, lookupEnv func(string) (string, bool)) []string {
+	var sources []string
+	for source := range envVars {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	var warnings []string
+	for _, source := range sources {
+		var unset []string
+		for _, name := range envVars[source] {
+			if _, ok := lookupEnv(name); !ok {
+				unset = append(unset, name)
+			}
+		}
+		warning := fmt.Sprintf("database %s is configured using environment variables %s, make sure they are set in the environment of the CLI as well", source, strings.Join(envVars[source], ", "))
+		if len(unset) > 0 {
+			warning += fmt.Sprintf(" (not set: %s)", strings.Join(unset, ", "))
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
+
+func checkDirectoriesWritable(fs afero.Fs, directories []string) error {
+	for _, dir := range directories {
+		if len(dir) == 0 {
+			continue
+		}
+		f, err := afero.TempFile(fs, dir, ".hasura-preflight-")
+		if err != nil {
+			return fmt.Errorf("directory %s is not writable: %w", dir, err)
+		}
+		f.Close()
+		if err := fs.Remove(f.Name()); err != nil {
+			return fmt.Errorf("directory %s is not writable: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// checkDiskSpace checks if there is enough space available to hold two copies of
+// the migrations and seeds, since they are backed up before the update and are
+// copied before the originals are removed
+func checkDiskSpace(fs afero.Fs, projectDirectory string, directories ...string) error {
+	var required uint64
+	for _, dir := range directories {
+		size, err := directorySize(fs, dir)
+		if err != nil {
+			return err
+		}
+		required += 2 * size
+	}
+	// free space can only be determined for directories on the OS filesystem
+	if _, ok := fs.(*afero.OsFs); !ok {
+		return nil
+	}
+	available, err := availableDiskSpace(projectDirectory)
+	if err != nil {
+		return fmt.Errorf("determining available disk space: %w", err)
+	}
+	if available < required {
+		return fmt.Errorf("not enough disk space: %d bytes are required, %d bytes are available", required, available)
+	}
+	return nil
+}
+
+func directorySize(fs afero.Fs, dir string) (uint64, error) {
+	var size uint64
+	err := afero.Walk(fs, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += uint64(info.Size())
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("determining size of %s: %w", dir, err)
+	}
+	return size, nil
+}
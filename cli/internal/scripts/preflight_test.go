@@ -0,0 +1,96 @@
+package scripts
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreflightReport(t *testing.T) {
+	report := &PreflightReport{Passed: true}
+	report.add(PreflightCheckConfigVersion, nil)
+	assert.True(t, report.Passed)
+	assert.NoError(t, report.Err())
+
+	report.add(PreflightCheckServerMetadataV3, errors.New("unsupported server version"))
+	report.add(PreflightCheckMetadataConsistent, errors.New("metadata is inconsistent"))
+	assert.False(t, report.Passed)
+	assert.EqualError(t, report.Err(), "unsupported server version")
+	assert.Equal(t, []PreflightCheck{
+		{Name: PreflightCheckConfigVersion, Passed: true},
+		{Name: PreflightCheckServerMetadataV3, Passed: false, Message: "unsupported server version"},
+		{Name: PreflightCheckMetadataConsistent, Passed: false, Message: "metadata is inconsistent"},
+	}, stripErrors(report.Checks))
+}
+
+func stripErrors(checks []PreflightCheck) []PreflightCheck {
+	var stripped []PreflightCheck
+	for _, c := range checks {
+		c.err = nil
+		stripped = append(stripped, c)
+	}
+	return stripped
+}
+
+func Test_checkDirectoriesWritable(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	if err := fs.MkdirAll("migrations", os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, checkDirectoriesWritable(fs, []string{"migrations", ""}))
+	files, err := afero.ReadDir(fs, "migrations")
+	assert.NoError(t, err)
+	assert.Len(t, files, 0)
+
+	assert.Error(t, checkDirectoriesWritable(afero.NewReadOnlyFs(fs), []string{"migrations"}))
+}
+
+func Test_directorySize(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	if err := afero.WriteFile(fs, "migrations/1604855964903_test/up.sql", []byte("SELECT 1;"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := afero.WriteFile(fs, "migrations/1604855964903_test/down.sql", []byte("SELECT 2;"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	got, err := directorySize(fs, "migrations")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(18), got)
+
+	got, err = directorySize(fs, "seeds")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), got)
+}
+
+func Test_envVarWarnings(t *testing.T) {
+	env := map[string]string{"PG_URL": "postgres://localhost"}
+	lookupEnv := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	got := envVarWarnings(map[string][]string{
+		"replica": {"PG_REPLICA_URL", "PG_URL"},
+		"default": {"PG_URL"},
+	}, lookupEnv)
+	assert.Equal(t, []string{
+		"database default is configured using environment variables PG_URL, make sure they are set in the environment of the CLI as well",
+		"database replica is configured using environment variables PG_REPLICA_URL, PG_URL, make sure they are set in the environment of the CLI as well (not set: PG_REPLICA_URL)",
+	}, got)
+}
+
+func Test_inconsistentMetadataWarnings(t *testing.T) {
+	got := inconsistentMetadataWarnings([]interface{}{
+		map[string]interface{}{
+			"definition": map[string]interface{}{"name": "countries"},
+			"reason":     "remote schema is unreachable",
+			"type":       "remote_schema",
+		},
+	})
+	assert.Equal(t, []string{
+		"metadata is inconsistent on the server (1 objects), continuing as --allow-inconsistent-metadata is set, the inconsistent objects will be exported as they are",
+		"inconsistent remote_schema countries: remote schema is unreachable",
+	}, got)
+}
@@ -0,0 +1,33 @@
+package scripts
+
+import (
+	"fmt"
+
+	"github.com/hasura/graphql-engine/cli/internal/metadataobject"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+type metadataReloader interface {
+	ReloadMetadata() error
+	GetInconsistentMetadata() (bool, []metadataobject.InconsistentMetadataObject, error)
+}
+
+// reloadMetadata reloads metadata on the server and reports the
+// objects found to be inconsistent after the reload, if any
+func reloadMetadata(reloader metadataReloader, logger *logrus.Logger) error {
+	if err := reloader.ReloadMetadata(); err != nil {
+		return errors.Wrap(err, "reloading metadata")
+	}
+	isConsistent, objects, err := reloader.GetInconsistentMetadata()
+	if err != nil {
+		return errors.Wrap(err, "getting inconsistent metadata")
+	}
+	if isConsistent {
+		return nil
+	}
+	for _, object := range objects {
+		logger.Warnf("inconsistent %s %s: %s", object.GetType(), object.GetName(), object.GetReason())
+	}
+	return fmt.Errorf("metadata on the server is inconsistent after reload, use 'hasura metadata inconsistency list' to list the inconsistent objects")
+}
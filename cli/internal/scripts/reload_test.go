@@ -0,0 +1,53 @@
+package scripts
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/internal/metadataobject"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeMetadataReloader struct {
+	reloadErr    error
+	isConsistent bool
+	reloads      int
+}
+
+func (f *fakeMetadataReloader) ReloadMetadata() error {
+	f.reloads++
+	return f.reloadErr
+}
+
+func (f *fakeMetadataReloader) GetInconsistentMetadata() (bool, []metadataobject.InconsistentMetadataObject, error) {
+	if f.isConsistent {
+		return true, nil, nil
+	}
+	return false, []metadataobject.InconsistentMetadataObject{
+		{Definition: map[string]interface{}{"name": "articles"}, Reason: "table does not exist", Type: "table"},
+	}, nil
+}
+
+func Test_reloadMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		reloader *fakeMetadataReloader
+		wantErr  bool
+	}{
+		{"consistent metadata", &fakeMetadataReloader{isConsistent: true}, false},
+		{"inconsistent metadata", &fakeMetadataReloader{}, true},
+		{"reload fails", &fakeMetadataReloader{reloadErr: fmt.Errorf("failed"), isConsistent: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := reloadMetadata(tt.reloader, logrus.New())
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, 1, tt.reloader.reloads)
+		})
+	}
+}
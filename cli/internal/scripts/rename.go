@@ -0,0 +1,231 @@
+package scripts
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/internal/metadataobject"
+	"github.com/hasura/graphql-engine/cli/internal/metadataobject/sources"
+	"github.com/hasura/graphql-engine/cli/internal/metadatautil"
+	"github.com/hasura/graphql-engine/cli/internal/statestore"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v2"
+)
+
+// RenameSource renames a source on the server and in the project. Migrations
+// and seeds directories of the source are moved, the migrations state in
+// catalog state is moved to the new name and the metadata is exported again.
+// The project is expected to be in config V3
+func RenameSource(ec *cli.ExecutionContext, fs afero.Fs, oldName, newName string) error {
+	if ec.Config.Version < cli.V3 {
+		return fmt.Errorf("renaming a database requires config V3")
+	}
+	if err := ValidateSourceDirectoryName(newName); err != nil {
+		return err
+	}
+	if oldName == newName {
+		return fmt.Errorf("new name of database %s is the same as the current name", oldName)
+	}
+	databases, err := metadatautil.GetSources(ec.APIClient.V1Metadata.ExportMetadata)
+	if err != nil {
+		return errors.Wrap(err, "getting list of databases")
+	}
+	var found bool
+	for _, source := range databases {
+		if source == newName {
+			return fmt.Errorf("database %s already exists", newName)
+		}
+		if source == oldName {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("database %s does not exist", oldName)
+	}
+
+	// everything which can be checked locally is checked before the server is
+	// changed, so that a rename is rarely left half done
+	destinations := []string{
+		filepath.Join(ec.MigrationDir, newName),
+		filepath.Join(ec.SeedsDirectory, newName),
+		sources.SourceDirectory(ec.MetadataDir, newName),
+	}
+	for _, dst := range destinations {
+		if _, err := fs.Stat(dst); err == nil {
+			return fmt.Errorf("cannot rename database %s to %s: %s already exists", oldName, newName, dst)
+		} else if !os.IsNotExist(err) {
+			return errors.Wrapf(err, "checking %s", dst)
+		}
+	}
+
+	rename := &sourceRename{
+		fs:                  fs,
+		oldName:             oldName,
+		newName:             newName,
+		migrationsDirectory: ec.MigrationDir,
+		seedsDirectory:      ec.SeedsDirectory,
+		renameOnServer: func(oldName, newName string) error {
+			return renameSourceOnServer(ec, oldName, newName)
+		},
+		catalogState: statestore.NewCLICatalogState(ec.APIClient.V1Metadata),
+	}
+	if ec.Config.DefaultSource == oldName {
+		rename.setDefaultSource = func(name string) error {
+			if err := ec.UpdateConfigFieldsFs(fs, yaml.MapSlice{{Key: "default_source", Value: name}}); err != nil {
+				return err
+			}
+			ec.Config.DefaultSource = name
+			return nil
+		}
+	}
+	if err := rename.run(); err != nil {
+		return err
+	}
+
+	// write metadata with the new database name, the metadata of the old
+	// name is only removed once the metadata is written
+	mdHandler := metadataobject.NewHandlerFromEC(ec)
+	mdHandler.SetFs(fs)
+	files, err := mdHandler.ExportMetadata()
+	if err != nil {
+		return rename.rollback(err)
+	}
+	if err := mdHandler.WriteMetadata(files); err != nil {
+		return rename.rollback(fmt.Errorf("%w, run hasura metadata export to write the metadata again", err))
+	}
+	if err := fs.RemoveAll(sources.SourceDirectory(ec.MetadataDir, oldName)); err != nil {
+		return errors.Wrap(err, "removing metadata of database")
+	}
+	return nil
+}
+
+type cliStateStore interface {
+	Get() (*statestore.CLIState, error)
+	Set(state statestore.CLIState) (io.Reader, error)
+}
+
+// sourceRename renames a source on the server, in catalog state and in the
+// project directory. The changes made are recorded, so that they can be
+// reverted when a later step of the rename fails
+type sourceRename struct {
+	fs                  afero.Fs
+	oldName, newName    string
+	migrationsDirectory string
+	seedsDirectory      string
+	renameOnServer      func(oldName, newName string) error
+	catalogState        cliStateStore
+	// setDefaultSource is set when the source is the default source of the project
+	setDefaultSource func(name string) error
+
+	undo []func() error
+}
+
+// run makes the changes, when one of them fails the changes
+// made so far are reverted and the error is returned
+func (r *sourceRename) run() error {
+	if err := r.renameOnServer(r.oldName, r.newName); err != nil {
+		return err
+	}
+	r.undo = append(r.undo, func() error {
+		if err := r.renameOnServer(r.newName, r.oldName); err != nil {
+			return fmt.Errorf("%w, rename database %s back to %s with the rename_source metadata API", err, r.newName, r.oldName)
+		}
+		return nil
+	})
+
+	// move state
+	state, err := r.catalogState.Get()
+	if err != nil {
+		return r.rollback(errors.Wrap(err, "getting catalog state"))
+	}
+	if state == nil {
+		state = &statestore.CLIState{}
+	}
+	state.Init()
+	state.RenameDatabase(r.oldName, r.newName)
+	if _, err := r.catalogState.Set(*state); err != nil {
+		return r.rollback(errors.Wrap(err, "updating catalog state"))
+	}
+	r.undo = append(r.undo, func() error {
+		state.RenameDatabase(r.newName, r.oldName)
+		if _, err := r.catalogState.Set(*state); err != nil {
+			return fmt.Errorf("%w, move the migrations state of database %s back to %s in catalog state", err, r.newName, r.oldName)
+		}
+		return nil
+	})
+
+	// move migrations and seeds directories
+	for _, dir := range []string{r.migrationsDirectory, r.seedsDirectory} {
+		src, dst := filepath.Join(dir, r.oldName), filepath.Join(dir, r.newName)
+		if err := renameDirectory(r.fs, src, dst); err != nil {
+			return r.rollback(err)
+		}
+		r.undo = append(r.undo, func() error {
+			if err := renameDirectory(r.fs, dst, src); err != nil {
+				return fmt.Errorf("%w, move %s back to %s", err, dst, src)
+			}
+			return nil
+		})
+	}
+
+	if r.setDefaultSource != nil {
+		if err := r.setDefaultSource(r.newName); err != nil {
+			return r.rollback(errors.Wrap(err, "updating default_source in config"))
+		}
+		r.undo = append(r.undo, func() error {
+			if err := r.setDefaultSource(r.oldName); err != nil {
+				return fmt.Errorf("%w, set default_source back to %s in config", err, r.oldName)
+			}
+			return nil
+		})
+	}
+	return nil
+}
+
+// rollback reverts the changes made so far in the reverse order, when
+// a change cannot be reverted the manual fix is returned instead
+func (r *sourceRename) rollback(cause error) error {
+	for idx := len(r.undo) - 1; idx >= 0; idx-- {
+		if err := r.undo[idx](); err != nil {
+			return fmt.Errorf("%v, and reverting the rename failed: %w", cause, err)
+		}
+	}
+	r.undo = nil
+	return cause
+}
+
+func renameSourceOnServer(ec *cli.ExecutionContext, oldName, newName string) error {
+	resp, body, err := ec.APIClient.V1Metadata.Send(hasura.RequestBody{
+		Type: "rename_source",
+		Args: map[string]string{
+			"name":     oldName,
+			"new_name": newName,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "renaming database on server")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("renaming database on server: %s", body)
+	}
+	return nil
+}
+
+func renameDirectory(fs afero.Fs, src, dst string) error {
+	if _, err := fs.Stat(src); os.IsNotExist(err) {
+		return nil
+	}
+	if _, err := fs.Stat(dst); err == nil {
+		return fmt.Errorf("cannot move %s: %s already exists", src, dst)
+	}
+	if err := fs.Rename(src, dst); err != nil {
+		return errors.Wrapf(err, "moving %s to %s", src, dst)
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package scripts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/internal/statestore"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_renameDirectory(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	if err := fs.MkdirAll("migrations/default/1604855964903_test", os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.MkdirAll("migrations/existing", os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, renameDirectory(fs, "migrations/default", "migrations/renamed"))
+	_, err := fs.Stat("migrations/renamed")
+	assert.NoError(t, err)
+	_, err = fs.Stat("migrations/default")
+	assert.True(t, os.IsNotExist(err))
+
+	// directories which do not exist are skipped
+	assert.NoError(t, renameDirectory(fs, "migrations/missing", "migrations/other"))
+	// existing directories are not overwritten
+	assert.Error(t, renameDirectory(fs, "migrations/renamed", "migrations/existing"))
+}
+
+// fakeRename records the changes made by a sourceRename, the nth call
+// of a change fails when an error is set for it in fail
+type fakeRename struct {
+	events   []string
+	calls    map[string]int
+	fail     map[string]error
+	state    statestore.CLIState
+	getState error
+}
+
+func (f *fakeRename) call(name string) error {
+	f.calls[name]++
+	if err, ok := f.fail[fmt.Sprintf("%s %d", name, f.calls[name])]; ok {
+		return err
+	}
+	return nil
+}
+
+func (f *fakeRename) Get() (*statestore.CLIState, error) {
+	if f.getState != nil {
+		return nil, f.getState
+	}
+	state := f.state
+	return &state, nil
+}
+
+func (f *fakeRename) Set(state statestore.CLIState) (io.Reader, error) {
+	if err := f.call("state"); err != nil {
+		return nil, err
+	}
+	migrations := statestore.MigrationsState{}
+	for database, versions := range state.Migrations {
+		migrations[database] = versions
+	}
+	f.state.Migrations = migrations
+	f.events = append(f.events, fmt.Sprintf("state %v", migrations))
+	return &bytes.Buffer{}, nil
+}
+
+func (f *fakeRename) renameOnServer(oldName, newName string) error {
+	if err := f.call("server"); err != nil {
+		return err
+	}
+	f.events = append(f.events, fmt.Sprintf("server %s -> %s", oldName, newName))
+	return nil
+}
+
+func (f *fakeRename) setDefaultSource(name string) error {
+	if err := f.call("default_source"); err != nil {
+		return err
+	}
+	f.events = append(f.events, "default_source "+name)
+	return nil
+}
+
+func Test_sourceRename(t *testing.T) {
+	tests := []struct {
+		name string
+		fail map[string]error
+		// getState is the error returned when catalog state is read
+		getState error
+		// rollback is the error with which the rename is rolled back after it was run
+		rollback   error
+		wantEvents []string
+		wantErr    string
+		// wantRenamed is set when the directories are left with the new name
+		wantRenamed bool
+	}{
+		{
+			name: "rename is complete",
+			wantEvents: []string{
+				"server default -> renamed",
+				"state map[renamed:map[1604855964903:false]]",
+				"default_source renamed",
+			},
+			wantRenamed: true,
+		},
+		{
+			name:       "rename fails on the server",
+			fail:       map[string]error{"server 1": errors.New("server error")},
+			wantEvents: nil,
+			wantErr:    "server error",
+		},
+		{
+			name:     "reading catalog state fails",
+			getState: errors.New("connection refused"),
+			wantEvents: []string{
+				"server default -> renamed",
+				"server renamed -> default",
+			},
+			wantErr: "getting catalog state: connection refused",
+		},
+		{
+			name: "changes are reverted in the reverse order",
+			fail: map[string]error{"default_source 1": errors.New("permission denied")},
+			wantEvents: []string{
+				"server default -> renamed",
+				"state map[renamed:map[1604855964903:false]]",
+				"state map[default:map[1604855964903:false]]",
+				"server renamed -> default",
+			},
+			wantErr: "updating default_source in config: permission denied",
+		},
+		{
+			name:     "rename is rolled back after it was run",
+			rollback: errors.New("exporting metadata"),
+			wantEvents: []string{
+				"server default -> renamed",
+				"state map[renamed:map[1604855964903:false]]",
+				"default_source renamed",
+				"default_source default",
+				"state map[default:map[1604855964903:false]]",
+				"server renamed -> default",
+			},
+			wantErr: "exporting metadata",
+		},
+		{
+			name: "catalog state cannot be reverted",
+			fail: map[string]error{
+				"default_source 1": errors.New("permission denied"),
+				"state 2":          errors.New("connection refused"),
+			},
+			wantEvents: []string{
+				"server default -> renamed",
+				"state map[renamed:map[1604855964903:false]]",
+			},
+			wantErr: "updating default_source in config: permission denied, and reverting the rename failed: connection refused, move the migrations state of database renamed back to default in catalog state",
+		},
+		{
+			name: "server rename cannot be reverted",
+			fail: map[string]error{
+				"state 1":  errors.New("connection refused"),
+				"server 2": errors.New("server error"),
+			},
+			wantEvents: []string{
+				"server default -> renamed",
+			},
+			wantErr: "updating catalog state: connection refused, and reverting the rename failed: server error, rename database renamed back to default with the rename_source metadata API",
+		},
+		{
+			name:     "default_source cannot be reverted",
+			rollback: errors.New("exporting metadata"),
+			fail:     map[string]error{"default_source 2": errors.New("permission denied")},
+			wantEvents: []string{
+				"server default -> renamed",
+				"state map[renamed:map[1604855964903:false]]",
+				"default_source renamed",
+			},
+			wantErr:     "exporting metadata, and reverting the rename failed: permission denied, set default_source back to default in config",
+			wantRenamed: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for _, dir := range []string{"migrations/default/1604855964903_test", "seeds/default"} {
+				if err := fs.MkdirAll(dir, os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+			}
+			fake := &fakeRename{
+				calls:    map[string]int{},
+				fail:     tc.fail,
+				getState: tc.getState,
+				state: statestore.CLIState{
+					Migrations: statestore.MigrationsState{"default": {"1604855964903": false}},
+				},
+			}
+			rename := &sourceRename{
+				fs:                  fs,
+				oldName:             "default",
+				newName:             "renamed",
+				migrationsDirectory: "migrations",
+				seedsDirectory:      "seeds",
+				renameOnServer:      fake.renameOnServer,
+				catalogState:        fake,
+				setDefaultSource:    fake.setDefaultSource,
+			}
+			err := rename.run()
+			if err == nil && tc.rollback != nil {
+				err = rename.rollback(tc.rollback)
+			}
+			if len(tc.wantErr) > 0 {
+				assert.EqualError(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.wantEvents, fake.events)
+			for _, dir := range []string{"migrations", "seeds"} {
+				renamed, err := afero.DirExists(fs, dir+"/renamed")
+				assert.NoError(t, err)
+				assert.Equal(t, tc.wantRenamed, renamed, dir)
+				original, err := afero.DirExists(fs, dir+"/default")
+				assert.NoError(t, err)
+				assert.Equal(t, !tc.wantRenamed, original, dir)
+			}
+		})
+	}
+}
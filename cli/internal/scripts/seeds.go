@@ -0,0 +1,88 @@
+package scripts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hasura/graphql-engine/cli/util"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// strategies for seed files which conflict with a file in the target directory
+const (
+	// abort the update, listing the conflicting files
+	SeedConflictFail = "fail"
+	// copy the seed file as <name>_<n><ext>
+	SeedConflictRename = "rename"
+	// replace the file in the target directory
+	SeedConflictOverwrite = "overwrite"
+)
+
+// seedDestinations returns the name each of the seed files is copied as in
+// targetDir, according to strategy. A file conflicts when a file of the same
+// name, compared case insensitively since the project can be used on case
+// insensitive filesystems, is already in targetDir or is copied before it
+func seedDestinations(fs afero.Fs, files []string, targetDir, strategy string) ([]string, error) {
+	switch strategy {
+	case "", SeedConflictFail, SeedConflictRename, SeedConflictOverwrite:
+	default:
+		return nil, fmt.Errorf("unknown seed conflict strategy %q, should be one of %s, %s, %s", strategy, SeedConflictFail, SeedConflictRename, SeedConflictOverwrite)
+	}
+	taken := map[string]bool{}
+	infos, err := afero.ReadDir(fs, targetDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, info := range infos {
+		taken[strings.ToLower(info.Name())] = true
+	}
+
+	destinations := make([]string, len(files))
+	var conflicts []string
+	for idx, name := range files {
+		dest := name
+		if taken[strings.ToLower(name)] {
+			switch strategy {
+			case SeedConflictOverwrite:
+			case SeedConflictRename:
+				ext := filepath.Ext(name)
+				base := strings.TrimSuffix(name, ext)
+				for n := 1; taken[strings.ToLower(dest)]; n++ {
+					dest = fmt.Sprintf("%s_%d%s", base, n, ext)
+				}
+			default:
+				conflicts = append(conflicts, name)
+			}
+		}
+		taken[strings.ToLower(dest)] = true
+		destinations[idx] = dest
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("seed files %s conflict with files in %s, use --seed-conflicts %s or %s to move them regardless", strings.Join(conflicts, ", "), targetDir, SeedConflictRename, SeedConflictOverwrite)
+	}
+	return destinations, nil
+}
+
+// copySeedFiles copies each of files from parentDir to targetDir as the
+// corresponding name in destinations, calling progress when it is not nil
+// before each file is copied. The checksums of copied files are logged to
+// logger when it is not nil
+func copySeedFiles(fs afero.Fs, files, destinations []string, parentDir, targetDir string, progress func(), logger *logrus.Logger) error {
+	for idx, name := range files {
+		if progress != nil {
+			progress()
+		}
+		err := util.CopyFileAfero(fs, filepath.Join(parentDir, name), filepath.Join(targetDir, destinations[idx]))
+		if err != nil {
+			return errors.Wrapf(err, "moving %s to %s", name, targetDir)
+		}
+		if err := verifyCopy(fs, filepath.Join(parentDir, name), filepath.Join(targetDir, destinations[idx]), logger); err != nil {
+			return errors.Wrapf(err, "moving %s to %s", name, targetDir)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package scripts
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_seedDestinations(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(fs, "seeds/default/users.sql", []byte("existing"), 0644))
+	assert.NoError(t, afero.WriteFile(fs, "seeds/default/users_1.sql", []byte("existing"), 0644))
+	files := []string{"users.sql", "Articles.sql", "articles.sql"}
+
+	_, err := seedDestinations(fs, files, "seeds/default", SeedConflictFail)
+	assert.Error(t, err)
+
+	got, err := seedDestinations(fs, files, "seeds/default", SeedConflictRename)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"users_2.sql", "Articles.sql", "articles_1.sql"}, got)
+
+	got, err = seedDestinations(fs, files, "seeds/default", SeedConflictOverwrite)
+	assert.NoError(t, err)
+	assert.Equal(t, files, got)
+
+	_, err = seedDestinations(fs, files, "seeds/default", "merge")
+	assert.Error(t, err)
+
+	got, err = seedDestinations(fs, []string{"users.sql"}, "seeds/other", SeedConflictFail)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"users.sql"}, got)
+}
@@ -0,0 +1,139 @@
+package scripts
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+var (
+	createSchemaRegex = regexp.MustCompile(`(?i)\bcreate\s+schema\s+(?:if\s+not\s+exists\s+)?("(?:[^"]|"")+"|[a-z_][a-z0-9_$]*)`)
+	// schema qualified names following the keywords which name a table
+	schemaReferenceRegex = regexp.MustCompile(`(?i)\b(?:into|from|join|update|table|references)\s+(?:only\s+)?("(?:[^"]|"")+"|[a-z_][a-z0-9_$]*)\s*\.`)
+)
+
+// SeedSchemaReferences returns the schemas created by the SQL of a seed file and
+// the schemas of the tables it refers to using schema qualified names, eg:
+// INSERT INTO orders.items. It is a best-effort analysis done using regular
+// expressions, references in strings or comments are also returned
+func SeedSchemaReferences(sql string) (created, referenced []string) {
+	return matchedIdentifiers(createSchemaRegex, sql), matchedIdentifiers(schemaReferenceRegex, sql)
+}
+
+func matchedIdentifiers(re *regexp.Regexp, sql string) []string {
+	seen := map[string]bool{}
+	var identifiers []string
+	for _, match := range re.FindAllStringSubmatch(sql, -1) {
+		identifier := normalizeIdentifier(match[1])
+		if !seen[identifier] {
+			seen[identifier] = true
+			identifiers = append(identifiers, identifier)
+		}
+	}
+	return identifiers
+}
+
+// normalizeIdentifier folds unquoted identifiers to lower case, as postgres does
+func normalizeIdentifier(identifier string) string {
+	if strings.HasPrefix(identifier, `"`) {
+		return strings.ReplaceAll(strings.Trim(identifier, `"`), `""`, `"`)
+	}
+	return strings.ToLower(identifier)
+}
+
+// MissingSeedSchemas returns the schemas referenced by each seed file in
+// seedsDirectory, keyed by the name of the file, which are neither one of
+// schemas nor created by one of the seed files of the directory
+func MissingSeedSchemas(fs afero.Fs, seedsDirectory string, schemas []string) (map[string][]string, error) {
+	files, err := afero.ReadDir(fs, seedsDirectory)
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]bool{}
+	for _, schema := range schemas {
+		known[schema] = true
+	}
+	references := map[string][]string{}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".sql" {
+			continue
+		}
+		b, err := afero.ReadFile(fs, filepath.Join(seedsDirectory, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		created, referenced := SeedSchemaReferences(string(b))
+		for _, schema := range created {
+			known[schema] = true
+		}
+		references[file.Name()] = referenced
+	}
+	missing := map[string][]string{}
+	for name, referenced := range references {
+		for _, schema := range referenced {
+			if !known[schema] {
+				missing[name] = append(missing[name], schema)
+			}
+		}
+	}
+	return missing, nil
+}
+
+// sourceSchemas lists the schemas of a postgres source
+func sourceSchemas(client hasura.PGSourceOps, source string) ([]string, error) {
+	resp, err := client.PGRunSQL(hasura.PGRunSQLInput{
+		SQL:      "SELECT schema_name FROM information_schema.schemata",
+		Source:   source,
+		ReadOnly: true,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing schemas of database %s", source)
+	}
+	if resp.ResultType != hasura.TuplesOK || len(resp.Result) < 1 {
+		return nil, fmt.Errorf("unexpected result when listing schemas of database %s", source)
+	}
+	var schemas []string
+	// the first row is the header
+	for _, row := range resp.Result[1:] {
+		if len(row) > 0 {
+			schemas = append(schemas, row[0])
+		}
+	}
+	return schemas, nil
+}
+
+// checkSeedSchemas warns about the seed files moved to each database which
+// refer to schemas missing in the database, databases on which queries
+// cannot be run are skipped
+func checkSeedSchemas(opts UpgradeToMuUpgradeProjectToMultipleSourcesOpts, databases []string, seedGroups map[string][]string, decisions *decisionLog) {
+	for _, database := range databases {
+		if len(seedGroups[database]) == 0 {
+			continue
+		}
+		schemas, err := sourceSchemas(opts.EC.APIClient.V2Query, database)
+		if err != nil {
+			opts.Logger.Debugf("skipping the check of schemas referenced by seed files of database %s: %v", database, err)
+			continue
+		}
+		missing, err := MissingSeedSchemas(opts.Fs, filepath.Join(opts.SeedsAbsDirectoryPath, database), schemas)
+		if err != nil {
+			opts.Logger.Warnf("checking schemas referenced by seed files of database %s: %v", database, err)
+			continue
+		}
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			opts.Logger.Warnf("seed file %s/%s refers to schemas which do not exist in database %s: %s, applying it might fail", database, name, database, strings.Join(missing[name], ", "))
+			decisions.record("seeds", "warn", "refers to schemas which do not exist in the database: "+strings.Join(missing[name], ", "), filepath.Join(database, name))
+		}
+	}
+}
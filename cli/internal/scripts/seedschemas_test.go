@@ -0,0 +1,35 @@
+package scripts
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeedSchemaReferences(t *testing.T) {
+	created, referenced := SeedSchemaReferences(`CREATE SCHEMA IF NOT EXISTS Billing;
+INSERT INTO billing.invoices (id) VALUES (1);
+INSERT INTO "Orders".items (id) SELECT id FROM public.users JOIN billing.accounts ON true;
+INSERT INTO users (id) VALUES (2);`)
+	assert.Equal(t, []string{"billing"}, created)
+	assert.Equal(t, []string{"billing", "Orders", "public"}, referenced)
+}
+
+func TestMissingSeedSchemas(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for file, content := range map[string]string{
+		"seeds/default/1_schema.sql":  "CREATE SCHEMA reporting;",
+		"seeds/default/2_reports.sql": "INSERT INTO reporting.daily VALUES (1);",
+		"seeds/default/3_orders.sql":  "INSERT INTO orders.items VALUES (1); INSERT INTO public.users VALUES (1);",
+		"seeds/default/notes.txt":     "INSERT INTO notes.items VALUES (1);",
+	} {
+		if err := afero.WriteFile(fs, file, []byte(content), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := MissingSeedSchemas(fs, "seeds/default", []string{"public", "hdb_catalog"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"3_orders.sql": {"orders"}}, got)
+}
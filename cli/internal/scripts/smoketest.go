@@ -0,0 +1,25 @@
+package scripts
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
+)
+
+// runSmokeTest runs an introspection query against the server, to check that
+// the server is responsive and is able to build a GraphQL schema using its
+// metadata. It returns the time taken by the query
+func runSmokeTest(client hasura.V1Graphql) (time.Duration, error) {
+	start := time.Now()
+	schema, err := client.GetIntrospectionSchema()
+	elapsed := time.Since(start)
+	if err != nil {
+		return elapsed, fmt.Errorf("running introspection query: %w", err)
+	}
+	s, ok := schema.(map[string]interface{})
+	if !ok || s["__schema"] == nil {
+		return elapsed, fmt.Errorf("introspection query did not return a schema")
+	}
+	return elapsed, nil
+}
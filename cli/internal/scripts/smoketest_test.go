@@ -0,0 +1,40 @@
+package scripts
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeV1Graphql struct {
+	schema hasura.IntrospectionSchema
+	err    error
+}
+
+func (f fakeV1Graphql) GetIntrospectionSchema() (hasura.IntrospectionSchema, error) {
+	return f.schema, f.err
+}
+
+func Test_runSmokeTest(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  fakeV1Graphql
+		wantErr bool
+	}{
+		{"schema is built", fakeV1Graphql{schema: map[string]interface{}{"__schema": map[string]interface{}{}}}, false},
+		{"no schema", fakeV1Graphql{schema: map[string]interface{}{}}, true},
+		{"query fails", fakeV1Graphql{err: fmt.Errorf("connection refused")}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runSmokeTest(tt.client)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package scripts
+
+import (
+	"fmt"
+	"strings"
+)
+
+// longest file name allowed by most filesystems, in bytes
+const maxDirectoryNameLength = 255
+
+// names reserved on windows, with or without an extension
+var reservedDirectoryNames = func() map[string]bool {
+	names := map[string]bool{"CON": true, "PRN": true, "AUX": true, "NUL": true}
+	for i := 1; i <= 9; i++ {
+		names[fmt.Sprintf("COM%d", i)] = true
+		names[fmt.Sprintf("LPT%d", i)] = true
+	}
+	return names
+}()
+
+// ValidateSourceDirectoryName checks if the name of a source can be used as
+// the name of its migrations and seeds directories on all platforms
+func ValidateSourceDirectoryName(name string) error {
+	invalid := func(reason string) error {
+		return fmt.Errorf("database name %q cannot be used as a directory name: %s, consider renaming the database on the server", name, reason)
+	}
+	if len(name) == 0 {
+		return invalid("name is empty")
+	}
+	if len(name) > maxDirectoryNameLength {
+		return invalid(fmt.Sprintf("name is longer than %d bytes", maxDirectoryNameLength))
+	}
+	if name == "." || name == ".." {
+		return invalid("name refers to a relative path")
+	}
+	for _, r := range name {
+		if r < 0x20 || strings.ContainsRune(`/\<>:"|?*`, r) {
+			return invalid(fmt.Sprintf("name contains %q", r))
+		}
+	}
+	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+		return invalid("name ends with a dot or a space")
+	}
+	base := strings.ToUpper(strings.SplitN(name, ".", 2)[0])
+	if reservedDirectoryNames[base] {
+		return invalid("name is reserved on windows")
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package scripts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSourceDirectoryName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"default", false},
+		{"my-db_2.replica", false},
+		{"", true},
+		{"..", true},
+		{"a/b", true},
+		{`a\b`, true},
+		{"a:b", true},
+		{"tab\tname", true},
+		{"trailing.", true},
+		{"trailing ", true},
+		{"con", true},
+		{"LPT1.db", true},
+		{"console", false},
+		{strings.Repeat("a", 256), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSourceDirectoryName(tt.name)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package scripts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// errCloneNotSupported is returned by cloneDirectory when the filesystem
+// does not support copy-on-write clones
+var errCloneNotSupported = errors.New("copy-on-write clones are not supported by the filesystem")
+
+// methods used to stage the project directory
+const (
+	stageMethodClone = "copy-on-write clone"
+	stageMethodCopy  = "copy"
+)
+
+// stagedProject is a copy of the project directory, next to it, on which the
+// update is run. The copy is a copy-on-write clone when the filesystem supports
+// it (eg: APFS, Btrfs, XFS), so that staging is near instant and takes no space.
+// Once the update is complete the copy replaces the project directory
+type stagedProject struct {
+	fs               afero.Fs
+	projectDirectory string
+	directory        string
+	method           string
+}
+
+func stageProject(fs afero.Fs, projectDirectory string, now time.Time) (*stagedProject, error) {
+	projectDirectory = filepath.Clean(projectDirectory)
+	stage := &stagedProject{
+		fs:               fs,
+		projectDirectory: projectDirectory,
+		directory: filepath.Join(filepath.Dir(projectDirectory),
+			fmt.Sprintf(".%s.update-v3-%d", filepath.Base(projectDirectory), now.UnixNano()/int64(time.Millisecond))),
+	}
+	// clones can only be made of directories on disk
+	if _, ok := fs.(*afero.OsFs); ok {
+		err := cloneDirectory(projectDirectory, stage.directory)
+		if err == nil {
+			stage.method = stageMethodClone
+			return stage, nil
+		}
+		if removeErr := os.RemoveAll(stage.directory); removeErr != nil {
+			return nil, removeErr
+		}
+		if !errors.Is(err, errCloneNotSupported) {
+			return nil, err
+		}
+	}
+	if err := copyPath(fs, projectDirectory, stage.directory); err != nil {
+		return nil, err
+	}
+	stage.method = stageMethodCopy
+	return stage, nil
+}
+
+// projectFs returns a filesystem on which paths in the project
+// directory refer to the same paths in the staged copy
+func (s *stagedProject) projectFs() afero.Fs {
+	return &redirectFs{Fs: s.fs, from: s.projectDirectory, to: s.directory}
+}
+
+// swap replaces the project directory with the staged copy. The project
+// directory is moved aside first and is moved back when the staged copy
+// cannot be moved in its place
+func (s *stagedProject) swap() error {
+	previous := s.directory + ".previous"
+	if err := s.fs.Rename(s.projectDirectory, previous); err != nil {
+		return err
+	}
+	if err := s.fs.Rename(s.directory, s.projectDirectory); err != nil {
+		if restoreErr := s.fs.Rename(previous, s.projectDirectory); restoreErr != nil {
+			return fmt.Errorf("%v, and moving the project directory back from %s failed: %w", err, previous, restoreErr)
+		}
+		return err
+	}
+	return s.fs.RemoveAll(previous)
+}
+
+// finish swaps the staged copy in place of the project directory when the
+// update is complete, otherwise the staged copy is discarded and the project
+// directory is left as it is. The error of the update is returned wrapped
+func (s *stagedProject) finish(err error, completed bool, logger *logrus.Logger) error {
+	if err != nil && !completed {
+		if discardErr := s.discard(); discardErr != nil {
+			logger.Warnf("removing staged project directory %s: %v", s.directory, discardErr)
+		}
+		return fmt.Errorf("update failed, the project directory was left as it is: %w", err)
+	}
+	if swapErr := s.swap(); swapErr != nil {
+		return fmt.Errorf("project was updated in %s, but it could not replace the project directory: %w", s.directory, swapErr)
+	}
+	return err
+}
+
+// discard removes the staged copy, leaving the project directory as it is
+func (s *stagedProject) discard() error {
+	return s.fs.RemoveAll(s.directory)
+}
+
+// redirectFs is a filesystem which uses the paths in to
+// in place of paths in from, other paths are left as they are
+type redirectFs struct {
+	afero.Fs
+	from, to string
+}
+
+func (r *redirectFs) path(name string) string {
+	name = filepath.Clean(name)
+	if name == r.from {
+		return r.to
+	}
+	if strings.HasPrefix(name, r.from+string(filepath.Separator)) {
+		return r.to + strings.TrimPrefix(name, r.from)
+	}
+	return name
+}
+
+func (r *redirectFs) Create(name string) (afero.File, error) {
+	return r.Fs.Create(r.path(name))
+}
+
+func (r *redirectFs) Mkdir(name string, perm os.FileMode) error {
+	return r.Fs.Mkdir(r.path(name), perm)
+}
+
+func (r *redirectFs) MkdirAll(path string, perm os.FileMode) error {
+	return r.Fs.MkdirAll(r.path(path), perm)
+}
+
+func (r *redirectFs) Open(name string) (afero.File, error) {
+	return r.Fs.Open(r.path(name))
+}
+
+func (r *redirectFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	return r.Fs.OpenFile(r.path(name), flag, perm)
+}
+
+func (r *redirectFs) Remove(name string) error {
+	return r.Fs.Remove(r.path(name))
+}
+
+func (r *redirectFs) RemoveAll(path string) error {
+	return r.Fs.RemoveAll(r.path(path))
+}
+
+func (r *redirectFs) Rename(oldname, newname string) error {
+	return r.Fs.Rename(r.path(oldname), r.path(newname))
+}
+
+func (r *redirectFs) Stat(name string) (os.FileInfo, error) {
+	return r.Fs.Stat(r.path(name))
+}
+
+func (r *redirectFs) Chmod(name string, mode os.FileMode) error {
+	return r.Fs.Chmod(r.path(name), mode)
+}
+
+func (r *redirectFs) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return r.Fs.Chtimes(r.path(name), atime, mtime)
+}
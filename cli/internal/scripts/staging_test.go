@@ -0,0 +1,210 @@
+package scripts
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/briandowns/spinner"
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStagedProject(t *testing.T) {
+	tests := []struct {
+		name        string
+		update      func(fs afero.Fs, project string) error
+		wantErr     bool
+		wantProject map[string]string
+	}{
+		{
+			"staged update replaces the project directory",
+			func(fs afero.Fs, project string) error {
+				migrations := filepath.Join(project, "migrations")
+				if err := copyMigration(fs, "1604855964903_test", migrations, filepath.Join(migrations, "default"), nil); err != nil {
+					return err
+				}
+				if err := removeDirectories(fs, migrations, []string{"1604855964903_test"}); err != nil {
+					return err
+				}
+				return afero.WriteFile(fs, filepath.Join(project, "config.yaml"), []byte("version: 3\n"), 0644)
+			},
+			false,
+			map[string]string{
+				"config.yaml": "version: 3\n",
+				"migrations/default/1604855964903_test/up.sql": "create table test();",
+			},
+		},
+		{
+			"failed update leaves the project directory as it is",
+			func(fs afero.Fs, project string) error {
+				migrations := filepath.Join(project, "migrations")
+				if err := copyMigration(fs, "1604855964903_test", migrations, filepath.Join(migrations, "default"), nil); err != nil {
+					return err
+				}
+				if err := removeDirectories(fs, migrations, []string{"1604855964903_test"}); err != nil {
+					return err
+				}
+				if err := afero.WriteFile(fs, filepath.Join(project, "config.yaml"), []byte("version: 3\n"), 0644); err != nil {
+					return err
+				}
+				// a later phase of the update fails
+				return errors.New("copying state failed")
+			},
+			true,
+			map[string]string{
+				"config.yaml":                          "version: 2\n",
+				"migrations/1604855964903_test/up.sql": "create table test();",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := afero.NewOsFs()
+			work := t.TempDir()
+			project := filepath.Join(work, "project")
+			if err := fs.MkdirAll(filepath.Join(project, "migrations", "1604855964903_test"), os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			for name, content := range map[string]string{
+				filepath.Join(project, "config.yaml"):                                "version: 2\n",
+				filepath.Join(project, "migrations", "1604855964903_test", "up.sql"): "create table test();",
+				filepath.Join(work, "other.yaml"):                                    "other",
+			} {
+				if err := afero.WriteFile(fs, name, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// the project directory is cloned or copied depending on the filesystem
+			stage, err := stageProject(fs, project, time.Unix(1604855964, 0))
+			assert.NoError(t, err)
+			assert.Equal(t, filepath.Join(work, ".project.update-v3-1604855964000"), stage.directory)
+
+			projectFs := stage.projectFs()
+			updateErr := tc.update(projectFs, project)
+			// paths outside of the project directory are not redirected
+			got, err := afero.ReadFile(projectFs, filepath.Join(work, "other.yaml"))
+			assert.NoError(t, err)
+			assert.Equal(t, "other", string(got))
+
+			err = stage.finish(updateErr, updateErr == nil, logrus.New())
+			if tc.wantErr {
+				assert.Error(t, err)
+				assert.True(t, errors.Is(err, updateErr))
+			} else {
+				assert.NoError(t, err)
+			}
+			for name, want := range tc.wantProject {
+				got, err := afero.ReadFile(fs, filepath.Join(project, filepath.FromSlash(name)))
+				assert.NoError(t, err)
+				assert.Equal(t, want, string(got), name)
+			}
+			for _, name := range []string{stage.directory, stage.directory + ".previous"} {
+				ok, err := afero.Exists(fs, name)
+				assert.NoError(t, err)
+				assert.False(t, ok, name)
+			}
+		})
+	}
+}
+
+func TestStageProjectCopiesWhenCloneIsNotPossible(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(fs, "/work/project/config.yaml", []byte("version: 2\n"), 0644))
+
+	stage, err := stageProject(fs, "/work/project", time.Unix(1604855964, 0))
+	assert.NoError(t, err)
+	// clones are only made of directories on disk
+	assert.Equal(t, stageMethodCopy, stage.method)
+	got, err := afero.ReadFile(stage.projectFs(), "/work/project/config.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "version: 2\n", string(got))
+	ok, err := afero.Exists(fs, "/work/.project.update-v3-1604855964000/config.yaml")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestStagedUpdateWritesDecisionLog(t *testing.T) {
+	tests := []struct {
+		name       string
+		configFile string
+		wantErr    bool
+		wantConfig string
+	}{
+		{"update is complete", "config.yaml", false, "version: 3\n"},
+		// the config file cannot be updated once migrations were moved
+		{"update fails", "missing.yaml", true, "version: 2\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := afero.NewOsFs()
+			work := t.TempDir()
+			project := filepath.Join(work, "project")
+			for name, content := range map[string]string{
+				filepath.Join(project, "config.yaml"):                                "version: 2\n",
+				filepath.Join(project, "migrations", "1604855964903_test", "up.sql"): "create table test();",
+				filepath.Join(project, "seeds", "users.sql"):                         "insert into users values (1);",
+				filepath.Join(project, "metadata", "version.yaml"):                   "version: 2\n",
+			} {
+				if err := fs.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+				if err := afero.WriteFile(fs, name, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			logger := logrus.New()
+			ec := &cli.ExecutionContext{
+				Logger:      logger,
+				Spinner:     spinner.New(spinner.CharSets[7], 0),
+				ConfigFile:  filepath.Join(project, tc.configFile),
+				MetadataDir: filepath.Join(project, "metadata"),
+				Config: &cli.Config{
+					Version:             cli.V2,
+					ServerConfig:        cli.ServerConfig{Endpoint: "http://localhost:8080"},
+					MetadataDirectory:   "metadata",
+					MigrationsDirectory: "migrations",
+				},
+			}
+			decisionLog := filepath.Join(project, "decisions.json")
+			err := UpdateProjectV3(UpgradeToMuUpgradeProjectToMultipleSourcesOpts{
+				Fs:                         fs,
+				ProjectDirectory:           project,
+				MigrationsAbsDirectoryPath: filepath.Join(project, "migrations"),
+				SeedsAbsDirectoryPath:      filepath.Join(project, "seeds"),
+				Logger:                     logger,
+				EC:                         ec,
+				Offline:                    true,
+				NonInteractive:             true,
+				TargetDatabase:             "default",
+				Staged:                     true,
+				DecisionLogPath:            decisionLog,
+			})
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			config, err := afero.ReadFile(fs, filepath.Join(project, "config.yaml"))
+			assert.NoError(t, err)
+			assert.Contains(t, string(config), tc.wantConfig)
+			// the original migrations are only removed when the update is complete
+			ok, err := afero.Exists(fs, filepath.Join(project, "migrations", "1604855964903_test"))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantErr, ok)
+			// the decision log is written to the project directory, not to the
+			// staged copy, which was swapped in or discarded before it is written
+			ok, err = afero.Exists(fs, decisionLog)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			staged, err := filepath.Glob(filepath.Join(work, ".project.update-v3-*"))
+			assert.NoError(t, err)
+			assert.Empty(t, staged)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package scripts
+
+import (
+	"fmt"
+
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/hasura/graphql-engine/cli/internal/metadatautil"
+	"github.com/hasura/graphql-engine/cli/internal/statestore"
+	"github.com/hasura/graphql-engine/cli/migrate"
+	"github.com/pkg/errors"
+)
+
+// methods by which a prior copy of state is detected
+const (
+	// the isStateCopyCompleted flag is set in catalog state
+	StateCopyMethodFlag = "is_state_copy_completed"
+	// older CLI versions did not set the flag, but migrations state
+	// is recorded for the source in catalog state
+	StateCopyMethodLegacyMigrations = "legacy_migrations_state"
+	// the earliest CLI versions recorded copied migrations state
+	// without a database name
+	StateCopyMethodLegacyUnnamedDatabase = "legacy_unnamed_database"
+)
+
+// DetectPriorStateCopy inspects catalog state on the server for markers left by
+// a previous copy of state to source, including the ones left by older CLI versions.
+// When state was copied, method is one of the StateCopyMethod* constants.
+func DetectPriorStateCopy(ec *cli.ExecutionContext, source string) (copied bool, method string, err error) {
+	state, err := statestore.NewCLICatalogState(ec.APIClient.V1Metadata).Get()
+	if err != nil {
+		return false, "", errors.Wrap(err, "getting catalog state")
+	}
+	copied, method = detectPriorStateCopy(state, source)
+	return copied, method, nil
+}
+
+func detectPriorStateCopy(state *statestore.CLIState, source string) (bool, string) {
+	if state == nil {
+		return false, ""
+	}
+	if state.IsStateCopyCompleted {
+		return true, StateCopyMethodFlag
+	}
+	// state recorded by a copy which was interrupted is copied again
+	if state.IsStateCopyInProgress {
+		return false, ""
+	}
+	if len(state.GetMigrationsByDatabase(source)) > 0 {
+		return true, StateCopyMethodLegacyMigrations
+	}
+	if len(state.GetMigrationsByDatabase("")) > 0 {
+		return true, StateCopyMethodLegacyUnnamedDatabase
+	}
+	return false, ""
+}
+
+// markStateCopyStarted sets the isStateCopyInProgress flag in catalog state,
+// before state is copied to it
+func markStateCopyStarted(ec *cli.ExecutionContext) error {
+	err := updateCatalogState(ec, func(state *statestore.CLIState) {
+		state.IsStateCopyInProgress = true
+	})
+	return errors.Wrap(err, "marking state copy as started")
+}
+
+// markStateCopyCompleted sets the isStateCopyCompleted flag in catalog state,
+// along with label when it is not empty
+func markStateCopyCompleted(ec *cli.ExecutionContext, label string) error {
+	err := updateCatalogState(ec, func(state *statestore.CLIState) {
+		state.IsStateCopyCompleted = true
+		state.IsStateCopyInProgress = false
+		if len(label) > 0 {
+			state.StateCopyLabel = label
+		}
+	})
+	return errors.Wrap(err, "marking state copy as completed")
+}
+
+func updateCatalogState(ec *cli.ExecutionContext, update func(state *statestore.CLIState)) error {
+	catalogState := statestore.NewCLICatalogState(ec.APIClient.V1Metadata)
+	state, err := catalogState.Get()
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		state = &statestore.CLIState{}
+	}
+	state.Init()
+	update(state)
+	_, err = catalogState.Set(*state)
+	return err
+}
+
+// AssertStateCopySupported returns an error when migrations state cannot be
+// copied to catalog state for source, either because the server does not
+// have catalog state or because migrations cannot be applied on the kind of
+// the source (eg: bigquery)
+func AssertStateCopySupported(ec *cli.ExecutionContext, source string) error {
+	if !ec.HasMetadataV3 {
+		return assertStateCopySupported(false, ec.Version.Server, nil, source)
+	}
+	sources, err := metadatautil.GetSourcesAndKind(ec.APIClient.V1Metadata.ExportMetadata)
+	if err != nil {
+		return errors.Wrap(err, "listing databases")
+	}
+	return assertStateCopySupported(true, ec.Version.Server, sources, source)
+}
+
+func assertStateCopySupported(hasMetadataV3 bool, serverVersion string, sources []metadatautil.Source, source string) error {
+	if !hasMetadataV3 {
+		return fmt.Errorf("cannot copy state to database %s: server version %v does not support catalog state, metadata version >= 3 is required", source, serverVersion)
+	}
+	for _, s := range sources {
+		if s.Name != source {
+			continue
+		}
+		if !migrate.IsMigrationsSupported(s.Kind) {
+			return fmt.Errorf("cannot copy state to database %s: migrations are not supported on databases of kind %s, so it cannot hold migrations state", source, s.Kind)
+		}
+		return nil
+	}
+	return fmt.Errorf("cannot copy state to database %s: database was not found on the server", source)
+}
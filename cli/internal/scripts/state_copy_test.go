@@ -0,0 +1,89 @@
+package scripts
+
+import (
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/internal/metadatautil"
+	"github.com/hasura/graphql-engine/cli/internal/statestore"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_detectPriorStateCopy(t *testing.T) {
+	tests := []struct {
+		name       string
+		state      *statestore.CLIState
+		wantCopied bool
+		wantMethod string
+	}{
+		{"no catalog state", nil, false, ""},
+		{"empty catalog state", &statestore.CLIState{}, false, ""},
+		{
+			"flag is set",
+			&statestore.CLIState{IsStateCopyCompleted: true},
+			true,
+			StateCopyMethodFlag,
+		},
+		{
+			"migrations state of the source",
+			&statestore.CLIState{Migrations: statestore.MigrationsState{"default": {"1604855964903": false}}},
+			true,
+			StateCopyMethodLegacyMigrations,
+		},
+		{
+			"migrations state without a database name",
+			&statestore.CLIState{Migrations: statestore.MigrationsState{"": {"1604855964903": false}}},
+			true,
+			StateCopyMethodLegacyUnnamedDatabase,
+		},
+		{
+			"copy was interrupted",
+			&statestore.CLIState{IsStateCopyInProgress: true, Migrations: statestore.MigrationsState{"default": {"1604855964903": false}}},
+			false,
+			"",
+		},
+		{
+			"migrations state of another source",
+			&statestore.CLIState{Migrations: statestore.MigrationsState{"other": {"1604855964903": false}}},
+			false,
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			copied, method := detectPriorStateCopy(tt.state, "default")
+			assert.Equal(t, tt.wantCopied, copied)
+			assert.Equal(t, tt.wantMethod, method)
+		})
+	}
+}
+
+func Test_assertStateCopySupported(t *testing.T) {
+	sources := []metadatautil.Source{
+		{Name: "default", Kind: hasura.SourceKindPG},
+		{Name: "mssql", Kind: hasura.SourceKindMSSQL},
+		{Name: "bq", Kind: "bigquery"},
+	}
+	tests := []struct {
+		name          string
+		hasMetadataV3 bool
+		source        string
+		wantErr       bool
+	}{
+		{"postgres database", true, "default", false},
+		{"mssql database", true, "mssql", false},
+		{"bigquery database", true, "bq", true},
+		{"unknown database", true, "unknown", true},
+		{"server without catalog state", false, "default", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertStateCopySupported(tt.hasMetadataV3, "v1.3.3", sources, tt.source)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package scripts
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/hasura/graphql-engine/cli/internal/statestore"
+	"github.com/sirupsen/logrus"
+)
+
+// output formats of UpdateProjectV3
+const (
+	// OutputFormatText logs the progress of the update
+	OutputFormatText = "text"
+	// OutputFormatJSON writes an UpdateSummary as JSON once the update is
+	// complete, instead of logging the progress of the update
+	OutputFormatJSON = "json"
+)
+
+// UpdateSummary describes an update of a project to config v3 done by UpdateProjectV3
+type UpdateSummary struct {
+	TargetDatabase   string `json:"target_database"`
+	MigrationsMoved  int    `json:"migrations_moved"`
+	SeedsMoved       int    `json:"seeds_moved"`
+	OldConfigVersion int    `json:"old_config_version"`
+	NewConfigVersion int    `json:"new_config_version"`
+	// BackupDirectory is set when the backup of the project directory was kept
+	BackupDirectory string `json:"backup_directory,omitempty"`
+	// Offline is set when the update has to be completed using ReconcileOfflineUpdate
+	Offline bool `json:"offline,omitempty"`
+	// StateChanges are the changes made to catalog state by the state copy, set with ShowStateDiff
+	StateChanges []statestore.StateChange `json:"state_changes,omitempty"`
+}
+
+func writeUpdateSummary(w io.Writer, summary UpdateSummary) error {
+	b, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+func validateOutputFormat(format string) error {
+	switch format {
+	case "", OutputFormatText, OutputFormatJSON:
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q, has to be %s or %s", format, OutputFormatText, OutputFormatJSON)
+}
+
+// silenceProgress hides the spinner and the logs of loggers below the warning
+// level, so that only the summary is written to the output. The returned
+// function restores them
+func silenceProgress(ec *cli.ExecutionContext, loggers ...*logrus.Logger) func() {
+	spinnerWriter := ec.Spinner.Writer
+	isTerminal := ec.IsTerminal
+	levels := make([]logrus.Level, len(loggers))
+	ec.Spinner.Writer = ioutil.Discard
+	// Spin logs messages instead of starting the spinner when not on a terminal
+	ec.IsTerminal = false
+	for idx, logger := range loggers {
+		levels[idx] = logger.GetLevel()
+		if levels[idx] > logrus.WarnLevel {
+			logger.SetLevel(logrus.WarnLevel)
+		}
+	}
+	return func() {
+		ec.Spinner.Writer = spinnerWriter
+		ec.IsTerminal = isTerminal
+		// in reverse, since the same logger can be passed more than once
+		for idx := len(loggers) - 1; idx >= 0; idx-- {
+			loggers[idx].SetLevel(levels[idx])
+		}
+	}
+}
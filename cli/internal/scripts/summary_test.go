@@ -0,0 +1,47 @@
+package scripts
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/briandowns/spinner"
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_writeUpdateSummary(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, writeUpdateSummary(&out, UpdateSummary{
+		TargetDatabase:   "default",
+		MigrationsMoved:  2,
+		SeedsMoved:       1,
+		OldConfigVersion: 2,
+		NewConfigVersion: 3,
+	}))
+	assert.JSONEq(t, `{
+		"target_database": "default",
+		"migrations_moved": 2,
+		"seeds_moved": 1,
+		"old_config_version": 2,
+		"new_config_version": 3
+	}`, out.String())
+}
+
+func Test_silenceProgress(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.DebugLevel)
+	var spinnerOut bytes.Buffer
+	s := spinner.New(spinner.CharSets[7], 0)
+	s.Writer = &spinnerOut
+	ec := &cli.ExecutionContext{Logger: logger, Spinner: s, IsTerminal: true}
+
+	restore := silenceProgress(ec, logger, ec.Logger)
+	assert.Equal(t, logrus.WarnLevel, logger.GetLevel())
+	assert.False(t, ec.IsTerminal)
+	restore()
+	assert.Equal(t, logrus.DebugLevel, logger.GetLevel())
+	assert.True(t, ec.IsTerminal)
+	assert.Equal(t, &spinnerOut, ec.Spinner.Writer)
+	assert.Error(t, validateOutputFormat("yaml"))
+}
@@ -0,0 +1,114 @@
+package scripts
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	PhaseStateCopy   = "state_copy"
+	PhaseMoves       = "moves"
+	PhaseConfigWrite = "config_write"
+	PhaseCleanup     = "cleanup"
+	PhaseExport      = "export"
+	PhaseReload      = "reload"
+)
+
+// PhaseTiming is the wall-clock duration of a phase of UpdateProjectV3
+type PhaseTiming struct {
+	Phase    string
+	Duration time.Duration
+}
+
+// PhaseSpan is a phase of UpdateProjectV3 recorded by a Tracer
+type PhaseSpan struct {
+	// Name is the name of the phase prefixed by "update_project_v3.", eg: update_project_v3.moves
+	Name       string
+	Start, End time.Time
+	// Attributes describe the update, eg: target_database, migrations_moved and
+	// duration_ms. Only the attributes known when the phase ended are set
+	Attributes map[string]interface{}
+}
+
+// Tracer records the phases of UpdateProjectV3 as spans, so that the update can
+// be traced from an instrumented program. Spans are recorded once a phase has
+// ended, eg: an OpenTelemetry tracer can be adapted by starting and ending a
+// span with the timestamps of the PhaseSpan
+type Tracer interface {
+	RecordSpan(span PhaseSpan)
+}
+
+// phaseTimer records the time elapsed between consecutive calls to done,
+// each call marking the end of a phase and the start of the next one
+type phaseTimer struct {
+	logger  *logrus.Logger
+	last    time.Time
+	timings []PhaseTiming
+	now     func() time.Time
+	// deadline is the time after which done returns an error, zero means no deadline
+	deadline time.Time
+	// tracer when not nil records each phase as a span carrying attributes
+	tracer     Tracer
+	attributes map[string]interface{}
+}
+
+// newPhaseTimer returns a phaseTimer, a timeout > 0 bounds the total
+// time taken by all phases (measured from now)
+func newPhaseTimer(logger *logrus.Logger, timeout time.Duration) *phaseTimer {
+	t := &phaseTimer{logger: logger, now: time.Now}
+	t.last = t.now()
+	if timeout > 0 {
+		t.deadline = t.last.Add(timeout)
+	}
+	return t
+}
+
+// setAttribute sets an attribute of the spans of the phases ending after the call
+func (t *phaseTimer) setAttribute(key string, value interface{}) {
+	if t.attributes == nil {
+		t.attributes = map[string]interface{}{}
+	}
+	t.attributes[key] = value
+}
+
+// start resets the start of the next phase to now, so that
+// time spent waiting between phases (eg: on prompts) is not counted
+func (t *phaseTimer) start() {
+	t.last = t.now()
+}
+
+// done marks the end of phase. When the deadline of the timer has passed
+// an error wrapping context.DeadlineExceeded is returned, so that the
+// operation can be stopped before the next phase is started
+func (t *phaseTimer) done(phase string) error {
+	now := t.now()
+	duration := now.Sub(t.last)
+	if t.tracer != nil {
+		attributes := make(map[string]interface{}, len(t.attributes)+1)
+		for key, value := range t.attributes {
+			attributes[key] = value
+		}
+		attributes["duration_ms"] = duration.Milliseconds()
+		t.tracer.RecordSpan(PhaseSpan{Name: "update_project_v3." + phase, Start: t.last, End: now, Attributes: attributes})
+	}
+	t.last = now
+	t.timings = append(t.timings, PhaseTiming{Phase: phase, Duration: duration})
+	if t.logger != nil {
+		t.logger.Debugf("%s took %s", phase, duration)
+	}
+	if !t.deadline.IsZero() && now.After(t.deadline) {
+		return fmt.Errorf("timed out after %s: %w", phase, context.DeadlineExceeded)
+	}
+	return nil
+}
+
+func (t *phaseTimer) total() time.Duration {
+	var total time.Duration
+	for _, timing := range t.timings {
+		total += timing.Duration
+	}
+	return total
+}
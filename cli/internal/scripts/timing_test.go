@@ -0,0 +1,78 @@
+package scripts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPhaseTimer(t *testing.T) {
+	clock := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	timer := &phaseTimer{now: func() time.Time { return clock }}
+	timer.start()
+
+	clock = clock.Add(2 * time.Second)
+	timer.done(PhaseStateCopy)
+	clock = clock.Add(time.Minute)
+	// time spent before start is not counted
+	timer.start()
+	clock = clock.Add(3 * time.Second)
+	timer.done(PhaseExport)
+
+	assert.Equal(t, []PhaseTiming{
+		{Phase: PhaseStateCopy, Duration: 2 * time.Second},
+		{Phase: PhaseExport, Duration: 3 * time.Second},
+	}, timer.timings)
+	assert.Equal(t, 5*time.Second, timer.total())
+}
+
+func TestPhaseTimer_deadline(t *testing.T) {
+	clock := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	timer := &phaseTimer{now: func() time.Time { return clock }, deadline: clock.Add(10 * time.Second)}
+	timer.start()
+
+	clock = clock.Add(5 * time.Second)
+	assert.NoError(t, timer.done(PhaseStateCopy))
+	clock = clock.Add(6 * time.Second)
+	err := timer.done(PhaseMoves)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+type fakeTracer []PhaseSpan
+
+func (f *fakeTracer) RecordSpan(span PhaseSpan) {
+	*f = append(*f, span)
+}
+
+func TestPhaseTimer_tracer(t *testing.T) {
+	clock := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	start := clock
+	tracer := &fakeTracer{}
+	timer := &phaseTimer{now: func() time.Time { return clock }, tracer: tracer}
+	timer.start()
+	timer.setAttribute("target_database", "default")
+
+	clock = clock.Add(2 * time.Second)
+	assert.NoError(t, timer.done(PhaseStateCopy))
+	timer.setAttribute("migrations_moved", 3)
+	clock = clock.Add(time.Second)
+	assert.NoError(t, timer.done(PhaseMoves))
+
+	assert.Equal(t, fakeTracer{
+		{
+			Name:       "update_project_v3.state_copy",
+			Start:      start,
+			End:        start.Add(2 * time.Second),
+			Attributes: map[string]interface{}{"target_database": "default", "duration_ms": int64(2000)},
+		},
+		{
+			Name:       "update_project_v3.moves",
+			Start:      start.Add(2 * time.Second),
+			End:        start.Add(3 * time.Second),
+			Attributes: map[string]interface{}{"target_database": "default", "migrations_moved": 3, "duration_ms": int64(1000)},
+		},
+	}, *tracer)
+}
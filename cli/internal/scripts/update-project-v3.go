@@ -1,9 +1,17 @@
 package scripts
 
 import (
+	"io"
+	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
 	"github.com/hasura/graphql-engine/cli/internal/metadataobject"
 
 	"github.com/hasura/graphql-engine/cli/internal/metadatautil"
@@ -13,8 +21,10 @@ import (
 	"github.com/hasura/graphql-engine/cli/internal/statestore"
 	"github.com/hasura/graphql-engine/cli/internal/statestore/migrations"
 	"github.com/hasura/graphql-engine/cli/internal/statestore/settings"
+	"github.com/hasura/graphql-engine/cli/seed"
 
 	"github.com/hasura/graphql-engine/cli"
+	"github.com/hasura/graphql-engine/cli/migrate"
 
 	"fmt"
 
@@ -22,8 +32,18 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
+	"gopkg.in/yaml.v2"
 )
 
+// MetadataHandler exports metadata from the server and writes it to the project,
+// it is implemented by *metadataobject.Handler
+type MetadataHandler interface {
+	ExportMetadataResumable(fs afero.Fs, stagingDir string, resume bool) (map[string][]byte, error)
+	WriteMetadata(files map[string][]byte) error
+	VerifyMetadataWrite(dir string, files map[string][]byte) ([]string, error)
+	metadataReloader
+}
+
 type UpgradeToMuUpgradeProjectToMultipleSourcesOpts struct {
 	EC *cli.ExecutionContext
 	Fs afero.Fs
@@ -33,11 +53,155 @@ type UpgradeToMuUpgradeProjectToMultipleSourcesOpts struct {
 	MigrationsAbsDirectoryPath string
 	SeedsAbsDirectoryPath      string
 	Logger                     *logrus.Logger
+	// CompactMigrationState when set will prune versions copied to catalog state
+	// which no longer have a migration directory on disk (after confirmation)
+	CompactMigrationState bool
+	// CheckOnly when set will only run the pre checks and write a report
+	// of them as JSON to stdout, without making any changes
+	CheckOnly bool
+	// StateStore is the name of a state store registered in the statestore
+	// registry to which state is copied, defaults to statestore.StateStoreCatalog
+	StateStore string
+	// ConfirmationThreshold is the number of migrations to be moved above which
+	// the name of the target database has to be typed to confirm the update,
+	// a value <= 0 disables it
+	ConfirmationThreshold int
+	// Sources when not empty is used as the list of databases to choose the
+	// target database from, instead of listing the databases on the server
+	Sources []string
+	// Offline when set only restructures the project directory and updates the
+	// config, without contacting the server. State is copied and metadata is
+	// exported later using ReconcileOfflineUpdate, once the server is reachable.
+	Offline bool
+	// StrictSeedValidation when set aborts the update when a seed file fails
+	// validation, instead of only warning about it
+	StrictSeedValidation bool
+	// NormalizeLineEndings when set converts CRLF line endings of the moved
+	// SQL migrations and seeds to LF
+	NormalizeLineEndings bool
+	// Timeout when > 0 bounds the time taken by the update once it is confirmed.
+	// When exceeded the update is stopped at the next phase boundary, in which
+	// case the project can be left partially updated
+	Timeout time.Duration
+	// ReloadMetadata when set reloads metadata on the server once the update
+	// is complete, failing when the server reports inconsistent metadata
+	ReloadMetadata bool
+	// StopIfServerActive when set fails the pre checks when the server appears
+	// to be applying migrations, which is otherwise only warned about
+	StopIfServerActive bool
+	// EmitAPICalls when set to one of the APICallsFormat* formats writes the calls
+	// which would be made to the server to copy state and export metadata to
+	// stdout, instead of updating the project, so that they can be run manually
+	EmitAPICalls string
+	// ExportConcurrency is the number of metadata objects exported concurrently
+	// by the default metadata handler
+	ExportConcurrency int
+	// MetadataHandler is used to export metadata once the project is updated,
+	// defaults to a handler created using metadataobject.NewHandlerFromEC
+	MetadataHandler MetadataHandler
+	// AllowInconsistentMetadata when set continues the update when metadata on
+	// the server is inconsistent, warning about each inconsistent object instead
+	// of failing. The inconsistent objects are exported to the project as they are
+	AllowInconsistentMetadata bool
+	// SeedConflictStrategy is one of the SeedConflict* strategies, used for seed
+	// files having the same name as a file in the target seeds directory,
+	// defaults to SeedConflictFail
+	SeedConflictStrategy string
+	// MigrationConflictStrategy is one of the MigrationConflict* strategies,
+	// used for migrations which already exist in the target migrations
+	// directory, defaults to MigrationConflictPrompt
+	MigrationConflictStrategy string
+	// Label when not empty is recorded in catalog state once state is copied
+	// (eg: "updated by CI run #1234"), so that it can be known later who or
+	// what updated the project
+	Label string
+	// DryRun when set logs the operations the update would run, checking that
+	// state can be read and listing the metadata files which would be written,
+	// without making changes to the project directory or the server
+	DryRun bool
+	// DatabaseMappingPath when set is the path of a YAML file of
+	// DatabaseMappingRule, used to move migrations and seeds to databases
+	// other than the target database, based on their name
+	DatabaseMappingPath string
+	// DatabasePrefixes are rules of the form <database>=<prefix> (see
+	// ParseDatabasePrefixes), applied after the rules of DatabaseMappingPath
+	DatabasePrefixes []string
+	// NoRollback when set leaves the project directory as it is when the
+	// update fails, instead of restoring it from the backup made before the
+	// update. It can be restored later using RollbackUpdateProjectV3
+	NoRollback bool
+	// KeepBackup when set keeps the backup of the migrations, seeds and
+	// metadata directories and the config file made before the update
+	// once the update is complete, it is removed otherwise
+	KeepBackup bool
+	// MetadataSnapshotPath when set is the path metadata on the server is
+	// written to before the update, so that it can be restored using
+	// RestoreServerMetadata
+	MetadataSnapshotPath string
+	// OutputFormat is one of the OutputFormat* formats, defaults to OutputFormatText.
+	// With OutputFormatJSON, an UpdateSummary is written to Output (os.Stdout by
+	// default) once the update is complete, the spinner and info logs are hidden
+	OutputFormat string
+	Output       io.Writer
+	// TargetDatabase when set is the database migrations and seeds are moved
+	// to, instead of asking for it. It has to be one of the databases on the server
+	TargetDatabase string
+	// NonInteractive when set never asks for input, so that the update can be
+	// run in automation. Confirmations are skipped, the only database on the
+	// server is used when TargetDatabase is not set and the update fails
+	// when there are several databases or a decision has to be made
+	NonInteractive bool
+	// IncludeLegacyTimestamps when set also moves migration directories named
+	// using the 10 digit timestamp of older CLI versions (<timestamp>_<name>),
+	// only 13 digit timestamps are matched otherwise
+	IncludeLegacyTimestamps bool
+	// CheckGlobalVersions when set warns about migration versions used by
+	// more than one database once migrations were moved, for projects
+	// relying on migrations being ordered by version across databases
+	CheckGlobalVersions bool
+	// CheckSeedSchemas when set warns about seed files which refer to
+	// schemas that do not exist in the database they were moved to, found
+	// using a best-effort analysis of their SQL
+	CheckSeedSchemas bool
+	// ShowStateDiff when set logs the changes made to catalog state by the
+	// state copy, read before and after it, and adds them to the summary
+	ShowStateDiff bool
+	// ForceStateCopy when set copies state to catalog state even when it was
+	// already copied by an earlier run of the update, which is skipped otherwise
+	ForceStateCopy bool
+	// Staged when set runs the update on a copy of the project directory,
+	// made next to it, which replaces the project directory once the update is
+	// complete. The copy is a copy-on-write clone when the filesystem supports
+	// it, a regular copy otherwise. NoRollback and KeepBackup are not used,
+	// since the project directory is not changed when the update fails
+	Staged bool
+	// SettingsAllowlist when not empty is the list of CLI settings copied along
+	// with migrations state, other settings are not copied. All settings are
+	// copied when it is empty
+	SettingsAllowlist []string
+	// SmokeTest when set runs an introspection query against the server once
+	// the update is complete, to check that the server can build a schema
+	// using its metadata. The result is recorded in the decision log
+	SmokeTest bool
+	// CheckDatabasesHealth when set checks that the server can run a query on
+	// each of the databases once the update is complete, failing when any of them
+	// is not reachable. The result is recorded in the decision log
+	CheckDatabasesHealth bool
+	// DecisionLogPath when set is the path of a file to which the decisions
+	// made during the update (target database, migrations and seeds moved or
+	// skipped, optional steps run) are written as JSON, even when the update fails
+	DecisionLogPath string
+	// Timings when set will be filled with the duration of each phase of the
+	// update, durations are also logged at debug level
+	Timings *[]PhaseTiming
+	// Tracer when set records each phase of the update as a span, with
+	// attributes describing the update. Nothing is recorded when it is not set
+	Tracer Tracer
 }
 
 // UpdateProjectV3 will help a project directory move from a single
 // The project is expected to be in Config V2
-func UpdateProjectV3(opts UpgradeToMuUpgradeProjectToMultipleSourcesOpts) error {
+func UpdateProjectV3(opts UpgradeToMuUpgradeProjectToMultipleSourcesOpts) (err error) {
 	/* New flow
 		Config V2 -> Config V3
 		- Warn user about creating a backup
@@ -48,118 +212,762 @@ func UpdateProjectV3(opts UpgradeToMuUpgradeProjectToMultipleSourcesOpts) error
 		- Update config file and version
 	*/
 
+	// make sure the terminal is not left garbled by the spinner on a panic
+	defer opts.EC.StopSpinnerOnPanic()
+	if err := validateOutputFormat(opts.OutputFormat); err != nil {
+		return err
+	}
+	if opts.OutputFormat == OutputFormatJSON {
+		defer silenceProgress(opts.EC, opts.Logger, opts.EC.Logger)()
+	}
+
 	// pre checks
-	if opts.EC.Config.Version != cli.V2 {
-		return fmt.Errorf("project should be using config V2 to be able to update to V3")
+	report := RunPreflightChecks(opts)
+	if opts.CheckOnly {
+		if err := report.Write(os.Stdout); err != nil {
+			return errors.Wrap(err, "writing preflight report")
+		}
+		if !report.Passed {
+			return fmt.Errorf("preflight checks failed")
+		}
+		return nil
+	}
+	if err := report.Err(); err != nil {
+		return err
 	}
-	if !opts.EC.HasMetadataV3 {
-		return fmt.Errorf("unsupported server version %v, config V3 is supported only on server with metadata version >= 3", opts.EC.Version.Server)
+	decisions := newDecisionLog(len(opts.DecisionLogPath) > 0)
+	// the log is written once the staged copy of the project directory was
+	// swapped in or discarded, so it is written using the filesystem as it was
+	// before opts.Fs is redirected to the staged copy
+	decisionLogFs := opts.Fs
+	defer func() {
+		if err := decisions.write(decisionLogFs, opts.DecisionLogPath); err != nil {
+			opts.Logger.Warn(err)
+		}
+	}()
+	for _, warning := range report.Warnings {
+		opts.Logger.Warn(warning)
 	}
-	if r, err := opts.EC.APIClient.V1Metadata.GetInconsistentMetadata(); err != nil {
-		return fmt.Errorf("determing server metadata inconsistency: %w", err)
-	} else {
-		if !r.IsConsistent {
-			return fmt.Errorf("cannot continue: metadata is inconsistent on the server")
+	if !opts.Offline {
+		if capabilities, err := ServerCapabilities(opts.EC); err == nil {
+			opts.Logger.Debugf("server capabilities: %s", capabilities)
 		}
 	}
 
 	opts.Logger.Infof("The upgrade process will make some changes to your project directory, It is advised to create a backup project directory before continuing")
 	opts.Logger.Warn(`Config V3 is expected to be used with servers >=v2.0.0-alpha.1`)
-	opts.Logger.Warn(`During the update process CLI uses the server as the source of truth, so make sure your server is upto date`)
-	opts.Logger.Warn(`The update process replaces project metadata with metadata on the server`)
+	if !opts.Offline {
+		opts.Logger.Warn(`During the update process CLI uses the server as the source of truth, so make sure your server is upto date`)
+		opts.Logger.Warn(`The update process replaces project metadata with metadata on the server`)
+	}
 
-	response, err := util.GetYesNoPrompt("continue?")
-	if err != nil {
+	// catch malformed seed files before they are moved into the database directory
+	if err := validateSeedFiles(opts); err != nil {
 		return err
 	}
-	if response == "n" {
-		return nil
-	}
+
 	// move migration child directories
 	// get directory names to move
-	targetDatabase, err := util.GetInputPrompt("what database does the current migrations / seeds belong to?")
+	isMigration := isHasuraCLIGeneratedMigration
+	if opts.IncludeLegacyTimestamps {
+		isMigration = isMigrationWithLegacyTimestamp
+	}
+	migrationDirectoriesToMove, err := getMatchingFilesAndDirs(opts.Fs, opts.MigrationsAbsDirectoryPath, isMigration)
 	if err != nil {
-		return err
+		return errors.Wrap(err, "getting list of migrations to move")
 	}
-	opts.EC.Spinner.Start()
-	opts.EC.Spin("updating project... ")
-	// copy state
-	// if a default database is setup copy state from it
-	sources, err := metadatautil.GetSources(opts.EC.APIClient.V1Metadata.ExportMetadata)
+	decisions.record("migrations", "move", "directory name matches <timestamp>_<name>", migrationDirectoriesToMove...)
+	if skipped, err := skippedEntries(opts.Fs, opts.MigrationsAbsDirectoryPath, migrationDirectoriesToMove); err == nil && len(skipped) > 0 {
+		decisions.record("migrations", "skip", "name does not match <timestamp>_<name>", skipped...)
+		opts.Logger.Warnf("%d entries of %s do not match <timestamp>_<name>, they will not be moved and will remain in %s: %s", len(skipped), opts.MigrationsAbsDirectoryPath, opts.MigrationsAbsDirectoryPath, strings.Join(skipped, ", "))
+		for _, name := range skipped {
+			if ok, _ := isMigrationWithLegacyTimestamp(name); ok && !opts.IncludeLegacyTimestamps {
+				opts.Logger.Warn("some of them have a 10 digit timestamp used by older CLI versions, use --include-legacy-timestamps to move them")
+				break
+			}
+		}
+	}
+	// for projects with a lot of migrations the name of the target database
+	// has to be typed to confirm, instead of a yes / no confirmation
+	requireTypedConfirmation := opts.ConfirmationThreshold > 0 && len(migrationDirectoriesToMove) > opts.ConfirmationThreshold
+	if !requireTypedConfirmation && !opts.DryRun && !opts.NonInteractive {
+		response, err := util.GetYesNoPrompt("continue?")
+		if err != nil {
+			return err
+		}
+		if response == "n" {
+			return nil
+		}
+	}
+	sources := report.Sources
+	targetDatabase, err := resolveTargetDatabase(opts.EC, sources, report.SourceKinds, opts.TargetDatabase, opts.NonInteractive)
 	if err != nil {
 		return err
 	}
-	if len(sources) >= 1 {
-		if err := copyState(opts.EC, targetDatabase); err != nil {
+	if err := ValidateSourceDirectoryName(targetDatabase); err != nil {
+		return err
+	}
+	// migrations and seeds not assigned to another database by
+	// the database mapping are moved to the target database
+	var mappingRules []DatabaseMappingRule
+	if len(opts.DatabaseMappingPath) > 0 {
+		mappingRules, err = readDatabaseMapping(opts.Fs, opts.DatabaseMappingPath)
+		if err != nil {
 			return err
 		}
 	}
-
-	// move migration child directories
-	// get directory names to move
-	migrationDirectoriesToMove, err := getMigrationDirectoryNames(opts.Fs, opts.MigrationsAbsDirectoryPath)
+	prefixRules, err := ParseDatabasePrefixes(opts.DatabasePrefixes)
 	if err != nil {
-		return errors.Wrap(err, "getting list of migrations to move")
+		return err
+	}
+	mappingRules = append(mappingRules, prefixRules...)
+	routes, err := newDatabaseRoutes(mappingRules, targetDatabase, sources)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(opts.TargetDatabase) > 0:
+		decisions.record("target_database", targetDatabase, "set using --database-name", sources...)
+	case opts.NonInteractive:
+		decisions.record("target_database", targetDatabase, "only database found, update is non-interactive")
+	case len(sources) == 0:
+		decisions.record("target_database", targetDatabase, "typed by the user, no databases were found")
+	default:
+		decisions.record("target_database", targetDatabase, "chosen by the user from the databases found", sources...)
+	}
+	if len(opts.EmitAPICalls) > 0 {
+		calls, err := planServerAPICalls(opts.EC, targetDatabase, opts.Label, opts.SettingsAllowlist)
+		if err != nil {
+			return errors.Wrap(err, "building server API calls")
+		}
+		if err := writeAPICalls(os.Stdout, calls, opts.EmitAPICalls); err != nil {
+			return errors.Wrap(err, "writing server API calls")
+		}
+		opts.Logger.Info("project was not updated, once the calls are run the update can be completed by running update-project-v3 again, which skips copying state")
+		return nil
+	}
+	if opts.DryRun {
+		return dryRunUpdate(opts, sources, routes, migrationDirectoriesToMove, decisions)
+	}
+	if requireTypedConfirmation && !opts.NonInteractive {
+		opts.Logger.Warnf("%d migrations will be moved to database %s", len(migrationDirectoriesToMove), targetDatabase)
+		input, err := util.GetInputPrompt(fmt.Sprintf("type the name of the database (%s) to continue", targetDatabase))
+		if err != nil {
+			return err
+		}
+		if input != targetDatabase {
+			return fmt.Errorf("confirmation %q does not match database name %s, aborting", input, targetDatabase)
+		}
+	}
+	summary := UpdateSummary{
+		TargetDatabase:   targetDatabase,
+		OldConfigVersion: int(opts.EC.Config.Version),
+		NewConfigVersion: int(cli.V3),
+	}
+	if opts.OutputFormat == OutputFormatJSON {
+		defer func() {
+			if err != nil {
+				return
+			}
+			output := opts.Output
+			if output == nil {
+				output = os.Stdout
+			}
+			err = writeUpdateSummary(output, summary)
+		}()
+	}
+	var journal *updateJournal
+	var completed bool
+	if opts.Staged {
+		// the update is made on a copy of the project directory, which
+		// replaces the project directory only once the update is complete
+		// stage is assigned rather than declared here, so that the deferred
+		// function sees the errors returned by the update in err
+		var stage *stagedProject
+		stage, err = stageProject(opts.Fs, opts.ProjectDirectory, time.Now())
+		if err != nil {
+			return errors.Wrap(err, "staging project directory")
+		}
+		opts.Logger.Debugf("project directory staged in %s using a %s", stage.directory, stage.method)
+		opts.Fs = stage.projectFs()
+		defer func() {
+			if err != nil && !completed {
+				opts.EC.Spinner.Stop()
+			}
+			err = stage.finish(err, completed, opts.Logger)
+		}()
+	} else {
+		// the parts of the project directory which are changed are backed up
+		// before any change is made, so that they are restored from the backup
+		// when the update fails before it is complete
+		journal = newUpdateJournal(opts.Fs, opts.ProjectDirectory, time.Now())
+		defer func() {
+			if err == nil || completed {
+				removeErr := journal.remove(opts.KeepBackup)
+				if removeErr != nil {
+					opts.Logger.Warnf("removing backup of the project directory: %v", removeErr)
+				} else if opts.KeepBackup {
+					opts.Logger.Infof("backup of the project directory was kept in %s", journal.BackupDirectory)
+					summary.BackupDirectory = journal.BackupDirectory
+				}
+				return
+			}
+			opts.EC.Spinner.Stop()
+			if opts.NoRollback {
+				opts.Logger.Warn("project directory was left as it is, run 'hasura scripts update-project-v3 --rollback' to restore it")
+				return
+			}
+			if restoreErr := restoreFromBackup(journal); restoreErr != nil {
+				opts.Logger.Warnf("restoring the project directory from %s failed: %v, run 'hasura scripts update-project-v3 --rollback' to try again", journal.BackupDirectory, restoreErr)
+				return
+			}
+			err = fmt.Errorf("update failed, the project directory was restored from backup: %w", err)
+		}()
+		for _, path := range []string{opts.MigrationsAbsDirectoryPath, opts.SeedsAbsDirectoryPath, opts.EC.ConfigFile, opts.EC.MetadataDir} {
+			if err := journal.changed(path); err != nil {
+				return errors.Wrap(err, "backing up project directory")
+			}
+		}
+	}
+	timer := newPhaseTimer(opts.Logger, opts.Timeout)
+	timer.tracer = opts.Tracer
+	timer.setAttribute("target_database", targetDatabase)
+	timer.setAttribute("migrations_to_move", len(migrationDirectoriesToMove))
+	timer.setAttribute("offline", opts.Offline)
+	defer func() {
+		if opts.Timings != nil {
+			*opts.Timings = timer.timings
+		}
+	}()
+	if len(opts.MetadataSnapshotPath) > 0 && !opts.Offline {
+		if err := SnapshotServerMetadata(opts.EC, opts.Fs, opts.MetadataSnapshotPath); err != nil {
+			return err
+		}
+		opts.Logger.Infof("metadata on the server was saved to %s, it can be restored using --restore-metadata", opts.MetadataSnapshotPath)
 	}
+	opts.EC.Spinner.Start()
+	opts.EC.Spin("updating project... ")
+	// copy state
+	// if a default database is setup copy state from it
+	if len(sources) >= 1 && !opts.Offline {
+		stateStore := opts.StateStore
+		if len(stateStore) == 0 {
+			stateStore = statestore.StateStoreCatalog
+		}
+		// state copied by an earlier run of the update (possibly by an older
+		// CLI version) is not copied again, so that state recorded since is kept
+		var copied bool
+		var method string
+		if stateStore == statestore.StateStoreCatalog && !opts.ForceStateCopy {
+			copied, method, err = DetectPriorStateCopy(opts.EC, targetDatabase)
+			if err != nil {
+				return err
+			}
+		}
+		if opts.ForceStateCopy {
+			decisions.record("state_copy", "force", "--force-state-copy was set, state is copied even when it was already copied")
+		}
+		if copied {
+			opts.Logger.Infof("state was already copied to catalog state (detected using %s), skipping state copy", method)
+			decisions.record("state_copy", "skip", "state was already copied, detected using "+method)
+		} else {
+			catalogState := statestore.NewCLICatalogState(opts.EC.APIClient.V1Metadata)
+			var stateBefore *statestore.CLIState
+			if opts.ShowStateDiff && stateStore == statestore.StateStoreCatalog {
+				if stateBefore, err = catalogState.Get(); err != nil {
+					return errors.Wrap(err, "reading catalog state before the state copy")
+				}
+			}
+			// a copy interrupted after this is resumed when the update is run again
+			if stateStore == statestore.StateStoreCatalog {
+				if err := markStateCopyStarted(opts.EC); err != nil {
+					return err
+				}
+			}
+			// state is copied to each of the target databases, versions routed
+			// to another database are then removed from it. Databases
+			// of kinds on which migrations cannot be applied (eg: bigquery)
+			// cannot hold migrations state, their directories are still moved
+			var stateDatabases []string
+			for _, database := range routes.databases() {
+				if kind, ok := report.SourceKinds[database]; ok && !migrate.IsMigrationsSupported(kind) {
+					opts.Logger.Warnf("migrations are not supported on database %s of kind %s, its migrations state is not copied", database, kind)
+					decisions.record("state_copy", "skip", "migrations are not supported on databases of kind "+string(kind), database)
+					continue
+				}
+				stateDatabases = append(stateDatabases, database)
+			}
+			for _, database := range stateDatabases {
+				if err := copyStateToStore(opts.EC, stateStore, database, opts.SettingsAllowlist); err != nil {
+					return err
+				}
+			}
+			if len(routes.rules) > 0 {
+				store, err := statestore.NewMigrationsStateStore(stateStore, stateStoreOptions(opts.EC))
+				if err != nil {
+					return err
+				}
+				if err := pruneRoutedVersions(store, routes, stateDatabases, migrationDirectoriesToMove); err != nil {
+					return errors.Wrap(err, "removing state of migrations moved to other databases")
+				}
+			}
+			if stateStore == statestore.StateStoreCatalog {
+				if err := markStateCopyCompleted(opts.EC, opts.Label); err != nil {
+					return err
+				}
+			}
+			decisions.record("state_copy", "copy", "copied to state store "+stateStore, stateDatabases...)
+			if opts.ShowStateDiff && stateStore == statestore.StateStoreCatalog {
+				stateAfter, err := catalogState.Get()
+				if err != nil {
+					return errors.Wrap(err, "reading catalog state after the state copy")
+				}
+				summary.StateChanges = statestore.DiffCLIState(stateBefore, stateAfter)
+				opts.Logger.Infof("the state copy made %d change(s) to catalog state", len(summary.StateChanges))
+				for _, change := range summary.StateChanges {
+					opts.Logger.Info(change)
+				}
+			} else if opts.ShowStateDiff {
+				opts.Logger.Warnf("changes to state can only be shown for catalog state, state was copied to %s", stateStore)
+			}
+		}
+	} else if opts.Offline {
+		decisions.record("state_copy", "skip", "update is offline")
+	} else {
+		decisions.record("state_copy", "skip", "no databases were found")
+	}
+	if err := timer.done(PhaseStateCopy); err != nil {
+		return err
+	}
+
 	// move seed child directories
 	// get directory names to move
 	seedFilesToMove, err := getSeedFiles(opts.Fs, opts.SeedsAbsDirectoryPath)
 	if err != nil {
 		return errors.Wrap(err, "getting list of seed files to move")
 	}
-
-	// create a new directory for TargetDatabase
-	targetMigrationsDirectoryName := filepath.Join(opts.MigrationsAbsDirectoryPath, targetDatabase)
-	if err = opts.Fs.Mkdir(targetMigrationsDirectoryName, 0755); err != nil {
-		errors.Wrap(err, "creating target migrations directory")
+	decisions.record("seeds", "move", "seed files are moved", seedFilesToMove...)
+	if skipped, err := skippedEntries(opts.Fs, opts.SeedsAbsDirectoryPath, seedFilesToMove); err == nil && len(skipped) > 0 {
+		decisions.record("seeds", "skip", "directories are not seed files", skipped...)
 	}
 
-	// create a new directory for TargetDatabase
-	targetSeedsDirectoryName := filepath.Join(opts.SeedsAbsDirectoryPath, targetDatabase)
-	if err = opts.Fs.Mkdir(targetSeedsDirectoryName, 0755); err != nil {
-		errors.Wrap(err, "creating target seeds directory")
+	// create a new directory for each of the target databases
+	for _, database := range routes.databases() {
+		for _, parent := range []string{opts.MigrationsAbsDirectoryPath, opts.SeedsAbsDirectoryPath} {
+			if err := opts.Fs.MkdirAll(filepath.Join(parent, database), 0755); err != nil {
+				return errors.Wrap(err, "creating target database directory")
+			}
+		}
+	}
+	migrationGroups := groupByDatabase(migrationDirectoriesToMove, routes.migrationDatabase)
+	seedGroups := groupByDatabase(seedFilesToMove, routes.seedDatabase)
+	for _, database := range routes.databases()[1:] {
+		if len(migrationGroups[database]) > 0 {
+			decisions.record("migrations", "route", "name matches the database mapping of "+database, migrationGroups[database]...)
+		}
+		if len(seedGroups[database]) > 0 {
+			decisions.record("seeds", "route", "name matches the database mapping of "+database, seedGroups[database]...)
+		}
+	}
+	// seed files conflicting with the ones already in the target
+	// directory are reported before anything is moved
+	seedDestinationNames := map[string][]string{}
+	for _, database := range routes.databases() {
+		targetSeedsDirectoryName := filepath.Join(opts.SeedsAbsDirectoryPath, database)
+		files := seedGroups[database]
+		names, err := seedDestinations(opts.Fs, files, targetSeedsDirectoryName, opts.SeedConflictStrategy)
+		if err != nil {
+			return err
+		}
+		for idx, name := range files {
+			if names[idx] != name {
+				opts.Logger.Warnf("seed file %s conflicts with a file in %s, moving it as %s", name, targetSeedsDirectoryName, names[idx])
+				decisions.record("seeds", "rename", "name conflicts with a file in the target directory", name, names[idx])
+			}
+		}
+		seedDestinationNames[database] = names
 	}
 
+	// migrations already in the target directory are either overwritten,
+	// skipped or abort the update, asking the user by default
+	conflicts, err := newMigrationConflictResolver(opts.Fs, opts.MigrationConflictStrategy, func(message string, options []string) (string, error) {
+		if opts.NonInteractive {
+			return "", fmt.Errorf("%s, use --migration-conflicts to choose what to do without being asked", message)
+		}
+		opts.EC.Spinner.Stop()
+		defer opts.EC.Spinner.Start()
+		return util.GetSelectPrompt(message, options)
+	})
+	if err != nil {
+		return err
+	}
 	// move migration directories to target database directory
-	if err := copyMigrations(opts.Fs, migrationDirectoriesToMove, opts.MigrationsAbsDirectoryPath, targetMigrationsDirectoryName); err != nil {
+	// migrations are copied as they are listed, instead of
+	// waiting for the whole migrations directory to be read
+	migrationProgress := newMoveProgress(opts.EC, "migration", len(migrationDirectoriesToMove))
+	copyToTarget := func(name string) error {
+		migrationProgress()
+		targetMigrationsDirectoryName := filepath.Join(opts.MigrationsAbsDirectoryPath, routes.migrationDatabase(name))
+		action, err := conflicts.resolve(name, targetMigrationsDirectoryName)
+		if err != nil {
+			return err
+		}
+		if action == MigrationConflictSkip {
+			opts.Logger.Warnf("migration %s already exists in %s, it was not moved", name, targetMigrationsDirectoryName)
+			return nil
+		}
+		// the migration is overwritten, it was backed up along with the migrations directory
+		if err := opts.Fs.RemoveAll(filepath.Join(targetMigrationsDirectoryName, name)); err != nil {
+			return err
+		}
+		return copyMigration(opts.Fs, name, opts.MigrationsAbsDirectoryPath, targetMigrationsDirectoryName, opts.Logger)
+	}
+	if err := walkMigrationDirectories(opts.Fs, opts.MigrationsAbsDirectoryPath, isMigration, copyToTarget); err != nil {
 		return errors.Wrap(err, "moving migrations to target database directory")
 	}
-	// move seed directories to target database directory
-	if err := copyFiles(opts.Fs, seedFilesToMove, opts.SeedsAbsDirectoryPath, targetSeedsDirectoryName); err != nil {
-		return errors.Wrap(err, "moving seeds to target database directory")
+	seedProgress := newMoveProgress(opts.EC, "seed file", len(seedFilesToMove))
+	for _, database := range routes.databases() {
+		targetMigrationsDirectoryName := filepath.Join(opts.MigrationsAbsDirectoryPath, database)
+		targetSeedsDirectoryName := filepath.Join(opts.SeedsAbsDirectoryPath, database)
+		// move seed directories to target database directory
+		if err := copySeedFiles(opts.Fs, seedGroups[database], seedDestinationNames[database], opts.SeedsAbsDirectoryPath, targetSeedsDirectoryName, seedProgress, opts.Logger); err != nil {
+			return errors.Wrap(err, "moving seeds to target database directory")
+		}
+		if opts.NormalizeLineEndings {
+			for _, dir := range []string{targetMigrationsDirectoryName, targetSeedsDirectoryName} {
+				if err := normalizeLineEndings(opts.Fs, dir, opts.Logger); err != nil {
+					return err
+				}
+			}
+		}
+		// migrations without a down (or up) migration are moved as they are,
+		// but are reported since rolling them back will fail later
+		unpaired, err := UnpairedMigrations(opts.Fs, targetMigrationsDirectoryName)
+		if err != nil {
+			return errors.Wrap(err, "validating moved migrations")
+		}
+		for _, m := range unpaired {
+			opts.Logger.Warnf("migration %s", m)
+			decisions.record("migrations", "warn", "migration has no "+m.Missing+" migration", m.Directory)
+		}
+		// record checksums of the seeds, so that changes to them can be detected
+		if err := seed.WriteLockFile(opts.Fs, targetSeedsDirectoryName); err != nil {
+			return err
+		}
+	}
+	if opts.NormalizeLineEndings {
+		decisions.record("normalize_line_endings", "run", "--normalize-line-endings was set")
+	}
+
+	timer.setAttribute("migrations_moved", len(excludeNames(migrationDirectoriesToMove, conflicts.skipped)))
+	timer.setAttribute("seeds_moved", len(seedFilesToMove))
+	if err := timer.done(PhaseMoves); err != nil {
+		return err
+	}
+
+	if len(sources) >= 1 && opts.CompactMigrationState && !opts.Offline {
+		opts.EC.Spinner.Stop()
+		// versions are compacted in the state store the state was copied to
+		stateStore := opts.StateStore
+		if len(stateStore) == 0 {
+			stateStore = statestore.StateStoreCatalog
+		}
+		store, err := statestore.NewMigrationsStateStore(stateStore, stateStoreOptions(opts.EC))
+		if err != nil {
+			return err
+		}
+		for _, database := range routes.databases() {
+			if err := compactMigrationState(opts.EC, opts.Fs, store, database, filepath.Join(opts.MigrationsAbsDirectoryPath, database), opts.NonInteractive); err != nil {
+				return errors.Wrap(err, "compacting migration state")
+			}
+		}
+		decisions.record("compact_migration_state", "run", "--compact-migration-state was set")
+		opts.EC.Spinner.Start()
+		timer.start()
 	}
 
 	// write new config file
+	// only the changed fields are updated so that comments and formatting are kept
+	// the target database is also recorded as the default database to be used by
+	// migrate and seed commands
 	newConfig := *opts.EC.Config
 	newConfig.Version = cli.V3
-	if err := opts.EC.WriteConfig(&newConfig); err != nil {
+	newConfig.DefaultSource = targetDatabase
+	if err := opts.EC.UpdateConfigFieldsFs(opts.Fs, configV3Fields(targetDatabase)); err != nil {
 		return err
 	}
 	opts.EC.Config = &newConfig
+	if err := timer.done(PhaseConfigWrite); err != nil {
+		return err
+	}
 
-	// delete original migrations
-	if err := removeDirectories(opts.Fs, opts.MigrationsAbsDirectoryPath, migrationDirectoriesToMove); err != nil {
+	// delete original migrations, except the ones which were skipped
+	if len(conflicts.skipped) > 0 {
+		decisions.record("migrations", "skip", "migration already exists in the target directory", conflicts.skipped...)
+	}
+	if err := removeDirectories(opts.Fs, opts.MigrationsAbsDirectoryPath, excludeNames(migrationDirectoriesToMove, conflicts.skipped)); err != nil {
 		return errors.Wrap(err, "removing up original migrations")
 	}
 	// delete original seeds
 	if err := removeDirectories(opts.Fs, opts.SeedsAbsDirectoryPath, seedFilesToMove); err != nil {
 		return errors.Wrap(err, "removing up original migrations")
 	}
+	// the root migrations directory is expected to only contain database
+	// directories, migrations left in it were not moved
+	unmoved, stragglers, err := MigrationsRootStragglers(opts.Fs, opts.MigrationsAbsDirectoryPath, append(append([]string{}, sources...), routes.databases()...))
+	if err != nil {
+		return errors.Wrap(err, "verifying root migrations directory")
+	}
+	if unmoved = excludeNames(unmoved, conflicts.skipped); len(unmoved) > 0 {
+		return fmt.Errorf("migrations were left in %s after the move: %s", opts.MigrationsAbsDirectoryPath, strings.Join(unmoved, ", "))
+	}
+	if len(stragglers) > 0 {
+		opts.Logger.Warnf("%s contains entries which are not database directories, they were left as they are: %s", opts.MigrationsAbsDirectoryPath, strings.Join(stragglers, ", "))
+		decisions.record("migrations", "warn", "left in the root migrations directory, not a database directory", stragglers...)
+	}
+	if opts.CheckGlobalVersions {
+		reused, err := VersionsReusedAcrossDatabases(opts.Fs, opts.MigrationsAbsDirectoryPath, append(append([]string{}, sources...), routes.databases()...))
+		if err != nil {
+			return errors.Wrap(err, "checking migration versions across databases")
+		}
+		versions := make([]uint64, 0, len(reused))
+		for version := range reused {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+		for _, version := range versions {
+			opts.Logger.Warnf("migration version %d is used by more than one database: %s", version, strings.Join(reused[version], ", "))
+			decisions.record("migrations", "warn", "version is used by more than one database", append([]string{strconv.FormatUint(version, 10)}, reused[version]...)...)
+		}
+	}
+	if opts.CheckSeedSchemas {
+		if opts.Offline {
+			opts.Logger.Warn("schemas referenced by seed files cannot be checked when the update is offline")
+		} else {
+			checkSeedSchemas(opts, routes.databases(), seedGroups, decisions)
+		}
+	}
+	summary.MigrationsMoved = len(excludeNames(migrationDirectoriesToMove, conflicts.skipped))
+	summary.SeedsMoved = len(seedFilesToMove)
+	// the decisions are kept, so that the database a file of the
+	// config v2 project was moved to can be found later
+	upgradeInfo := UpgradeInfo{TargetDatabase: targetDatabase, Migrations: map[string]string{}, Seeds: map[string]string{}}
+	for _, name := range excludeNames(migrationDirectoriesToMove, conflicts.skipped) {
+		upgradeInfo.Migrations[name] = routes.migrationDatabase(name)
+	}
+	for database, files := range seedGroups {
+		for idx, file := range files {
+			upgradeInfo.Seeds[file] = path.Join(database, seedDestinationNames[database][idx])
+		}
+	}
+	if err := journal.changed(filepath.Join(opts.ProjectDirectory, UpgradeInfoFile)); err != nil {
+		return err
+	}
+	if err := writeUpgradeInfo(opts.Fs, opts.ProjectDirectory, upgradeInfo); err != nil {
+		return errors.Wrapf(err, "writing %s", UpgradeInfoFile)
+	}
+	if opts.Offline {
+		// metadata files are left as they are, they are replaced
+		// by metadata on the server during reconciliation
+		if err := journal.changed(filepath.Join(opts.ProjectDirectory, OfflineUpdateMarkerFile)); err != nil {
+			return err
+		}
+		if err := writeOfflineUpdateMarker(opts.Fs, opts.ProjectDirectory, targetDatabase); err != nil {
+			return errors.Wrap(err, "marking project as pending reconciliation")
+		}
+		_ = timer.done(PhaseCleanup)
+		opts.EC.Spinner.Stop()
+		summary.Offline = true
+		opts.Logger.Warn("project was updated offline, state was not copied and metadata was not exported")
+		opts.Logger.Warn("once the server is reachable, run 'hasura scripts update-project-v3 --reconcile' to complete the update")
+		return nil
+	}
 	// remove functions.yaml and tables.yaml files
 	metadataFiles := []string{"functions.yaml", "tables.yaml"}
 	if err := removeDirectories(opts.Fs, opts.EC.MetadataDir, metadataFiles); err != nil {
 		return err
 	}
+	if err := timer.done(PhaseCleanup); err != nil {
+		return err
+	}
+	// the export is staged, so that when it fails it can be
+	// continued using hasura metadata export --resume
 	var files map[string][]byte
-	mdHandler := metadataobject.NewHandlerFromEC(opts.EC)
-	files, err = mdHandler.ExportMetadata()
-	if err != nil {
+	stagingDir := filepath.Join(opts.ProjectDirectory, metadataobject.ExportStagingDirectory)
+	if err := journal.changed(stagingDir); err != nil {
 		return err
 	}
+	mdHandler := opts.MetadataHandler
+	if mdHandler == nil {
+		handler := metadataobject.NewHandlerFromEC(opts.EC)
+		handler.SetFs(opts.Fs)
+		handler.SetExportConcurrency(opts.ExportConcurrency)
+		mdHandler = handler
+	}
+	files, err = mdHandler.ExportMetadataResumable(opts.Fs, stagingDir, false)
+	if err != nil {
+		if opts.NoRollback {
+			return errors.Wrap(err, "exporting metadata, use 'hasura metadata export --resume' to continue the export")
+		}
+		return errors.Wrap(err, "exporting metadata")
+	}
 	if err := mdHandler.WriteMetadata(files); err != nil {
 		return err
 	}
+	removed, err := mdHandler.VerifyMetadataWrite(opts.EC.MetadataDir, files)
+	for _, path := range removed {
+		opts.Logger.Debugf("removed temporary metadata file %s", path)
+	}
+	if err != nil {
+		return errors.Wrap(err, "verifying exported metadata")
+	}
+	if err := opts.Fs.RemoveAll(stagingDir); err != nil {
+		return err
+	}
+	if err := timer.done(PhaseExport); err != nil {
+		return err
+	}
+	// the update is complete, failures of the optional
+	// steps which follow are not rolled back
+	completed = true
+	if opts.ReloadMetadata {
+		opts.EC.Spin("reloading metadata... ")
+		if err := reloadMetadata(mdHandler, opts.Logger); err != nil {
+			opts.EC.Spinner.Stop()
+			return fmt.Errorf("project was updated, but %w", err)
+		}
+		decisions.record("reload_metadata", "run", "--reload-metadata was set")
+		_ = timer.done(PhaseReload)
+	}
+	if opts.SmokeTest {
+		opts.EC.Spin("running smoke test... ")
+		elapsed, err := runSmokeTest(opts.EC.APIClient.V1Graphql)
+		if err != nil {
+			decisions.record("smoke_test", "failed", err.Error())
+			opts.EC.Spinner.Stop()
+			return fmt.Errorf("project was updated, but smoke test failed: %w", err)
+		}
+		decisions.record("smoke_test", "passed", fmt.Sprintf("introspection query took %s", elapsed))
+		opts.Logger.Debugf("smoke test passed, introspection query took %s", elapsed)
+	}
+	if opts.CheckDatabasesHealth {
+		opts.EC.Spin("checking health of databases... ")
+		results, err := CheckAllSourcesHealthy(opts.EC)
+		if err != nil {
+			opts.EC.Spinner.Stop()
+			return fmt.Errorf("project was updated, but checking health of databases failed: %w", err)
+		}
+		var healthy, unhealthy []string
+		for name, err := range results {
+			if err != nil {
+				opts.Logger.Warn(err)
+				unhealthy = append(unhealthy, name)
+				continue
+			}
+			healthy = append(healthy, name)
+		}
+		sort.Strings(healthy)
+		sort.Strings(unhealthy)
+		if len(unhealthy) > 0 {
+			decisions.record("database_health", "failed", "the server could not run a query on the databases", unhealthy...)
+			opts.EC.Spinner.Stop()
+			return fmt.Errorf("project was updated, but databases %s are not reachable", strings.Join(unhealthy, ", "))
+		}
+		decisions.record("database_health", "passed", "the server could run a query on the databases", healthy...)
+	}
 	opts.EC.Spinner.Stop()
+	opts.Logger.Debugf("updating project took %s", timer.total())
+	return nil
+}
+
+// configV3Fields returns the fields of the config file which are
+// updated when moving the project to targetDatabase
+func configV3Fields(targetDatabase string) yaml.MapSlice {
+	return yaml.MapSlice{
+		{Key: "version", Value: cli.V3},
+		{Key: "default_source", Value: targetDatabase},
+	}
+}
+
+// resolveTargetDatabase returns targetDatabase when it is set, checking that it is
+// one of sources. Otherwise the user is asked for it, unless nonInteractive is set,
+// in which case the only database found is used and an error naming the candidate
+// databases is returned when there are several of them
+func resolveTargetDatabase(ec *cli.ExecutionContext, sources []string, kinds map[string]hasura.SourceKind, targetDatabase string, nonInteractive bool) (string, error) {
+	if len(targetDatabase) > 0 {
+		var found bool
+		for _, source := range sources {
+			found = found || source == targetDatabase
+		}
+		if len(sources) > 0 && !found {
+			return "", fmt.Errorf("database %s was not found on the server, databases found: %s", targetDatabase, strings.Join(sources, ", "))
+		}
+		return targetDatabase, nil
+	}
+	if !nonInteractive {
+		return getTargetDatabase(ec, sources, kinds)
+	}
+	switch len(sources) {
+	case 0:
+		return "", fmt.Errorf("no databases were found on the server, the database has to be set using --database-name")
+	case 1:
+		return sources[0], nil
+	default:
+		return "", fmt.Errorf("more than one database was found on the server (%s), the database has to be set using --database-name", strings.Join(sources, ", "))
+	}
+}
+
+// getTargetDatabase asks for the target database, sources are listed
+// as "name (kind)" when their kind is known
+func getTargetDatabase(ec *cli.ExecutionContext, sources []string, kinds map[string]hasura.SourceKind) (string, error) {
+	const message = "what database does the current migrations / seeds belong to?"
+	if len(sources) == 0 {
+		return util.GetInputPrompt(message)
+	}
+	options := make([]string, len(sources))
+	for idx, source := range sources {
+		options[idx] = sourceOption(source, kinds)
+	}
+	var selected string
+	var err error
+	if ec.IsTerminal {
+		selected, err = util.GetSelectPromptWithSearch(message, options)
+	} else {
+		selected, err = util.GetSelectPrompt(message, options)
+	}
+	if err != nil {
+		return "", err
+	}
+	for idx, option := range options {
+		if option == selected {
+			return sources[idx], nil
+		}
+	}
+	return selected, nil
+}
+
+func sourceOption(source string, kinds map[string]hasura.SourceKind) string {
+	if kind, ok := kinds[source]; ok && len(kind) > 0 {
+		return fmt.Sprintf("%s (%s)", source, kind)
+	}
+	return source
+}
+
+func validateSeedFiles(opts UpgradeToMuUpgradeProjectToMultipleSourcesOpts) error {
+	seedFiles, err := getSeedFiles(opts.Fs, opts.SeedsAbsDirectoryPath)
+	if err != nil {
+		return errors.Wrap(err, "getting list of seed files to validate")
+	}
+	var invalid int
+	for _, file := range seedFiles {
+		if err := seed.ValidateSeedFile(opts.Fs, filepath.Join(opts.SeedsAbsDirectoryPath, file)); err != nil {
+			opts.Logger.Warnf("seed file %s looks malformed: %v", file, err)
+			invalid++
+		}
+	}
+	if invalid > 0 && opts.StrictSeedValidation {
+		return fmt.Errorf("%d seed files failed validation", invalid)
+	}
 	return nil
 }
 
@@ -172,31 +980,58 @@ func removeDirectories(fs afero.Fs, parentDirectory string, dirNames []string) e
 	return nil
 }
 
-func copyMigrations(fs afero.Fs, dirs []string, parentDir, target string) error {
-	for _, dir := range dirs {
-		f, _ := fs.Stat(filepath.Join(parentDir, dir))
-		if f != nil {
-			if f.IsDir() {
-				err := util.CopyDirAfero(fs, filepath.Join(parentDir, dir), filepath.Join(target, dir))
-				if err != nil {
-					return errors.Wrapf(err, "moving %s to %s", dir, target)
-				}
-			} else {
-				err := util.CopyFileAfero(fs, filepath.Join(parentDir, dir), filepath.Join(target, dir))
-				if err != nil {
-					return errors.Wrapf(err, "moving %s to %s", dir, target)
-				}
-			}
+// excludeNames returns names without the ones in excluded
+func excludeNames(names, excluded []string) []string {
+	if len(excluded) == 0 {
+		return names
+	}
+	skip := make(map[string]bool, len(excluded))
+	for _, name := range excluded {
+		skip[name] = true
+	}
+	var kept []string
+	for _, name := range names {
+		if !skip[name] {
+			kept = append(kept, name)
 		}
+	}
+	return kept
+}
 
+// newMoveProgress returns a function to be called before each of total
+// migrations or seed files (kind) is moved, which shows the progress of the
+// move on the spinner, eg: "moving migration 42/5000... ", so that a long move
+// is not mistaken for a hung update. When the output is not a terminal,
+// progress is logged at debug level instead
+func newMoveProgress(ec *cli.ExecutionContext, kind string, total int) func() {
+	done := 0
+	return func() {
+		done++
+		message := fmt.Sprintf("moving %s %d/%d... ", kind, done, total)
+		if ec.IsTerminal {
+			ec.Spin(message)
+			return
+		}
+		ec.Logger.Debug(message)
 	}
-	return nil
 }
 
-func copyFiles(fs afero.Fs, files []string, parentDir, target string) error {
-	for _, dir := range files {
-		err := util.CopyFileAfero(fs, filepath.Join(parentDir, dir), filepath.Join(target, dir))
-		if err != nil {
+func copyMigration(fs afero.Fs, dir string, parentDir, target string, logger *logrus.Logger) error {
+	f, _ := fs.Stat(filepath.Join(parentDir, dir))
+	if f != nil {
+		if f.IsDir() {
+			err := util.CopyDirAfero(fs, filepath.Join(parentDir, dir), filepath.Join(target, dir))
+			if err != nil {
+				return errors.Wrapf(err, "moving %s to %s", dir, target)
+			}
+		} else {
+			err := util.CopyFileAfero(fs, filepath.Join(parentDir, dir), filepath.Join(target, dir))
+			if err != nil {
+				return errors.Wrapf(err, "moving %s to %s", dir, target)
+			}
+		}
+		// the original is removed once all migrations are moved
+		if err := verifyCopy(fs, filepath.Join(parentDir, dir), filepath.Join(target, dir), logger); err != nil {
 			return errors.Wrapf(err, "moving %s to %s", dir, target)
 		}
 	}
@@ -248,49 +1083,178 @@ func isHasuraCLIGeneratedMigration(dirPath string) (bool, error) {
 	return regexp.MatchString(regex, filepath.Base(dirPath))
 }
 
-func copyState(ec *cli.ExecutionContext, destdatabase string) error {
-	// copy migrations state
-	src := cli.GetMigrationsStateStore(ec)
-	if err := src.PrepareMigrationsStateStore(); err != nil {
+// isMigrationWithLegacyTimestamp also matches migrations having the
+// 10 digit timestamp (in seconds) used by older CLI versions
+func isMigrationWithLegacyTimestamp(dirPath string) (bool, error) {
+	const regex = `^([0-9]{10}|[0-9]{13})_(.*)$`
+	return regexp.MatchString(regex, filepath.Base(dirPath))
+}
+
+// copyStateToStore copies state from the state stores currently used by the project
+// to the state stores registered as dst in the statestore registry, copying only
+// the settings in settingsAllowlist when it is not empty
+func copyStateToStore(ec *cli.ExecutionContext, dst string, destdatabase string, settingsAllowlist []string) error {
+	if err := AssertStateCopySupported(ec, destdatabase); err != nil {
 		return err
 	}
-	dst := migrations.NewCatalogStateStore(statestore.NewCLICatalogState(ec.APIClient.V1Metadata))
-	if err := dst.PrepareMigrationsStateStore(); err != nil {
+	return copyStateBetweenStores(stateStoreOptions(ec), ec.Logger, projectStateStore(ec), dst, destdatabase, settingsAllowlist)
+}
+
+// projectStateStore returns the name of the state store used by the project
+func projectStateStore(ec *cli.ExecutionContext) string {
+	if ec.Config.Version <= cli.V2 {
+		return statestore.StateStoreHdbTable
+	}
+	return statestore.StateStoreCatalog
+}
+
+func stateStoreOptions(ec *cli.ExecutionContext) statestore.StateStoreOptions {
+	return statestore.StateStoreOptions{Client: ec.APIClient, HasMetadataV3: ec.HasMetadataV3, PGSourceOps: cli.GetPGSourceOps(ec)}
+}
+
+// copyStateBetweenStores copies migrations state and settings from the state
+// stores registered as src to the ones registered as dst in the statestore registry,
+// recording migrations state under database destdatabase. Settings which change are logged at debug level.
+// When settingsAllowlist is not empty only the settings named in it are copied
+func copyStateBetweenStores(storeOpts statestore.StateStoreOptions, logger *logrus.Logger, src, dst string, destdatabase string, settingsAllowlist []string) error {
+	// copy migrations state
+	srcMigrationsStore, err := statestore.NewMigrationsStateStore(src, storeOpts)
+	if err != nil {
 		return err
 	}
-	err := statestore.CopyMigrationState(src, dst, "", destdatabase)
+	dstMigrationsStore, err := statestore.NewMigrationsStateStore(dst, storeOpts)
 	if err != nil {
 		return err
 	}
+	for _, store := range []statestore.MigrationsStateStore{srcMigrationsStore, dstMigrationsStore} {
+		if err := store.PrepareMigrationsStateStore(); err != nil {
+			return err
+		}
+	}
+	if err := statestore.CopyMigrationState(srcMigrationsStore, dstMigrationsStore, "", destdatabase); err != nil {
+		return err
+	}
+	// writes can be lost without an error being reported, so state is read back
+	if err := statestore.VerifyMigrationState(srcMigrationsStore, dstMigrationsStore, "", destdatabase); err != nil {
+		return err
+	}
 	// copy settings state
-	srcSettingsStore := cli.GetSettingsStateStore(ec)
-	if err := srcSettingsStore.PrepareSettingsDriver(); err != nil {
+	srcSettingsStore, err := statestore.NewSettingsStateStore(src, storeOpts)
+	if err != nil {
 		return err
 	}
-	dstSettingsStore := settings.NewStateStoreCatalog(statestore.NewCLICatalogState(ec.APIClient.V1Metadata))
+	dstSettingsStore, err := statestore.NewSettingsStateStore(dst, storeOpts)
+	if err != nil {
+		return err
+	}
+	for _, store := range []statestore.SettingsStateStore{srcSettingsStore, dstSettingsStore} {
+		if err := store.PrepareSettingsDriver(); err != nil {
+			return err
+		}
+	}
+	if diff, err := statestore.DiffSettings(srcSettingsStore, dstSettingsStore); err == nil && logger != nil {
+		for name, values := range diff {
+			if !statestore.SettingAllowed(name, settingsAllowlist) {
+				continue
+			}
+			logger.Debugf("setting %s: %q in %s, %q in %s", name, values[1], src, values[0], dst)
+		}
+	}
+	if err := statestore.CopySettingsState(srcSettingsStore, dstSettingsStore, settingsAllowlist...); err != nil {
+		return err
+	}
+	return statestore.VerifySettingsState(srcSettingsStore, dstSettingsStore, settingsAllowlist...)
+}
+
+// CopyStateBetweenServers copies the migrations state of database source and
+// the settings from catalog state of the server srcClient talks to, to the catalog
+// state of the server dstClient talks to, recording migrations state under database dest
+func CopyStateBetweenServers(srcClient, dstClient *hasura.Client, source, dest string) error {
+	// copy migrations state
+	src := migrations.NewCatalogStateStore(statestore.NewCLICatalogState(srcClient.V1Metadata))
+	dst := migrations.NewCatalogStateStore(statestore.NewCLICatalogState(dstClient.V1Metadata))
+	if err := dst.PrepareMigrationsStateStore(); err != nil {
+		return err
+	}
+	if err := statestore.CopyMigrationState(src, dst, source, dest); err != nil {
+		return errors.Wrap(err, "copying migrations state")
+	}
+	// copy settings state
+	srcSettingsStore := settings.NewStateStoreCatalog(statestore.NewCLICatalogState(srcClient.V1Metadata))
+	dstSettingsStore := settings.NewStateStoreCatalog(statestore.NewCLICatalogState(dstClient.V1Metadata))
 	if err := dstSettingsStore.PrepareSettingsDriver(); err != nil {
 		return err
 	}
-	err = statestore.CopySettingsState(srcSettingsStore, dstSettingsStore)
+	if err := statestore.CopySettingsState(srcSettingsStore, dstSettingsStore); err != nil {
+		return errors.Wrap(err, "copying settings state")
+	}
+	return nil
+}
+
+func getMigrationVersion(dirName string) (uint64, error) {
+	return strconv.ParseUint(strings.SplitN(filepath.Base(dirName), "_", 2)[0], 10, 64)
+}
+
+// compactMigrationState prunes versions in store of database which are not
+// represented by a migration directory in migrationsDir, the user is asked
+// before they are removed unless nonInteractive is set
+func compactMigrationState(ec *cli.ExecutionContext, fs afero.Fs, store statestore.MigrationsStateStore, database, migrationsDir string, nonInteractive bool) error {
+	// migrations with a legacy timestamp are included, so that their versions are never removed
+	dirs, err := getMatchingFilesAndDirs(fs, migrationsDir, isMigrationWithLegacyTimestamp)
 	if err != nil {
 		return err
 	}
-	return nil
+	onDisk := map[uint64]bool{}
+	for _, dir := range dirs {
+		version, err := getMigrationVersion(dir)
+		if err != nil {
+			return err
+		}
+		onDisk[version] = true
+	}
+	versions, err := statestore.GetCompactableVersions(store, database, onDisk)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		ec.Logger.Debugf("no migration versions to compact for %s", database)
+		return nil
+	}
+	ec.Logger.Infof("%d migration versions recorded for %s have no corresponding migration files", len(versions), database)
+	if !nonInteractive {
+		response, err := util.GetYesNoPrompt("remove these versions from the state store?")
+		if err != nil {
+			return err
+		}
+		if response == "n" {
+			return nil
+		}
+	}
+	return statestore.CompactMigrationState(store, database, versions)
 }
 
 func CheckIfUpdateToConfigV3IsRequired(ec *cli.ExecutionContext) error {
 	// see if an update to config V3 is necessary
-	if ec.Config.Version <= cli.V1 && ec.HasMetadataV3 {
+	if !ec.Config.Version.After(cli.V1) && ec.HasMetadataV3 {
 		ec.Logger.Info("config v1 is deprecated from v1.4")
 		return errors.New("please upgrade your project to a newer version.\nuse " + color.New(color.FgCyan).SprintFunc()("hasura scripts update-project-v2") + " to upgrade your project to config v2")
 	}
-	if ec.Config.Version < cli.V3 && ec.HasMetadataV3 {
-		sources, err := metadatautil.GetSources(ec.APIClient.V1Metadata.ExportMetadata)
+	if ec.Config.Version.Before(cli.V3) && ec.HasMetadataV3 {
+		sources, err := metadatautil.GetSourcesAndKind(ec.APIClient.V1Metadata.ExportMetadata)
 		if err != nil {
 			return err
 		}
 		upgrade := func() error {
 			ec.Logger.Info("Looks like you are trying to use hasura with multiple databases, which requires some changes on your project directory\n")
+			if len(sources) > 0 {
+				kinds := make(map[string]hasura.SourceKind, len(sources))
+				names := make([]string, len(sources))
+				for idx, source := range sources {
+					kinds[source.Name] = source.Kind
+					names[idx] = sourceOption(source.Name, kinds)
+				}
+				ec.Logger.Infof("databases found on the server: %s", strings.Join(names, ", "))
+			}
 			ec.Logger.Info("please use " + color.New(color.FgCyan).SprintFunc()("hasura scripts update-project-v3") + " to make this change")
 			return errors.New("update to config V3")
 		}
@@ -301,7 +1265,7 @@ func CheckIfUpdateToConfigV3IsRequired(ec *cli.ExecutionContext) error {
 		// if 1 source is configured and it is not "default" then it's a custom database
 		// then also prompt an upgrade
 		if len(sources) == 1 {
-			if sources[0] != "default" {
+			if sources[0].Name != "default" {
 				return upgrade()
 			}
 		}
@@ -14,12 +14,17 @@ import (
 
 	"github.com/hasura/graphql-engine/cli"
 	"github.com/hasura/graphql-engine/cli/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/internal/metadataobject"
 	"github.com/hasura/graphql-engine/cli/internal/testutil"
 
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 )
 
+var _ MetadataHandler = (*metadataobject.Handler)(nil)
+
 func Test_checkIfDirectoryIsMigration(t *testing.T) {
 	type args struct {
 		dirPath string
@@ -67,6 +72,21 @@ func Test_checkIfDirectoryIsMigration(t *testing.T) {
 	}
 }
 
+func Test_isMigrationWithLegacyTimestamp(t *testing.T) {
+	for dirPath, want := range map[string]bool{
+		"1604855964903_test":   true,
+		"1604855964_test":      true,
+		"160485596490_test":    false,
+		"16048559649031_test":  false,
+		"testdata/1604855964_": true,
+		"create_table":         false,
+	} {
+		got, err := isMigrationWithLegacyTimestamp(dirPath)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, dirPath)
+	}
+}
+
 func Test_getMigrationDirectoryNames(t *testing.T) {
 	type args struct {
 		fs                afero.Fs
@@ -163,8 +183,10 @@ func Test_moveMigrationsToDatabaseDirectory(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := copyMigrations(tt.args.fs, tt.args.dirs, tt.args.parentMigrationsDirectory, tt.args.target); (err != nil) != tt.wantErr {
-				assert.NoError(t, err)
+			for _, dir := range tt.args.dirs {
+				if err := copyMigration(tt.args.fs, dir, tt.args.parentMigrationsDirectory, tt.args.target, nil); (err != nil) != tt.wantErr {
+					assert.NoError(t, err)
+				}
 			}
 			for _, want := range tt.want {
 				_, err := tt.args.fs.Stat(want)
@@ -174,6 +196,28 @@ func Test_moveMigrationsToDatabaseDirectory(t *testing.T) {
 	}
 }
 
+func Test_newMoveProgress(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	ec := &cli.ExecutionContext{Logger: logger}
+	fs := afero.NewMemMapFs()
+	for _, dir := range []string{"migrations/1", "migrations/2", "migrations/3"} {
+		if err := fs.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	progress := newMoveProgress(ec, "migration", 3)
+	for _, dir := range []string{"1", "2", "3"} {
+		progress()
+		assert.NoError(t, copyMigration(fs, dir, "migrations", "migrations/default", nil))
+	}
+	var messages []string
+	for _, entry := range hook.AllEntries() {
+		messages = append(messages, entry.Message)
+	}
+	assert.Equal(t, []string{"moving migration 1/3... ", "moving migration 2/3... ", "moving migration 3/3... "}, messages)
+}
+
 func Test_removeDirectories(t *testing.T) {
 	type args struct {
 		fs              afero.Fs
@@ -220,7 +264,7 @@ func Test_removeDirectories(t *testing.T) {
 	}
 }
 
-func Test_copyState(t *testing.T) {
+func Test_copyStateToStore(t *testing.T) {
 	port, teardown := testutil.StartHasura(t, testutil.HasuraVersion)
 	defer teardown()
 	type args struct {
@@ -240,6 +284,8 @@ func Test_copyState(t *testing.T) {
 						Config: &cli.Config{
 							Version: cli.V2,
 						},
+						// state can only be copied to a database on the server
+						HasMetadataV3: true,
 						APIClient: &hasura.Client{
 							V1Metadata: v1metadata.New(testutil.NewHttpcClient(t, port, nil), "v1/metadata"),
 							V1Query:    v1query.New(testutil.NewHttpcClient(t, port, nil), "v1/query"),
@@ -247,7 +293,7 @@ func Test_copyState(t *testing.T) {
 						},
 					}
 				}(),
-				"test",
+				"default",
 			},
 			false,
 		},
@@ -263,8 +309,8 @@ func Test_copyState(t *testing.T) {
 			dstMigrations := migrations.NewCatalogStateStore(statestore.NewCLICatalogState(tt.args.ec.APIClient.V1Metadata))
 			assert.NoError(t, srcSettings.UpdateSetting("test", "test"))
 			assert.NoError(t, srcMigrations.SetVersion("", 123, false))
-			if err := copyState(tt.args.ec, tt.args.destdatabase); (err != nil) != tt.wantErr {
-				t.Fatalf("copyState() error = %v, wantErr %v", err, tt.wantErr)
+			if err := copyStateToStore(tt.args.ec, statestore.StateStoreCatalog, tt.args.destdatabase, nil); (err != nil) != tt.wantErr {
+				t.Fatalf("copyStateToStore() error = %v, wantErr %v", err, tt.wantErr)
 			}
 			v, err := dstSettings.GetSetting("test")
 			assert.NoError(t, err)
@@ -275,3 +321,119 @@ func Test_copyState(t *testing.T) {
 		})
 	}
 }
+
+func TestCopyStateBetweenServers(t *testing.T) {
+	srcPort, srcTeardown := testutil.StartHasura(t, testutil.HasuraVersion)
+	defer srcTeardown()
+	dstPort, dstTeardown := testutil.StartHasura(t, testutil.HasuraVersion)
+	defer dstTeardown()
+	srcClient := &hasura.Client{
+		V1Metadata: v1metadata.New(testutil.NewHttpcClient(t, srcPort, nil), "v1/metadata"),
+	}
+	dstClient := &hasura.Client{
+		V1Metadata: v1metadata.New(testutil.NewHttpcClient(t, dstPort, nil), "v1/metadata"),
+	}
+
+	srcSettings := settings.NewStateStoreCatalog(statestore.NewCLICatalogState(srcClient.V1Metadata))
+	assert.NoError(t, srcSettings.PrepareSettingsDriver())
+	assert.NoError(t, srcSettings.UpdateSetting("test", "test"))
+	srcMigrations := migrations.NewCatalogStateStore(statestore.NewCLICatalogState(srcClient.V1Metadata))
+	assert.NoError(t, srcMigrations.SetVersion("default", 123, false))
+
+	assert.NoError(t, CopyStateBetweenServers(srcClient, dstClient, "default", "test"))
+
+	dstSettings := settings.NewStateStoreCatalog(statestore.NewCLICatalogState(dstClient.V1Metadata))
+	v, err := dstSettings.GetSetting("test")
+	assert.NoError(t, err)
+	assert.Equal(t, "test", v)
+	dstMigrations := migrations.NewCatalogStateStore(statestore.NewCLICatalogState(dstClient.V1Metadata))
+	m, err := dstMigrations.GetVersions("test")
+	assert.NoError(t, err)
+	assert.Equal(t, map[uint64]bool{123: false}, m)
+}
+
+type fakeSettingsStateStore map[string]string
+
+func (s fakeSettingsStateStore) GetSetting(name string) (string, error) {
+	return s[name], nil
+}
+
+func (s fakeSettingsStateStore) UpdateSetting(name string, value string) error {
+	s[name] = value
+	return nil
+}
+
+func (s fakeSettingsStateStore) GetAllSettings() (map[string]string, error) {
+	return s, nil
+}
+
+func (s fakeSettingsStateStore) PrepareSettingsDriver() error {
+	return nil
+}
+
+func Test_copyStateBetweenStores(t *testing.T) {
+	srcMigrations := fakeMigrationsStateStore{"": {1604855964903: false}}
+	dstMigrations := fakeMigrationsStateStore{}
+	srcSettings := fakeSettingsStateStore{"migration_mode": "true"}
+	dstSettings := fakeSettingsStateStore{}
+	for name, stores := range map[string]struct {
+		migrations fakeMigrationsStateStore
+		settings   fakeSettingsStateStore
+	}{
+		"fake_src": {srcMigrations, srcSettings},
+		"fake_dst": {dstMigrations, dstSettings},
+	} {
+		stores := stores
+		statestore.RegisterMigrationsStateStore(name, func(statestore.StateStoreOptions) statestore.MigrationsStateStore { return stores.migrations })
+		statestore.RegisterSettingsStateStore(name, func(statestore.StateStoreOptions) statestore.SettingsStateStore { return stores.settings })
+	}
+
+	assert.NoError(t, copyStateBetweenStores(statestore.StateStoreOptions{}, logrus.New(), "fake_src", "fake_dst", "default", nil))
+	assert.Equal(t, fakeMigrationsStateStore{"default": {1604855964903: false}}, dstMigrations)
+	assert.Equal(t, fakeSettingsStateStore{"migration_mode": "true"}, dstSettings)
+
+	srcSettings["other"] = "value"
+	assert.NoError(t, copyStateBetweenStores(statestore.StateStoreOptions{}, logrus.New(), "fake_src", "fake_dst", "default", []string{"other"}))
+	assert.Equal(t, fakeSettingsStateStore{"migration_mode": "true", "other": "value"}, dstSettings)
+	srcSettings["migration_mode"] = "false"
+	assert.NoError(t, copyStateBetweenStores(statestore.StateStoreOptions{}, logrus.New(), "fake_src", "fake_dst", "default", []string{"other"}))
+	assert.Equal(t, "true", dstSettings["migration_mode"])
+
+	assert.Error(t, copyStateBetweenStores(statestore.StateStoreOptions{}, logrus.New(), "unknown", "fake_dst", "default", nil))
+}
+
+func Test_resolveTargetDatabase(t *testing.T) {
+	tests := []struct {
+		name           string
+		sources        []string
+		targetDatabase string
+		want           string
+		wantErr        bool
+	}{
+		{"uses the database which was set", []string{"default", "orders"}, "orders", "orders", false},
+		{"fails for a database not on the server", []string{"default"}, "orders", "", true},
+		{"uses the database which was set when none were found", nil, "orders", "orders", false},
+		{"uses the only database", []string{"default"}, "", "default", false},
+		{"fails when there are several databases", []string{"default", "orders"}, "", "", true},
+		{"fails when no databases were found", nil, "", "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := resolveTargetDatabase(nil, tc.sources, nil, tc.targetDatabase, true)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func Test_sourceOption(t *testing.T) {
+	kinds := map[string]hasura.SourceKind{"default": hasura.SourceKindPG, "warehouse": "bigquery"}
+	assert.Equal(t, "default (postgres)", sourceOption("default", kinds))
+	assert.Equal(t, "warehouse (bigquery)", sourceOption("warehouse", kinds))
+	assert.Equal(t, "orders", sourceOption("orders", kinds))
+	assert.Equal(t, "orders", sourceOption("orders", nil))
+}
@@ -0,0 +1,89 @@
+package scripts
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v2"
+)
+
+// UpgradeInfoFile is written to the project directory by UpdateProjectV3,
+// recording the database the project was moved to and where each migration
+// and seed file was moved, so that other commands and tools can find the
+// database a file of the config v2 project belongs to
+const UpgradeInfoFile = ".hasura-v3-upgrade.yaml"
+
+// UpgradeInfo is the content of UpgradeInfoFile
+type UpgradeInfo struct {
+	TargetDatabase string `yaml:"target_database"`
+	// Migrations are the databases migration directories were moved to,
+	// keyed by the name of the directory
+	Migrations map[string]string `yaml:"migrations,omitempty"`
+	// Seeds are the paths seed files were moved to relative to the seeds
+	// directory (eg: default/users.sql), keyed by the name of the file
+	Seeds map[string]string `yaml:"seeds,omitempty"`
+}
+
+func writeUpgradeInfo(fs afero.Fs, projectDirectory string, info UpgradeInfo) error {
+	b, err := yaml.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return afero.WriteFile(fs, filepath.Join(projectDirectory, UpgradeInfoFile), b, 0644)
+}
+
+// ReadUpgradeInfo reads UpgradeInfoFile from the project directory, it
+// returns nil when the project was not updated using UpdateProjectV3
+func ReadUpgradeInfo(fs afero.Fs, projectDirectory string) (*UpgradeInfo, error) {
+	b, err := afero.ReadFile(fs, filepath.Join(projectDirectory, UpgradeInfoFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var info UpgradeInfo
+	if err := yaml.Unmarshal(b, &info); err != nil {
+		return nil, errors.Wrapf(err, "reading %s", UpgradeInfoFile)
+	}
+	return &info, nil
+}
+
+// ResolveSeedFiles returns the names in the seeds directory of database of
+// seed files named as they were before the update, other names are returned
+// as they are. Names of files which exist in the seeds directory of database
+// are not remapped, unless the update moved a file of the same name to another
+// file, in which case the name is ambiguous. It fails for seed files which were
+// moved to another database
+func (i *UpgradeInfo) ResolveSeedFiles(fs afero.Fs, seedsDirectory string, names []string, database string) ([]string, error) {
+	resolved := make([]string, 0, len(names))
+	for _, name := range names {
+		moved, ok := i.Seeds[name]
+		if !ok {
+			resolved = append(resolved, name)
+			continue
+		}
+		exists, err := afero.Exists(fs, filepath.Join(seedsDirectory, database, name))
+		if err != nil {
+			return nil, err
+		}
+		movedDatabase, movedName := path.Split(moved)
+		movedDatabase = path.Clean(movedDatabase)
+		if exists {
+			if movedDatabase == database && movedName == name {
+				resolved = append(resolved, name)
+				continue
+			}
+			return nil, fmt.Errorf("seed file %s is ambiguous: it exists in the seeds of database %s and seed file %s was moved to %s when the project was updated to config v3", name, database, name, moved)
+		}
+		if movedDatabase != database {
+			return nil, fmt.Errorf("seed file %s was moved to database %s when the project was updated to config v3", name, movedDatabase)
+		}
+		resolved = append(resolved, movedName)
+	}
+	return resolved, nil
+}
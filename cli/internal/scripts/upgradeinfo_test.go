@@ -0,0 +1,41 @@
+package scripts
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpgradeInfo(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	info, err := ReadUpgradeInfo(fs, "project")
+	assert.NoError(t, err)
+	assert.Nil(t, info)
+
+	assert.NoError(t, writeUpgradeInfo(fs, "project", UpgradeInfo{
+		TargetDatabase: "default",
+		Migrations:     map[string]string{"1604855964903_test": "default", "1604855964904_orders": "orders"},
+		Seeds:          map[string]string{"users.sql": "default/users_1.sql", "orders.sql": "orders/orders.sql"},
+	}))
+	info, err = ReadUpgradeInfo(fs, "project")
+	assert.NoError(t, err)
+	assert.Equal(t, "default", info.TargetDatabase)
+	assert.Equal(t, "orders", info.Migrations["1604855964904_orders"])
+
+	got, err := info.ResolveSeedFiles(fs, "project/seeds", []string{"users.sql", "new.sql"}, "default")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"users_1.sql", "new.sql"}, got)
+	_, err = info.ResolveSeedFiles(fs, "project/seeds", []string{"orders.sql"}, "default")
+	assert.Error(t, err)
+
+	// files which exist in the seeds of the database are not remapped
+	assert.NoError(t, afero.WriteFile(fs, "project/seeds/default/new.sql", []byte("select 1;"), 0644))
+	assert.NoError(t, afero.WriteFile(fs, "project/seeds/default/users.sql", []byte("select 1;"), 0644))
+	got, err = info.ResolveSeedFiles(fs, "project/seeds", []string{"new.sql"}, "default")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"new.sql"}, got)
+	// unless a file of the same name was moved to another file
+	_, err = info.ResolveSeedFiles(fs, "project/seeds", []string{"users.sql"}, "default")
+	assert.Error(t, err)
+}
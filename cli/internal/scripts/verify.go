@@ -0,0 +1,142 @@
+package scripts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v2"
+)
+
+// kinds of problems found by VerifyProjectV3
+const (
+	ProblemConfigVersion        = "config_version"
+	ProblemMissingDatabases     = "missing_databases"
+	ProblemUnknownMigrationsDir = "unknown_migrations_database"
+	ProblemUnmovedMigration     = "unmoved_migration"
+	ProblemUnknownSeedsDir      = "unknown_seeds_database"
+	ProblemUnmovedSeed          = "unmoved_seed"
+	ProblemLegacyMetadataFile   = "legacy_metadata_file"
+)
+
+// ValidationProblem is a problem found in the layout of a config v3 project
+type ValidationProblem struct {
+	Kind    string `json:"kind"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+// metadata files of config v2 projects, which are replaced
+// by the files of each database in config v3 projects
+var legacyMetadataFiles = []string{"tables.yaml", "functions.yaml"}
+
+// VerifyProjectV3 checks the layout of a config v3 project without the server,
+// eg: after a manual or interrupted update to config v3. The databases of the
+// project are read from the metadata directory, migrations and seeds have
+// to be in the directory of one of them. All problems found are returned,
+// an error is only returned when the project directory cannot be read
+func VerifyProjectV3(fs afero.Fs, version cli.ConfigVersion, migrationsDirectory, seedsDirectory, metadataDirectory string) ([]ValidationProblem, error) {
+	var problems []ValidationProblem
+	if version != cli.V3 {
+		problems = append(problems, ValidationProblem{
+			Kind:    ProblemConfigVersion,
+			Message: fmt.Sprintf("project is using config v%d, config v3 is expected", version),
+		})
+	}
+
+	databasesFile := filepath.Join(metadataDirectory, "databases", "databases.yaml")
+	databases, err := projectDatabases(fs, databasesFile)
+	if err != nil {
+		if !os.IsNotExist(errors.Cause(err)) {
+			return nil, err
+		}
+		problems = append(problems, ValidationProblem{
+			Kind:    ProblemMissingDatabases,
+			Path:    databasesFile,
+			Message: fmt.Sprintf("%s does not exist, databases of the project cannot be found", databasesFile),
+		})
+	}
+
+	// when the databases are not known, only migrations and seed
+	// files which were not moved to any directory are reported
+	migrationProblems, err := verifyDatabaseDirectories(fs, migrationsDirectory, databases, func(entry os.FileInfo) (ValidationProblem, bool) {
+		if ok, _ := isMigrationWithLegacyTimestamp(entry.Name()); ok {
+			return ValidationProblem{Kind: ProblemUnmovedMigration, Message: fmt.Sprintf("migration %s was not moved to the directory of a database", entry.Name())}, true
+		}
+		return ValidationProblem{Kind: ProblemUnknownMigrationsDir, Message: fmt.Sprintf("%s in the migrations directory is not the directory of a database of the project", entry.Name())}, databases != nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	problems = append(problems, migrationProblems...)
+
+	seedProblems, err := verifyDatabaseDirectories(fs, seedsDirectory, databases, func(entry os.FileInfo) (ValidationProblem, bool) {
+		if !entry.IsDir() {
+			return ValidationProblem{Kind: ProblemUnmovedSeed, Message: fmt.Sprintf("seed file %s was not moved to the directory of a database", entry.Name())}, true
+		}
+		return ValidationProblem{Kind: ProblemUnknownSeedsDir, Message: fmt.Sprintf("%s in the seeds directory is not the directory of a database of the project", entry.Name())}, databases != nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	problems = append(problems, seedProblems...)
+
+	for _, name := range legacyMetadataFiles {
+		path := filepath.Join(metadataDirectory, name)
+		if exists, err := afero.Exists(fs, path); err != nil {
+			return nil, err
+		} else if exists {
+			problems = append(problems, ValidationProblem{
+				Kind:    ProblemLegacyMetadataFile,
+				Path:    path,
+				Message: fmt.Sprintf("%s is a metadata file of config v2 projects, it is not used by config v3 projects", path),
+			})
+		}
+	}
+	return problems, nil
+}
+
+// projectDatabases returns the names of the databases in databases.yaml
+func projectDatabases(fs afero.Fs, databasesFile string) (map[string]bool, error) {
+	b, err := afero.ReadFile(fs, databasesFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", databasesFile)
+	}
+	var sources []struct {
+		Name string `yaml:"name"`
+	}
+	if err := yaml.Unmarshal(b, &sources); err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", databasesFile)
+	}
+	databases := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		databases[source.Name] = true
+	}
+	return databases, nil
+}
+
+// verifyDatabaseDirectories calls problem for each entry of dir which is not
+// the directory of one of databases, returning the problems it reports
+func verifyDatabaseDirectories(fs afero.Fs, dir string, databases map[string]bool, problem func(entry os.FileInfo) (ValidationProblem, bool)) ([]ValidationProblem, error) {
+	entries, err := afero.ReadDir(fs, dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var problems []ValidationProblem
+	for _, entry := range entries {
+		if entry.IsDir() && databases[entry.Name()] {
+			continue
+		}
+		if p, ok := problem(entry); ok {
+			p.Path = filepath.Join(dir, entry.Name())
+			problems = append(problems, p)
+		}
+	}
+	return problems, nil
+}
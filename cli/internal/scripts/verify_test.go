@@ -0,0 +1,55 @@
+package scripts
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyProjectV3(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for _, dir := range []string{
+		"migrations/default/1604855964903_test",
+		"migrations/1604855964904_test2",
+		"migrations/analytics",
+		"seeds/default",
+		"seeds/orders",
+	} {
+		if err := fs.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for file, content := range map[string]string{
+		"seeds/users.sql":                        "INSERT INTO users VALUES (1);",
+		"metadata/tables.yaml":                   "[]",
+		"metadata/databases/databases.yaml":      "- name: default\n  kind: postgres\n- name: orders\n  kind: postgres\n",
+		"metadata/databases/default/tables.yaml": "[]",
+	} {
+		if err := afero.WriteFile(fs, file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	problems, err := VerifyProjectV3(fs, cli.V2, "migrations", "seeds", "metadata")
+	assert.NoError(t, err)
+	var kinds, paths []string
+	for _, problem := range problems {
+		kinds = append(kinds, problem.Kind)
+		paths = append(paths, problem.Path)
+	}
+	assert.Equal(t, []string{ProblemConfigVersion, ProblemUnmovedMigration, ProblemUnknownMigrationsDir, ProblemUnmovedSeed, ProblemLegacyMetadataFile}, kinds)
+	assert.Equal(t, []string{"", "migrations/1604855964904_test2", "migrations/analytics", "seeds/users.sql", "metadata/tables.yaml"}, paths)
+
+	// without databases.yaml only unmoved migrations and seeds are reported
+	assert.NoError(t, fs.Remove("metadata/databases/databases.yaml"))
+	problems, err = VerifyProjectV3(fs, cli.V3, "migrations", "seeds", "metadata")
+	assert.NoError(t, err)
+	kinds = nil
+	for _, problem := range problems {
+		kinds = append(kinds, problem.Kind)
+	}
+	assert.Equal(t, []string{ProblemMissingDatabases, ProblemUnmovedMigration, ProblemUnmovedSeed, ProblemLegacyMetadataFile}, kinds)
+}
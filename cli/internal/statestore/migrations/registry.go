@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"github.com/hasura/graphql-engine/cli/internal/statestore"
+)
+
+const (
+	DefaultSchema          = "hdb_catalog"
+	DefaultMigrationsTable = "schema_migrations"
+)
+
+func init() {
+	statestore.RegisterMigrationsStateStore(statestore.StateStoreHdbTable, func(opts statestore.StateStoreOptions) statestore.MigrationsStateStore {
+		return NewMigrationStateStoreHdbTable(opts.GetPGSourceOps(), DefaultSchema, DefaultMigrationsTable)
+	})
+	statestore.RegisterMigrationsStateStore(statestore.StateStoreCatalog, func(opts statestore.StateStoreOptions) statestore.MigrationsStateStore {
+		return NewCatalogStateStore(statestore.NewCLICatalogState(opts.Client.V1Metadata))
+	})
+}
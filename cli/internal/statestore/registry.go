@@ -0,0 +1,109 @@
+package statestore
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
+)
+
+// names of the state store implementations registered by default
+const (
+	// state is stored in tables in the hdb_catalog schema (config v1 and v2)
+	StateStoreHdbTable = "hdb_table"
+	// state is stored in the catalog state of the server (config v3)
+	StateStoreCatalog = "catalog"
+)
+
+// StateStoreOptions has the dependencies available to state store implementations
+type StateStoreOptions struct {
+	Client *hasura.Client
+	// HasMetadataV3 is set when the server supports metadata v3
+	HasMetadataV3 bool
+	// PGSourceOps when set is used for running SQL, instead of choosing
+	// between the v1 and v2 query APIs of Client based on HasMetadataV3
+	PGSourceOps hasura.PGSourceOps
+}
+
+// GetPGSourceOps returns the client to be used by state stores for running SQL
+func (o StateStoreOptions) GetPGSourceOps() hasura.PGSourceOps {
+	if o.PGSourceOps != nil {
+		return o.PGSourceOps
+	}
+	if !o.HasMetadataV3 {
+		return o.Client.V1Query
+	}
+	return o.Client.V2Query
+}
+
+type MigrationsStateStoreFactory func(opts StateStoreOptions) MigrationsStateStore
+type SettingsStateStoreFactory func(opts StateStoreOptions) SettingsStateStore
+
+var (
+	registryMu                 sync.RWMutex
+	migrationsStateStoreByName = map[string]MigrationsStateStoreFactory{}
+	settingsStateStoreByName   = map[string]SettingsStateStoreFactory{}
+)
+
+// RegisterMigrationsStateStore makes a migrations state store implementation
+// available by name. Registering a name twice replaces the earlier implementation
+func RegisterMigrationsStateStore(name string, factory MigrationsStateStoreFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	migrationsStateStoreByName[name] = factory
+}
+
+// RegisterSettingsStateStore makes a settings state store implementation
+// available by name. Registering a name twice replaces the earlier implementation
+func RegisterSettingsStateStore(name string, factory SettingsStateStoreFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	settingsStateStoreByName[name] = factory
+}
+
+// NewMigrationsStateStore creates the migrations state store registered as name
+func NewMigrationsStateStore(name string, opts StateStoreOptions) (MigrationsStateStore, error) {
+	registryMu.RLock()
+	factory, ok := migrationsStateStoreByName[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown migrations state store %q, available: %v", name, MigrationsStateStores())
+	}
+	return factory(opts), nil
+}
+
+// NewSettingsStateStore creates the settings state store registered as name
+func NewSettingsStateStore(name string, opts StateStoreOptions) (SettingsStateStore, error) {
+	registryMu.RLock()
+	factory, ok := settingsStateStoreByName[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown settings state store %q, available: %v", name, SettingsStateStores())
+	}
+	return factory(opts), nil
+}
+
+// MigrationsStateStores returns the sorted names of registered migrations state stores
+func MigrationsStateStores() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	var names []string
+	for name := range migrationsStateStoreByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SettingsStateStores returns the sorted names of registered settings state stores
+func SettingsStateStores() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	var names []string
+	for name := range settingsStateStoreByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
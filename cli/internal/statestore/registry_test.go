@@ -0,0 +1,39 @@
+package statestore
+
+import (
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterMigrationsStateStore(t *testing.T) {
+	store := inMemoryMigrationsStateStore{}
+	RegisterMigrationsStateStore("in_memory", func(opts StateStoreOptions) MigrationsStateStore {
+		return store
+	})
+	defer func() {
+		registryMu.Lock()
+		delete(migrationsStateStoreByName, "in_memory")
+		registryMu.Unlock()
+	}()
+
+	assert.Contains(t, MigrationsStateStores(), "in_memory")
+	got, err := NewMigrationsStateStore("in_memory", StateStoreOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, got.InsertVersion("default", 1))
+	assert.Equal(t, inMemoryMigrationsStateStore{"default": {1: false}}, store)
+
+	_, err = NewMigrationsStateStore("unknown", StateStoreOptions{})
+	assert.Error(t, err)
+	_, err = NewSettingsStateStore("unknown", StateStoreOptions{})
+	assert.Error(t, err)
+}
+
+type fakePGSourceOps struct{ hasura.PGSourceOps }
+
+func TestStateStoreOptions_GetPGSourceOps(t *testing.T) {
+	ops := &fakePGSourceOps{}
+	opts := StateStoreOptions{Client: &hasura.Client{}, HasMetadataV3: true, PGSourceOps: ops}
+	assert.Equal(t, ops, opts.GetPGSourceOps())
+}
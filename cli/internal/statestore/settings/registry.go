@@ -0,0 +1,19 @@
+package settings
+
+import (
+	"github.com/hasura/graphql-engine/cli/internal/statestore"
+)
+
+const (
+	DefaultSchema        = "hdb_catalog"
+	DefaultSettingsTable = "migration_settings"
+)
+
+func init() {
+	statestore.RegisterSettingsStateStore(statestore.StateStoreHdbTable, func(opts statestore.StateStoreOptions) statestore.SettingsStateStore {
+		return NewStateStoreHdbTable(opts.GetPGSourceOps(), DefaultSchema, DefaultSettingsTable)
+	})
+	statestore.RegisterSettingsStateStore(statestore.StateStoreCatalog, func(opts statestore.StateStoreOptions) statestore.SettingsStateStore {
+		return NewStateStoreCatalog(statestore.NewCLICatalogState(opts.Client.V1Metadata))
+	})
+}
@@ -2,7 +2,11 @@ package statestore
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/hasura/graphql-engine/cli/internal/hasura"
 )
@@ -61,6 +65,16 @@ type MigrationsState map[string]map[string]bool
 type CLIState struct {
 	Migrations MigrationsState   `json:"migrations" mapstructure:"migrations"`
 	Settings   map[string]string `json:"settings" mapstructure:"settings"`
+	// IsStateCopyCompleted is set once state of a project is copied
+	// from hdb_catalog tables to catalog state
+	IsStateCopyCompleted bool `json:"isStateCopyCompleted" mapstructure:"isStateCopyCompleted"`
+	// StateCopyLabel is an optional label recorded along with IsStateCopyCompleted,
+	// describing who or what copied the state (eg: a CI run)
+	StateCopyLabel string `json:"stateCopyLabel,omitempty" mapstructure:"stateCopyLabel"`
+	// IsStateCopyInProgress is set before state is copied and unset along with
+	// setting IsStateCopyCompleted, so that a copy which was interrupted
+	// is not mistaken for a completed copy of an older CLI version
+	IsStateCopyInProgress bool `json:"isStateCopyInProgress,omitempty" mapstructure:"isStateCopyInProgress"`
 }
 
 func (c *CLIState) Init() {
@@ -82,6 +96,16 @@ func (c *CLIState) UnsetMigration(database, key string) {
 	delete(c.Migrations[database], key)
 }
 
+// RenameDatabase moves the migrations state recorded for database src to database dst
+func (c *CLIState) RenameDatabase(src, dst string) {
+	migrations, ok := c.Migrations[src]
+	if !ok {
+		return
+	}
+	c.Migrations[dst] = migrations
+	delete(c.Migrations, src)
+}
+
 func (c *CLIState) GetMigrationsByDatabase(database string) map[string]bool {
 	return c.Migrations[database]
 }
@@ -114,18 +138,168 @@ func CopyMigrationState(src, dest MigrationsStateStore, srcdatabase, destdatabas
 	if err != nil {
 		return err
 	}
+	// versions are upserted, so that a copy which was interrupted can be run again
 	for k, v := range versions {
-		dest.SetVersion(destdatabase, int64(k), v)
+		if err := dest.SetVersion(destdatabase, int64(k), v); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func CopySettingsState(src, dest SettingsStateStore) error {
+// VerifyMigrationState reads back the versions of srcdatabase in src and of
+// destdatabase in dest once they were copied, returning an error listing the
+// versions of src which are missing in dest or recorded with a different dirty flag
+func VerifyMigrationState(src, dest MigrationsStateStore, srcdatabase, destdatabase string) error {
+	srcVersions, err := src.GetVersions(srcdatabase)
+	if err != nil {
+		return err
+	}
+	destVersions, err := dest.GetVersions(destdatabase)
+	if err != nil {
+		return err
+	}
+	var missing, mismatched []uint64
+	for version, dirty := range srcVersions {
+		destDirty, ok := destVersions[version]
+		switch {
+		case !ok:
+			missing = append(missing, version)
+		case destDirty != dirty:
+			mismatched = append(mismatched, version)
+		}
+	}
+	if len(missing) == 0 && len(mismatched) == 0 {
+		return nil
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+	sort.Slice(mismatched, func(i, j int) bool { return mismatched[i] < mismatched[j] })
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("%d versions are missing: %v", len(missing), missing))
+	}
+	if len(mismatched) > 0 {
+		problems = append(problems, fmt.Sprintf("%d versions have a different dirty flag: %v", len(mismatched), mismatched))
+	}
+	return fmt.Errorf("migrations state of database %q does not match the state it was copied from, %s", destdatabase, strings.Join(problems, ", "))
+}
+
+// MigrationStatus is the status of a migration version of a database,
+// as recorded in a state store and present in the project directory
+type MigrationStatus struct {
+	Version         int64
+	AppliedOnServer bool
+	PresentLocally  bool
+}
+
+// Drifted reports whether the migration is applied on the server
+// but missing locally, or present locally but not applied
+func (s MigrationStatus) Drifted() bool {
+	return s.AppliedOnServer != s.PresentLocally
+}
+
+// GetMigrationStatus compares the versions of database recorded in store with
+// the versions of local migrations, returning the status of each of the versions
+// ordered by version. It works with any state store, so that the status can be
+// known both before and after a project is moved to config v3
+func GetMigrationStatus(store MigrationsStateStore, database string, local []uint64) ([]MigrationStatus, error) {
+	applied, err := store.GetVersions(database)
+	if err != nil {
+		return nil, err
+	}
+	statuses := map[uint64]*MigrationStatus{}
+	for version := range applied {
+		statuses[version] = &MigrationStatus{Version: int64(version), AppliedOnServer: true}
+	}
+	for _, version := range local {
+		if status, ok := statuses[version]; ok {
+			status.PresentLocally = true
+			continue
+		}
+		statuses[version] = &MigrationStatus{Version: int64(version), PresentLocally: true}
+	}
+	result := make([]MigrationStatus, 0, len(statuses))
+	for _, status := range statuses {
+		result = append(result, *status)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
+
+// GetCompactableVersions returns the versions recorded for a database in the
+// state store which are not present in onDisk. The highest recorded version
+// is never returned, so that the applied high-water mark is retained even
+// when its migration file was squashed or removed.
+func GetCompactableVersions(store MigrationsStateStore, database string, onDisk map[uint64]bool) ([]uint64, error) {
+	versions, err := store.GetVersions(database)
+	if err != nil {
+		return nil, err
+	}
+	var highWaterMark uint64
+	for version := range versions {
+		if version > highWaterMark {
+			highWaterMark = version
+		}
+	}
+	var compactable []uint64
+	for version := range versions {
+		if version == highWaterMark || onDisk[version] {
+			continue
+		}
+		compactable = append(compactable, version)
+	}
+	sort.Slice(compactable, func(i, j int) bool { return compactable[i] < compactable[j] })
+	return compactable, nil
+}
+
+// CompactMigrationState removes the given versions of a database from the state store
+func CompactMigrationState(store MigrationsStateStore, database string, versions []uint64) error {
+	for _, version := range versions {
+		if err := store.RemoveVersion(database, int64(version)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DiffSettings returns the settings whose values differ between src and dst,
+// keyed by setting name, with the value in dst (old) followed by the value in
+// src (new), as they would change when copying settings from src to dst.
+// A setting present in only one of the stores has an empty value for the other
+func DiffSettings(src, dst SettingsStateStore) (map[string][2]string, error) {
+	srcSettings, err := src.GetAllSettings()
+	if err != nil {
+		return nil, err
+	}
+	dstSettings, err := dst.GetAllSettings()
+	if err != nil {
+		return nil, err
+	}
+	diff := map[string][2]string{}
+	for k, v := range srcSettings {
+		if old, ok := dstSettings[k]; !ok || old != v {
+			diff[k] = [2]string{old, v}
+		}
+	}
+	for k, v := range dstSettings {
+		if _, ok := srcSettings[k]; !ok {
+			diff[k] = [2]string{v, ""}
+		}
+	}
+	return diff, nil
+}
+
+// CopySettingsState copies settings from src to dest. When keys are given
+// only the settings named in keys are copied, otherwise all settings are copied
+func CopySettingsState(src, dest SettingsStateStore, keys ...string) error {
 	settings, err := src.GetAllSettings()
 	if err != nil {
 		return err
 	}
 	for k, v := range settings {
+		if !SettingAllowed(k, keys) {
+			continue
+		}
 		err := dest.UpdateSetting(k, v)
 		if err != nil {
 			return err
@@ -133,3 +307,164 @@ func CopySettingsState(src, dest SettingsStateStore) error {
 	}
 	return nil
 }
+
+// VerifySettingsState reads back the settings of src and dest once they were
+// copied, returning an error listing the settings of src (only the ones named in
+// keys, when keys are given) which are missing in dest or have a different value
+func VerifySettingsState(src, dest SettingsStateStore, keys ...string) error {
+	srcSettings, err := src.GetAllSettings()
+	if err != nil {
+		return err
+	}
+	destSettings, err := dest.GetAllSettings()
+	if err != nil {
+		return err
+	}
+	var different []string
+	for k, v := range srcSettings {
+		if !SettingAllowed(k, keys) {
+			continue
+		}
+		if destValue, ok := destSettings[k]; !ok || destValue != v {
+			different = append(different, k)
+		}
+	}
+	if len(different) == 0 {
+		return nil
+	}
+	sort.Strings(different)
+	return fmt.Errorf("settings state does not match the state it was copied from, settings missing or different: %s", strings.Join(different, ", "))
+}
+
+// SettingAllowed reports whether setting name is in allowlist, all settings
+// are allowed when allowlist is empty
+func SettingAllowed(name string, allowlist []string) bool {
+	if len(allowlist) == 0 {
+		return true
+	}
+	for _, key := range allowlist {
+		if key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// kinds of StateChange
+const (
+	StateChangeMigration = "migration"
+	StateChangeSetting   = "setting"
+	StateChangeFlag      = "flag"
+)
+
+// StateChange is a difference between two CLIState, eg: a migration version
+// recorded for a database. Before and After are empty when the migration or
+// setting did not exist, migrations have "dirty" or "clean" as their value
+type StateChange struct {
+	Kind   string `json:"kind"`
+	Source string `json:"source,omitempty"`
+	Key    string `json:"key"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+func (c StateChange) String() string {
+	name := c.Key
+	if len(c.Source) > 0 {
+		name = c.Source + "/" + c.Key
+	}
+	switch {
+	case len(c.Before) == 0:
+		return fmt.Sprintf("%s %s added (%s)", c.Kind, name, c.After)
+	case len(c.After) == 0:
+		return fmt.Sprintf("%s %s removed (was %s)", c.Kind, name, c.Before)
+	}
+	return fmt.Sprintf("%s %s changed from %s to %s", c.Kind, name, c.Before, c.After)
+}
+
+// DiffCLIState returns the changes from before to after in migrations
+// state, settings and flags, sorted by kind, source and key. A nil
+// state is treated as an empty one
+func DiffCLIState(before, after *CLIState) []StateChange {
+	if before == nil {
+		before = &CLIState{}
+	}
+	if after == nil {
+		after = &CLIState{}
+	}
+	var changes []StateChange
+	for _, source := range sortedKeys(before.Migrations, after.Migrations) {
+		b, a := before.Migrations[source], after.Migrations[source]
+		versions := map[string]bool{}
+		for version := range b {
+			versions[version] = true
+		}
+		for version := range a {
+			versions[version] = true
+		}
+		for _, version := range sortedStrings(versions) {
+			change := StateChange{Kind: StateChangeMigration, Source: source, Key: version}
+			if dirty, ok := b[version]; ok {
+				change.Before = dirtyState(dirty)
+			}
+			if dirty, ok := a[version]; ok {
+				change.After = dirtyState(dirty)
+			}
+			if change.Before != change.After {
+				changes = append(changes, change)
+			}
+		}
+	}
+	settings := map[string]bool{}
+	for name := range before.Settings {
+		settings[name] = true
+	}
+	for name := range after.Settings {
+		settings[name] = true
+	}
+	for _, name := range sortedStrings(settings) {
+		if before.Settings[name] != after.Settings[name] {
+			changes = append(changes, StateChange{Kind: StateChangeSetting, Key: name, Before: before.Settings[name], After: after.Settings[name]})
+		}
+	}
+	flags := []struct {
+		name          string
+		before, after string
+	}{
+		{"isStateCopyCompleted", strconv.FormatBool(before.IsStateCopyCompleted), strconv.FormatBool(after.IsStateCopyCompleted)},
+		{"isStateCopyInProgress", strconv.FormatBool(before.IsStateCopyInProgress), strconv.FormatBool(after.IsStateCopyInProgress)},
+		{"stateCopyLabel", before.StateCopyLabel, after.StateCopyLabel},
+	}
+	for _, flag := range flags {
+		if flag.before != flag.after {
+			changes = append(changes, StateChange{Kind: StateChangeFlag, Key: flag.name, Before: flag.before, After: flag.after})
+		}
+	}
+	return changes
+}
+
+func dirtyState(dirty bool) string {
+	if dirty {
+		return "dirty"
+	}
+	return "clean"
+}
+
+func sortedKeys(states ...MigrationsState) []string {
+	keys := map[string]bool{}
+	for _, state := range states {
+		for key := range state {
+			keys[key] = true
+		}
+	}
+	return sortedStrings(keys)
+}
+
+func sortedStrings(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -118,3 +118,183 @@ func TestCLICatalogState_Set(t *testing.T) {
 		})
 	}
 }
+
+// inMemoryMigrationsStateStore is a MigrationsStateStore backed by a map, for tests
+// which do not need a running server
+type inMemoryMigrationsStateStore map[string]map[uint64]bool
+
+func (s inMemoryMigrationsStateStore) InsertVersion(database string, version int64) error {
+	return s.SetVersion(database, version, false)
+}
+
+func (s inMemoryMigrationsStateStore) RemoveVersion(database string, version int64) error {
+	delete(s[database], uint64(version))
+	return nil
+}
+
+func (s inMemoryMigrationsStateStore) SetVersion(database string, version int64, dirty bool) error {
+	if s[database] == nil {
+		s[database] = map[uint64]bool{}
+	}
+	s[database][uint64(version)] = dirty
+	return nil
+}
+
+func (s inMemoryMigrationsStateStore) GetVersions(database string) (map[uint64]bool, error) {
+	return s[database], nil
+}
+
+func (s inMemoryMigrationsStateStore) PrepareMigrationsStateStore() error {
+	return nil
+}
+
+func TestGetCompactableVersions(t *testing.T) {
+	type args struct {
+		store    MigrationsStateStore
+		database string
+		onDisk   map[uint64]bool
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []uint64
+		wantErr bool
+	}{
+		{
+			"returns versions not present on disk",
+			args{
+				store: inMemoryMigrationsStateStore{
+					"default": {1: false, 2: false, 3: false, 4: false},
+				},
+				database: "default",
+				onDisk:   map[uint64]bool{1: true, 4: true},
+			},
+			[]uint64{2, 3},
+			false,
+		},
+		{
+			"keeps the high-water mark even when it is not on disk",
+			args{
+				store: inMemoryMigrationsStateStore{
+					"default": {1: false, 2: false, 3: false},
+				},
+				database: "default",
+				onDisk:   map[uint64]bool{},
+			},
+			[]uint64{1, 2},
+			false,
+		},
+		{
+			"returns nothing for an unknown database",
+			args{
+				store:    inMemoryMigrationsStateStore{},
+				database: "default",
+				onDisk:   map[uint64]bool{},
+			},
+			nil,
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetCompactableVersions(tt.args.store, tt.args.database, tt.args.onDisk)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCompactMigrationState(t *testing.T) {
+	store := inMemoryMigrationsStateStore{
+		"default": {1: false, 2: false, 3: false},
+	}
+	assert.NoError(t, CompactMigrationState(store, "default", []uint64{1, 2}))
+	got, err := store.GetVersions("default")
+	assert.NoError(t, err)
+	assert.Equal(t, map[uint64]bool{3: false}, got)
+}
+
+type mapSettingsStateStore map[string]string
+
+func (s mapSettingsStateStore) GetSetting(name string) (string, error) {
+	return s[name], nil
+}
+
+func (s mapSettingsStateStore) UpdateSetting(name string, value string) error {
+	s[name] = value
+	return nil
+}
+
+func (s mapSettingsStateStore) GetAllSettings() (map[string]string, error) {
+	return s, nil
+}
+
+func (s mapSettingsStateStore) PrepareSettingsDriver() error {
+	return nil
+}
+
+func TestDiffSettings(t *testing.T) {
+	src := mapSettingsStateStore{"migration_mode": "false", "same": "x", "new": "y"}
+	dst := mapSettingsStateStore{"migration_mode": "true", "same": "x", "old": "z"}
+	got, err := DiffSettings(src, dst)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][2]string{
+		"migration_mode": {"true", "false"},
+		"new":            {"", "y"},
+		"old":            {"z", ""},
+	}, got)
+}
+
+func TestVerifyMigrationState(t *testing.T) {
+	src := inMemoryMigrationsStateStore{"": {1: false, 2: true, 3: false}}
+	assert.NoError(t, VerifyMigrationState(src, inMemoryMigrationsStateStore{"default": {1: false, 2: true, 3: false, 4: false}}, "", "default"))
+
+	err := VerifyMigrationState(src, inMemoryMigrationsStateStore{"default": {2: false}}, "", "default")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "2 versions are missing: [1 3]")
+		assert.Contains(t, err.Error(), "1 versions have a different dirty flag: [2]")
+	}
+}
+
+func TestVerifySettingsState(t *testing.T) {
+	src := mapSettingsStateStore{"migration_mode": "true", "other": "x"}
+	assert.NoError(t, VerifySettingsState(src, mapSettingsStateStore{"migration_mode": "true", "other": "x", "extra": "y"}))
+	assert.NoError(t, VerifySettingsState(src, mapSettingsStateStore{"other": "x"}, "other"))
+
+	err := VerifySettingsState(src, mapSettingsStateStore{"migration_mode": "false"})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "migration_mode, other")
+	}
+}
+
+func TestDiffCLIState(t *testing.T) {
+	before := &CLIState{
+		Migrations: MigrationsState{"default": {"1604855964903": false, "1604855964904": true}},
+		Settings:   map[string]string{"migration_mode": "true"},
+	}
+	after := &CLIState{
+		Migrations: MigrationsState{
+			"default": {"1604855964903": false, "1604855964904": false},
+			"orders":  {"1604855964905": false},
+		},
+		Settings:             map[string]string{"migration_mode": "false", "api_version": "1"},
+		IsStateCopyCompleted: true,
+	}
+	changes := DiffCLIState(before, after)
+	assert.Equal(t, []StateChange{
+		{Kind: StateChangeMigration, Source: "default", Key: "1604855964904", Before: "dirty", After: "clean"},
+		{Kind: StateChangeMigration, Source: "orders", Key: "1604855964905", After: "clean"},
+		{Kind: StateChangeSetting, Key: "api_version", After: "1"},
+		{Kind: StateChangeSetting, Key: "migration_mode", Before: "true", After: "false"},
+		{Kind: StateChangeFlag, Key: "isStateCopyCompleted", Before: "false", After: "true"},
+	}, changes)
+	assert.Equal(t, "migration orders/1604855964905 added (clean)", changes[1].String())
+	assert.Equal(t, "setting migration_mode changed from true to false", changes[3].String())
+
+	assert.Empty(t, DiffCLIState(after, after))
+	assert.Len(t, DiffCLIState(nil, after), 6)
+}
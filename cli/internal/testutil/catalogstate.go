@@ -0,0 +1,38 @@
+package testutil
+
+import (
+	"encoding/json"
+
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
+)
+
+// ResetCatalogState clears the migrations state of source, the settings and the
+// isStateCopyCompleted flag from the CLI catalog state, so that tests copying
+// state can be run repeatedly against the same server.
+// It accepts a hasura.CatalogStateOperations (eg: ec.APIClient.V1Metadata)
+// rather than cli.ExecutionContext, since cli depends on packages which use testutil
+func ResetCatalogState(t TestingT, client hasura.CatalogStateOperations, source string) {
+	r, err := client.Get()
+	if err != nil {
+		t.Fatalf("getting catalog state: %v", err)
+	}
+	var catalogState struct {
+		CLIState map[string]interface{} `json:"cli_state"`
+	}
+	if err := json.NewDecoder(r).Decode(&catalogState); err != nil {
+		t.Fatalf("decoding catalog state: %v", err)
+	}
+
+	state := catalogState.CLIState
+	if state == nil {
+		state = map[string]interface{}{}
+	}
+	if migrations, ok := state["migrations"].(map[string]interface{}); ok {
+		delete(migrations, source)
+	}
+	state["settings"] = map[string]string{}
+	state["isStateCopyCompleted"] = false
+	if _, err := client.Set("cli", state); err != nil {
+		t.Fatalf("resetting catalog state: %v", err)
+	}
+}
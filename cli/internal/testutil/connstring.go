@@ -0,0 +1,25 @@
+package testutil
+
+import "fmt"
+
+// credentials of the postgres containers started by the helpers
+const (
+	PostgresUser     = "postgres"
+	PostgresPassword = "postgrespassword"
+)
+
+// PostgresConnString returns the url of database db of a postgres container
+// started by the helpers, reachable at host:port
+func PostgresConnString(host, port, db string, sslDisabled bool) string {
+	connString := fmt.Sprintf("postgres://%s:%s@%s:%s/%s", PostgresUser, PostgresPassword, host, port, db)
+	if sslDisabled {
+		connString += "?sslmode=disable"
+	}
+	return connString
+}
+
+// MSSQLConnString returns the ODBC connection string (as expected by hasura) of
+// database db of a mssql container started by the helpers, reachable at host:port
+func MSSQLConnString(host, port, db string) string {
+	return fmt.Sprintf("DRIVER={ODBC Driver 17 for SQL Server};SERVER=%s,%s;DATABASE=%s;Uid=SA;Pwd=%s;Encrypt=no", host, port, db, MSSQLPassword)
+}
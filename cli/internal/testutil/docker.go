@@ -0,0 +1,84 @@
+package testutil
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ory/dockertest/v3"
+	dc "github.com/ory/dockertest/v3/docker"
+)
+
+var (
+	sharedPoolOnce sync.Once
+	sharedPool     *dockertest.Pool
+	sharedPoolErr  error
+)
+
+// Pool returns the docker pool shared by the helpers which start containers.
+// The pool is created and the connection to docker is checked only once
+func Pool() (*dockertest.Pool, error) {
+	sharedPoolOnce.Do(func() {
+		pool, err := dockertest.NewPool("")
+		if err != nil {
+			sharedPoolErr = err
+			return
+		}
+		if err := pool.Client.Ping(); err != nil {
+			sharedPoolErr = err
+			return
+		}
+		sharedPool = pool
+	})
+	return sharedPool, sharedPoolErr
+}
+
+// mustGetPool returns the shared docker pool, failing t when docker is not reachable
+func mustGetPool(t TestingT) *dockertest.Pool {
+	pool, err := Pool()
+	if err != nil {
+		t.Fatalf("Could not connect to docker: %s", err)
+	}
+	return pool
+}
+
+// DumpContainerLogs captures the logs of containers, which would otherwise be lost
+// when they are purged. Logs are written to <container name>.log files in the directory
+// set in HASURA_TEST_CONTAINER_LOGS_DIR, or to the test log when it is not set.
+func DumpContainerLogs(t TestingT, pool *dockertest.Pool, resources ...*dockertest.Resource) {
+	for _, resource := range resources {
+		if resource == nil || resource.Container == nil {
+			continue
+		}
+		name := strings.TrimPrefix(resource.Container.Name, "/")
+		var logs bytes.Buffer
+		err := pool.Client.Logs(dc.LogsOptions{
+			Container:    resource.Container.ID,
+			OutputStream: &logs,
+			ErrorStream:  &logs,
+			Stdout:       true,
+			Stderr:       true,
+		})
+		if err != nil {
+			t.Logf("could not get logs of container %s: %v", name, err)
+			continue
+		}
+		if len(ContainerLogsDir) == 0 {
+			t.Logf("logs of container %s:\n%s", name, logs.String())
+			continue
+		}
+		if err := os.MkdirAll(ContainerLogsDir, os.ModePerm); err != nil {
+			t.Logf("could not create container logs directory: %v", err)
+			continue
+		}
+		path := filepath.Join(ContainerLogsDir, name+".log")
+		if err := ioutil.WriteFile(path, logs.Bytes(), 0644); err != nil {
+			t.Logf("could not write logs of container %s: %v", name, err)
+			continue
+		}
+		t.Logf("logs of container %s written to %s", name, path)
+	}
+}
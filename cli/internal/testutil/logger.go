@@ -0,0 +1,30 @@
+package testutil
+
+import (
+	"regexp"
+)
+
+// Logger receives diagnostics of the start helpers. A TestingT is a
+// Logger, which is used when no logger is passed to the start helpers
+type Logger interface {
+	Logf(format string, args ...interface{})
+}
+
+func getLogger(t TestingT, loggers []Logger) Logger {
+	if len(loggers) > 0 && loggers[0] != nil {
+		return loggers[0]
+	}
+	return t
+}
+
+var (
+	passwordRegex    = regexp.MustCompile(`(?i)\b(pwd|password)=[^;&\s]*`)
+	urlPasswordRegex = regexp.MustCompile(`://([^:/@\s]+):[^@\s]*@`)
+)
+
+// redactSecrets masks passwords in connection strings and database
+// urls, so that they can be logged
+func redactSecrets(s string) string {
+	s = passwordRegex.ReplaceAllString(s, "$1=*****")
+	return urlPasswordRegex.ReplaceAllString(s, "://$1:*****@")
+}
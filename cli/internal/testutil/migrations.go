@@ -0,0 +1,37 @@
+package testutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+)
+
+// timestamp (in milliseconds) of the first migration created by GenerateMigrations
+const generatedMigrationsBaseVersion int64 = 1600000000000
+
+// GenerateMigrations creates count migration directories in dir, each named
+// <13 digit timestamp>_migration_<n> and having an up.sql and a down.sql.
+// Timestamps increase by a second from one migration to the next, so that
+// large migration histories can be created for benchmarks
+func GenerateMigrations(fs afero.Fs, dir string, count int) error {
+	for i := 0; i < count; i++ {
+		version := generatedMigrationsBaseVersion + int64(i)*1000
+		name := fmt.Sprintf("%d_migration_%d", version, i)
+		migrationDir := filepath.Join(dir, name)
+		if err := fs.MkdirAll(migrationDir, os.ModePerm); err != nil {
+			return err
+		}
+		table := fmt.Sprintf("generated_%d", i)
+		up := fmt.Sprintf("CREATE TABLE %s (id serial PRIMARY KEY);\n", table)
+		if err := afero.WriteFile(fs, filepath.Join(migrationDir, "up.sql"), []byte(up), 0644); err != nil {
+			return err
+		}
+		down := fmt.Sprintf("DROP TABLE %s;\n", table)
+		if err := afero.WriteFile(fs, filepath.Join(migrationDir, "down.sql"), []byte(down), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	dc "github.com/ory/dockertest/v3/docker"
+)
+
+// ReusedContainerLabel is set on the containers started by StartHasuraShared,
+// which are left running to be reused, so that they can be found and purged
+// using PurgeReusedContainers
+const ReusedContainerLabel = "io.hasura.cli.test.reused"
+
+// ReusedContainerStartedLabel is set to the unix time at which a container
+// labelled with ReusedContainerLabel was started, so that the containers still
+// being started by the tests of another package are not taken as stale
+const ReusedContainerStartedLabel = "io.hasura.cli.test.started"
+
+// reusedContainerStartTimeout is how long a reused container can take to
+// start, including pulling its image, before it is taken as stale
+const reusedContainerStartTimeout = 5 * time.Minute
+
+// errReusedContainersStarting is returned by findReusableContainers when the
+// reused containers are still being started, eg: by the tests of another package
+var errReusedContainersStarting = errors.New("reused containers are still being started")
+
+var invalidContainerNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// StartHasuraShared returns the port of a hasura instance and a postgres database
+// in docker, which are reused by the tests calling it with the same version, even
+// across packages. The containers are started when they are not running yet and are
+// left running by teardown, they are only removed by PurgeReusedContainers.
+// When the tests of several packages start the containers at the same time, one of
+// them starts the containers and the others wait for the containers to be started.
+// Since state is shared, tests using it should not depend on a fresh database
+func StartHasuraShared(t TestingT, version string, logger ...Logger) (port string, teardown func()) {
+	pool := mustGetPool(t)
+	name := "hasura-cli-test-shared-" + invalidContainerNameChars.ReplaceAllString(version, "_")
+	ctx, cancel := context.WithTimeout(context.Background(), reusedContainerStartTimeout)
+	defer cancel()
+	var pg, hasura *dockertest.Resource
+	var reused bool
+	for {
+		var err error
+		pg, hasura, err = findReusableContainers(pool, name, time.Now())
+		if err == nil && hasura == nil {
+			labels := map[string]string{
+				ReusedContainerLabel:        "true",
+				ReusedContainerStartedLabel: strconv.FormatInt(time.Now().Unix(), 10),
+			}
+			// a name conflict means the containers were created by the tests of another
+			// package since they were looked up, so they are looked up again to be reused
+			pg, hasura, err = startHasuraContainers(ctx, t, pool, version, name, labels)
+			if err == nil {
+				break
+			}
+		} else if err == nil {
+			reused = true
+			break
+		}
+		if !errors.Is(err, errReusedContainersStarting) && !errors.Is(err, dc.ErrContainerAlreadyExists) {
+			t.Fatalf("Could not look up reusable containers: %s", err)
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("Could not look up reusable containers: %s: %s", ctx.Err(), err)
+		case <-time.After(time.Second):
+		}
+	}
+	if reused {
+		if err := waitForHasura(ctx, pool, hasura.GetPort("8080/tcp")); err != nil {
+			DumpContainerLogs(t, pool, pg, hasura)
+			t.Fatalf("Could not connect to reused hasura container: %s", err)
+		}
+		getLogger(t, logger).Logf("reusing hasura %s at %s:%s", version, BaseURL, hasura.GetPort("8080/tcp"))
+	} else {
+		getLogger(t, logger).Logf("hasura %s is ready at %s:%s, it will be reused", version, BaseURL, hasura.GetPort("8080/tcp"))
+	}
+
+	teardown = func() {
+		if t.Failed() {
+			DumpContainerLogs(t, pool, pg, hasura)
+		}
+	}
+	return hasura.GetPort("8080/tcp"), teardown
+}
+
+// findReusableContainers returns the running containers <name>-pg and
+// <name>-hasura started by StartHasuraShared. When only one of them is
+// running, it is removed and none are returned, so that both are started again.
+// Containers are only removed once they had reusedContainerStartTimeout to
+// start, until then errReusedContainersStarting is returned
+func findReusableContainers(pool *dockertest.Pool, name string, now time.Time) (pg, hasura *dockertest.Resource, err error) {
+	containers, err := listReusedContainers(pool)
+	if err != nil {
+		return nil, nil, err
+	}
+	var stale []dc.APIContainers
+	for _, container := range containers {
+		var containerName string
+		if len(container.Names) > 0 {
+			containerName = strings.TrimPrefix(container.Names[0], "/")
+		}
+		if containerName != name+"-pg" && containerName != name+"-hasura" {
+			continue
+		}
+		if container.State != "running" {
+			stale = append(stale, container)
+			continue
+		}
+		c, err := pool.Client.InspectContainer(container.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if containerName == name+"-pg" {
+			pg = &dockertest.Resource{Container: c}
+		} else {
+			hasura = &dockertest.Resource{Container: c}
+		}
+	}
+	if pg == nil || hasura == nil {
+		for _, container := range containers {
+			if (pg != nil && container.ID == pg.Container.ID) || (hasura != nil && container.ID == hasura.Container.ID) {
+				stale = append(stale, container)
+			}
+		}
+		pg, hasura = nil, nil
+	}
+	for _, container := range stale {
+		if isStartingContainer(container, now) {
+			return nil, nil, errReusedContainersStarting
+		}
+	}
+	for _, container := range stale {
+		if err := pool.Client.RemoveContainer(dc.RemoveContainerOptions{ID: container.ID, Force: true, RemoveVolumes: true}); err != nil {
+			return nil, nil, fmt.Errorf("removing stale container %s: %w", container.ID, err)
+		}
+	}
+	return pg, hasura, nil
+}
+
+// isStartingContainer returns if container was started less than
+// reusedContainerStartTimeout before now, containers without
+// ReusedContainerStartedLabel are never taken as starting
+func isStartingContainer(container dc.APIContainers, now time.Time) bool {
+	started, err := strconv.ParseInt(container.Labels[ReusedContainerStartedLabel], 10, 64)
+	if err != nil {
+		return false
+	}
+	return now.Sub(time.Unix(started, 0)) < reusedContainerStartTimeout
+}
+
+func listReusedContainers(pool *dockertest.Pool) ([]dc.APIContainers, error) {
+	return pool.Client.ListContainers(dc.ListContainersOptions{
+		All:     true,
+		Filters: map[string][]string{"label": {ReusedContainerLabel}},
+	})
+}
+
+// PurgeReusedContainers removes the containers started by StartHasuraShared,
+// it is meant to be called once all of the tests of a package were run, eg: from TestMain
+func PurgeReusedContainers(t TestingT) {
+	pool := mustGetPool(t)
+	containers, err := listReusedContainers(pool)
+	if err != nil {
+		t.Fatalf("Could not list reused containers: %s", err)
+	}
+	for _, container := range containers {
+		// containers which are still starting are used by the tests of another package
+		if container.State != "running" && isStartingContainer(container, time.Now()) {
+			continue
+		}
+		if err := pool.Client.RemoveContainer(dc.RemoveContainerOptions{ID: container.ID, Force: true, RemoveVolumes: true}); err != nil {
+			t.Fatalf("Could not purge reused container %s: %s", container.ID, err)
+		}
+	}
+}
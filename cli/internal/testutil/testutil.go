@@ -3,24 +3,27 @@ package testutil
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/gofrs/uuid"
-	"github.com/stretchr/testify/require"
 
 	"github.com/Pallinder/go-randomdata"
 
 	_ "github.com/denisenkom/go-mssqldb"
+	_ "github.com/go-sql-driver/mysql"
 	"github.com/hasura/graphql-engine/cli/internal/httpc"
 	_ "github.com/lib/pq"
 	"github.com/ory/dockertest/v3"
+	dc "github.com/ory/dockertest/v3/docker"
 )
 
 // As a workaround for using test helpers on Ginkgo tests
@@ -31,46 +34,95 @@ type TestingT interface {
 	Skip(args ...interface{})
 	Fatal(args ...interface{})
 	Fatalf(format string, args ...interface{})
+	Logf(format string, args ...interface{})
+	Failed() bool
 }
 
-func StartHasura(t TestingT, version string) (port string, teardown func()) {
+// StartHasura starts a hasura instance and a postgres database in docker. Diagnostics
+// are logged to logger when one is passed, or to t otherwise. When HASURA_TEST_REUSE_CONTAINER
+// is set to true, the containers started by StartHasuraShared are used instead
+func StartHasura(t TestingT, version string, logger ...Logger) (port string, teardown func()) {
+	if ReuseContainers {
+		return StartHasuraShared(t, version, logger...)
+	}
+	return StartHasuraWithContext(context.Background(), t, version, logger...)
+}
+
+// StartHasuraWithContext is StartHasura, giving up on starting the containers
+// when ctx is done, eg: when an image pull stalls. Containers which were already
+// started are removed before t is failed
+func StartHasuraWithContext(ctx context.Context, t TestingT, version string, logger ...Logger) (port string, teardown func()) {
+	pool := mustGetPool(t)
+	pg, hasura, err := startHasuraContainers(ctx, t, pool, version, getUniqueName(t), nil)
+	if err != nil {
+		t.Fatalf("Could not start resource: %s", err)
+	}
+	getLogger(t, logger).Logf("hasura %s is ready at %s:%s", version, BaseURL, hasura.GetPort("8080/tcp"))
+
+	teardown = func() {
+		if t.Failed() {
+			DumpContainerLogs(t, pool, pg, hasura)
+		}
+		if err := pool.Purge(hasura); err != nil {
+			t.Fatalf("Could not purge resource: %s", err)
+		}
+		if err := pool.Purge(pg); err != nil {
+			t.Fatalf("Could not purge resource: %s", err)
+		}
+	}
+	return hasura.GetPort("8080/tcp"), teardown
+}
+
+// startHasuraContainers starts a postgres container named <name>-pg and a hasura
+// container using it named <name>-hasura, with labels, and waits for hasura to be ready.
+// When starting them fails or ctx is done, the containers started are removed.
+// An error is only returned when a container named <name>-pg or <name>-hasura
+// already exists, t is failed otherwise
+func startHasuraContainers(ctx context.Context, t TestingT, pool *dockertest.Pool, version, name string, labels map[string]string) (pg, hasura *dockertest.Resource, err error) {
 	if len(version) == 0 {
 		t.Fatal("no hasura version provided, probably use testutil.HasuraVersion")
 	}
-	var err error
-	pool, err := dockertest.NewPool("")
-	if err != nil {
-		t.Fatalf("Could not connect to docker: %s", err)
+	fail := func(format string, err error, started ...*dockertest.Resource) {
+		DumpContainerLogs(t, pool, started...)
+		for _, resource := range started {
+			if purgeErr := pool.Purge(resource); purgeErr != nil {
+				t.Logf("could not purge resource: %s", purgeErr)
+			}
+		}
+		t.Fatalf(format, err)
 	}
-	uniqueName := getUniqueName(t)
 	pgopts := &dockertest.RunOptions{
-		Name:       fmt.Sprintf("%s-%s", uniqueName, "pg"),
+		Name:       fmt.Sprintf("%s-%s", name, "pg"),
 		Repository: "postgres",
 		Tag:        "11",
 		Env: []string{
-			"POSTGRES_PASSWORD=postgrespassword",
+			fmt.Sprintf("POSTGRES_PASSWORD=%s", PostgresPassword),
 			"POSTGRES_DB=postgres",
 		},
 		ExposedPorts: []string{"5432/tcp"},
+		Labels:       labels,
+	}
+	pg, err = runWithContext(ctx, pool, pgopts)
+	if errors.Is(err, dc.ErrContainerAlreadyExists) {
+		return nil, nil, err
 	}
-	pg, err := pool.RunWithOptions(pgopts)
 	if err != nil {
 		t.Fatalf("Could not start resource: %s", err)
 	}
 	var db *sql.DB
-	if err = pool.Retry(func() error {
+	if err = retryWithContext(ctx, pool, func(ctx context.Context) error {
 		var err error
-		db, err = sql.Open("postgres", fmt.Sprintf("postgres://postgres:postgrespassword@%s:%s/%s?sslmode=disable", "0.0.0.0", pg.GetPort("5432/tcp"), "postgres"))
+		db, err = sql.Open("postgres", PostgresConnString("0.0.0.0", pg.GetPort("5432/tcp"), "postgres", true))
 		if err != nil {
 			return err
 		}
-		return db.Ping()
+		return db.PingContext(ctx)
 	}); err != nil {
-		t.Fatal(err)
+		fail("Could not connect to postgres: %s", err, pg)
 	}
 
 	envs := []string{
-		fmt.Sprintf("HASURA_GRAPHQL_DATABASE_URL=postgres://postgres:postgrespassword@%s:%s/postgres", DockerSwitchIP, pg.GetPort("5432/tcp")),
+		fmt.Sprintf("HASURA_GRAPHQL_DATABASE_URL=%s", PostgresConnString(DockerSwitchIP, pg.GetPort("5432/tcp"), "postgres", false)),
 		`HASURA_GRAPHQL_ENABLE_CONSOLE=true`,
 		"HASURA_GRAPHQL_DEV_MODE=true",
 		"HASURA_GRAPHQL_ENABLED_LOG_TYPES=startup, http-log, webhook-log, websocket-log, query-log",
@@ -80,57 +132,109 @@ func StartHasura(t TestingT, version string) (port string, teardown func()) {
 		envs = append(envs, fmt.Sprintf("HASURA_GRAPHQL_ADMIN_SECRET=%s", adminSecret))
 	}
 	hasuraopts := &dockertest.RunOptions{
-		Name:         fmt.Sprintf("%s-%s", uniqueName, "hasura"),
+		Name:         fmt.Sprintf("%s-%s", name, "hasura"),
 		Repository:   HasuraDockerRepo,
 		Tag:          version,
 		Env:          envs,
 		ExposedPorts: []string{"8080/tcp"},
+		Labels:       labels,
+	}
+	hasura, err = runWithContext(ctx, pool, hasuraopts)
+	if errors.Is(err, dc.ErrContainerAlreadyExists) {
+		if purgeErr := pool.Purge(pg); purgeErr != nil {
+			t.Logf("could not purge resource: %s", purgeErr)
+		}
+		return nil, nil, err
 	}
-	hasura, err := pool.RunWithOptions(hasuraopts)
 	if err != nil {
-		t.Fatalf("Could not start resource: %s", err)
+		fail("Could not start resource: %s", err, pg)
 	}
-	if err = pool.Retry(func() error {
-		var err error
-		resp, err := http.Get(fmt.Sprintf("http://localhost:%s/healthz", hasura.GetPort("8080/tcp")))
+	if err = waitForHasura(ctx, pool, hasura.GetPort("8080/tcp")); err != nil {
+		fail("Could not connect to docker: %s", err, pg, hasura)
+	}
+	return pg, hasura, nil
+}
+
+// runWithContext starts a container using pool.RunWithOptions, returning when ctx
+// is done. A container which starts once ctx is done is removed
+func runWithContext(ctx context.Context, pool *dockertest.Pool, opts *dockertest.RunOptions) (*dockertest.Resource, error) {
+	type result struct {
+		resource *dockertest.Resource
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resource, err := pool.RunWithOptions(opts)
+		done <- result{resource, err}
+	}()
+	select {
+	case res := <-done:
+		return res.resource, res.err
+	case <-ctx.Done():
+		go func() {
+			if res := <-done; res.resource != nil {
+				_ = pool.Purge(res.resource)
+			}
+		}()
+		return nil, fmt.Errorf("starting container %s: %w", opts.Name, ctx.Err())
+	}
+}
+
+// retryWithContext calls op until it succeeds, giving up once pool.MaxWait
+// elapses or ctx is done, in which case the last error of op is returned along with the reason
+func retryWithContext(ctx context.Context, pool *dockertest.Pool, op func(ctx context.Context) error) error {
+	if pool.MaxWait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, pool.MaxWait)
+		defer cancel()
+	}
+	for {
+		err := op(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %v", ctx.Err(), err)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// waitForHasura waits for the health check of the hasura instance at port to pass
+func waitForHasura(ctx context.Context, pool *dockertest.Pool, port string) error {
+	return retryWithContext(ctx, pool, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://localhost:%s/healthz", port), nil)
 		if err != nil {
 			return err
 		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
 		if resp.StatusCode != http.StatusOK {
 			return errors.New("not ready")
 		}
 		return nil
-	}); err != nil {
-		t.Fatalf("Could not connect to docker: %s", err)
-	}
-
-	teardown = func() {
-		if err = pool.Purge(hasura); err != nil {
-			t.Fatalf("Could not purge resource: %s", err)
-		}
-		if err = pool.Purge(pg); err != nil {
-			t.Fatalf("Could not purge resource: %s", err)
-		}
-	}
-	return hasura.GetPort("8080/tcp"), teardown
+	})
 }
 
-func StartHasuraWithMetadataDatabase(t *testing.T, version string) (port string, teardown func()) {
+// StartHasuraWithMetadataDatabase starts a hasura instance with a metadata database in docker.
+// Diagnostics are logged to logger when one is passed, or to t otherwise
+func StartHasuraWithMetadataDatabase(t *testing.T, version string, logger ...Logger) (port string, teardown func()) {
 	if len(version) == 0 {
 		t.Fatal("no hasura version provided, probably use testutil.HasuraVersion")
 	}
 	var err error
-	pool, err := dockertest.NewPool("")
-	if err != nil {
-		t.Fatalf("Could not connect to docker: %s", err)
-	}
+	pool := mustGetPool(t)
 	uniqueName := getUniqueName(t)
 	pgopts := &dockertest.RunOptions{
 		Name:       fmt.Sprintf("%s-%s", uniqueName, "pg"),
 		Repository: "postgres",
 		Tag:        "11",
 		Env: []string{
-			"POSTGRES_PASSWORD=postgrespassword",
+			fmt.Sprintf("POSTGRES_PASSWORD=%s", PostgresPassword),
 			"POSTGRES_DB=postgres",
 		},
 	}
@@ -141,16 +245,17 @@ func StartHasuraWithMetadataDatabase(t *testing.T, version string) (port string,
 	var db *sql.DB
 	if err = pool.Retry(func() error {
 		var err error
-		db, err = sql.Open("postgres", fmt.Sprintf("postgres://postgres:postgrespassword@%s:%s/%s?sslmode=disable", "0.0.0.0", pg.GetPort("5432/tcp"), "postgres"))
+		db, err = sql.Open("postgres", PostgresConnString("0.0.0.0", pg.GetPort("5432/tcp"), "postgres", true))
 		if err != nil {
 			return err
 		}
 		return db.Ping()
 	}); err != nil {
+		DumpContainerLogs(t, pool, pg)
 		t.Fatal(err)
 	}
 	envs := []string{
-		fmt.Sprintf("HASURA_GRAPHQL_METADATA_DATABASE_URL=postgres://postgres:postgrespassword@%s:%s/postgres", DockerSwitchIP, pg.GetPort("5432/tcp")),
+		fmt.Sprintf("HASURA_GRAPHQL_METADATA_DATABASE_URL=%s", PostgresConnString(DockerSwitchIP, pg.GetPort("5432/tcp"), "postgres", false)),
 		`HASURA_GRAPHQL_ENABLE_CONSOLE=true`,
 		"HASURA_GRAPHQL_DEV_MODE=true",
 		"HASURA_GRAPHQL_ENABLED_LOG_TYPES=startup, http-log, webhook-log, websocket-log, query-log",
@@ -168,6 +273,7 @@ func StartHasuraWithMetadataDatabase(t *testing.T, version string) (port string,
 	}
 	hasura, err := pool.RunWithOptions(hasuraopts)
 	if err != nil {
+		DumpContainerLogs(t, pool, pg)
 		t.Fatalf("Could not start resource: %s", err)
 	}
 
@@ -182,10 +288,15 @@ func StartHasuraWithMetadataDatabase(t *testing.T, version string) (port string,
 		}
 		return nil
 	}); err != nil {
+		DumpContainerLogs(t, pool, pg, hasura)
 		t.Fatalf("Could not connect to docker: %s", err)
 	}
+	getLogger(t, logger).Logf("hasura %s is ready at %s:%s", version, BaseURL, hasura.GetPort("8080/tcp"))
 
 	teardown = func() {
+		if t.Failed() {
+			DumpContainerLogs(t, pool, pg, hasura)
+		}
 		if err = pool.Purge(hasura); err != nil {
 			t.Fatalf("Could not purge resource: %s", err)
 		}
@@ -198,8 +309,9 @@ func StartHasuraWithMetadataDatabase(t *testing.T, version string) (port string,
 
 // starts a hasura instance with a metadata database and a msssql source
 // returns the mssql port, source name and teardown function
-func StartHasuraWithMSSQLSource(t *testing.T, version string) (string, string, func()) {
-	hasuraPort, hasuraTeardown := StartHasuraWithMetadataDatabase(t, version)
+// Diagnostics are logged to logger when one is passed, or to t otherwise
+func StartHasuraWithMSSQLSource(t *testing.T, version string, logger ...Logger) (string, string, func()) {
+	hasuraPort, hasuraTeardown := StartHasuraWithMetadataDatabase(t, version, logger...)
 	sourcename := randomdata.SillyName()
 	mssqlPort, mssqlTeardown := startMSSQLContainer(t)
 
@@ -207,18 +319,22 @@ func StartHasuraWithMSSQLSource(t *testing.T, version string) (string, string, f
 		hasuraTeardown()
 		mssqlTeardown()
 	}
-	connectionString := fmt.Sprintf("DRIVER={ODBC Driver 17 for SQL Server};SERVER=%s,%s;DATABASE=master;Uid=SA;Pwd=%s;Encrypt=no", DockerSwitchIP, mssqlPort, MSSQLPassword)
-	addSourceToHasura(t, fmt.Sprintf("%s:%s", BaseURL, hasuraPort), connectionString, sourcename)
+	connectionString := MSSQLConnString(DockerSwitchIP, mssqlPort, "master")
+	if err := addSourceToHasura(getLogger(t, logger), fmt.Sprintf("%s:%s", BaseURL, hasuraPort), "mssql", sourcename, connectionStringConfiguration("connection_string", connectionString)); err != nil {
+		// mark the test as failed before teardown, so that logs of the containers are dumped
+		t.Errorf("cannot add mssql source to hasura: %v", err)
+		teardown()
+		t.FailNow()
+	}
 	return hasuraPort, sourcename, teardown
 }
 
 // startsMSSQLContainer and creates a database and returns the port number
 func startMSSQLContainer(t *testing.T) (string, func()) {
-	pool, err := dockertest.NewPool("")
+	// MaxWait is only changed for mssql, on a copy of the shared pool
+	shared := *mustGetPool(t)
+	pool := &shared
 	pool.MaxWait = time.Minute
-	if err != nil {
-		t.Fatalf("Could not connect to docker: %s", err)
-	}
 	opts := &dockertest.RunOptions{
 		Name:       fmt.Sprintf("%s-%s", randomdata.SillyName(), "mssql"),
 		Repository: "mcr.microsoft.com/mssql/server",
@@ -247,9 +363,13 @@ func startMSSQLContainer(t *testing.T) (string, func()) {
 		}
 		return nil
 	}); err != nil {
+		DumpContainerLogs(t, pool, mssql)
 		t.Fatal(err)
 	}
 	teardown := func() {
+		if t.Failed() {
+			DumpContainerLogs(t, pool, mssql)
+		}
 		if err = pool.Purge(mssql); err != nil {
 			t.Fatalf("Could not purge resource: %s", err)
 		}
@@ -257,26 +377,43 @@ func startMSSQLContainer(t *testing.T) (string, func()) {
 	return mssql.GetPort("1433/tcp"), teardown
 }
 
-func addSourceToHasura(t *testing.T, hasuraEndpoint, connectionString, sourceName string) {
+// addSourceToHasura adds a source of kind (eg: mssql, citus, postgres or bigquery) to hasura
+// using the <kind>_add_source metadata API, configuration is marshalled to JSON as the
+// configuration of the source, eg: a map with the connection_info of the source
+func addSourceToHasura(logger Logger, hasuraEndpoint, kind, sourceName string, configuration interface{}) error {
 	url := fmt.Sprintf("%s/v1/metadata", hasuraEndpoint)
-	body := fmt.Sprintf(`
-{
-  "type": "mssql_add_source",
-  "args": {
-    "name": "%s",
-    "configuration": {
-        "connection_info": {
-            "connection_string": "%s"
-        }
-    }
-  }
+	body, err := json.Marshal(map[string]interface{}{
+		"type": kind + "_add_source",
+		"args": map[string]interface{}{
+			"name":          sourceName,
+			"configuration": configuration,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	logger.Logf("adding %s source %s to hasura at %s", kind, sourceName, hasuraEndpoint)
+	return sendMetadataRequest(url, string(body))
+}
+
+// connectionStringConfiguration returns the configuration of a source
+// connecting using a connection string, field is the name of the field of
+// connection_info holding it (connection_string for mssql, database_url for postgres)
+func connectionStringConfiguration(field, connectionString string) map[string]interface{} {
+	return map[string]interface{}{
+		"connection_info": map[string]interface{}{
+			field: connectionString,
+		},
+	}
 }
-`, sourceName, connectionString)
-	fmt.Println(connectionString)
-	fmt.Println(hasuraEndpoint)
 
+// sendMetadataRequest sends a request with body to the metadata API at url,
+// using the admin secret in HASURA_GRAPHQL_TEST_ADMIN_SECRET when it is set
+func sendMetadataRequest(url, body string) error {
 	req, err := http.NewRequest("POST", url, strings.NewReader(body))
-	require.NoError(t, err)
+	if err != nil {
+		return err
+	}
 	req.Header.Set("Content-Type", "application/json")
 	adminSecret := os.Getenv("HASURA_GRAPHQL_TEST_ADMIN_SECRET")
 	if adminSecret != "" {
@@ -284,16 +421,161 @@ func addSourceToHasura(t *testing.T, hasuraEndpoint, connectionString, sourceNam
 	}
 
 	r, err := http.DefaultClient.Do(req)
-	require.NoError(t, err)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
 	if r.StatusCode != http.StatusOK {
 		body, err := ioutil.ReadAll(r.Body)
 		if err != nil {
-			t.Fatal(err)
+			return err
 		}
-		defer r.Body.Close()
-		t.Fatalf("cannot add mssql source to hasura: %s", string(body))
+		return fmt.Errorf("%s", string(body))
 	}
+	return nil
 }
+
+// starts a hasura instance with a metadata database and a citus source
+// returns the hasura port, source name and teardown function
+// Diagnostics are logged to logger when one is passed, or to t otherwise
+func StartHasuraWithCitusSource(t *testing.T, version string, logger ...Logger) (string, string, func()) {
+	hasuraPort, hasuraTeardown := StartHasuraWithMetadataDatabase(t, version, logger...)
+	sourcename := randomdata.SillyName()
+	citusPort, citusTeardown := startCitusContainer(t)
+
+	teardown := func() {
+		hasuraTeardown()
+		citusTeardown()
+	}
+	connectionString := PostgresConnString(DockerSwitchIP, citusPort, "postgres", true)
+	if err := addSourceToHasura(getLogger(t, logger), fmt.Sprintf("%s:%s", BaseURL, hasuraPort), "citus", sourcename, connectionStringConfiguration("database_url", connectionString)); err != nil {
+		// mark the test as failed before teardown, so that logs of the containers are dumped
+		t.Errorf("cannot add citus source to hasura: %v", err)
+		teardown()
+		t.FailNow()
+	}
+	return hasuraPort, sourcename, teardown
+}
+
+// startCitusContainer starts a single node citus container and returns
+// the port number once it can be connected to
+func startCitusContainer(t *testing.T) (string, func()) {
+	shared := *mustGetPool(t)
+	pool := &shared
+	pool.MaxWait = time.Minute
+	opts := &dockertest.RunOptions{
+		Name:       fmt.Sprintf("%s-%s", randomdata.SillyName(), "citus"),
+		Repository: "citusdata/citus",
+		Tag:        "10.2",
+		Env: []string{
+			fmt.Sprintf("POSTGRES_USER=%s", PostgresUser),
+			fmt.Sprintf("POSTGRES_PASSWORD=%s", PostgresPassword),
+		},
+		ExposedPorts: []string{"5432/tcp"},
+	}
+	citus, err := pool.RunWithOptions(opts)
+	if err != nil {
+		t.Fatalf("Could not start resource: %s", err)
+	}
+	if err = pool.Retry(func() error {
+		db, err := sql.Open("postgres", PostgresConnString("0.0.0.0", citus.GetPort("5432/tcp"), "postgres", true))
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return db.PingContext(context.Background())
+	}); err != nil {
+		DumpContainerLogs(t, pool, citus)
+		t.Fatal(err)
+	}
+	teardown := func() {
+		if t.Failed() {
+			DumpContainerLogs(t, pool, citus)
+		}
+		if err = pool.Purge(citus); err != nil {
+			t.Fatalf("Could not purge resource: %s", err)
+		}
+	}
+	return citus.GetPort("5432/tcp"), teardown
+}
+
+// starts a hasura instance with a metadata database and a mysql source
+// returns the hasura port, source name and teardown function
+// Diagnostics are logged to logger when one is passed, or to t otherwise
+func StartHasuraWithMySQLSource(t *testing.T, version string, logger ...Logger) (string, string, func()) {
+	hasuraPort, hasuraTeardown := StartHasuraWithMetadataDatabase(t, version, logger...)
+	sourcename := randomdata.SillyName()
+	mysqlPort, mysqlTeardown := startMySQLContainer(t)
+
+	teardown := func() {
+		hasuraTeardown()
+		mysqlTeardown()
+	}
+	if err := addMySQLSourceToHasura(getLogger(t, logger), fmt.Sprintf("%s:%s", BaseURL, hasuraPort), DockerSwitchIP, mysqlPort, sourcename); err != nil {
+		// mark the test as failed before teardown, so that logs of the containers are dumped
+		t.Errorf("cannot add mysql source to hasura: %v", err)
+		teardown()
+		t.FailNow()
+	}
+	return hasuraPort, sourcename, teardown
+}
+
+// startMySQLContainer starts a mysql 8 container with database MySQLDatabase
+// and returns the port number once it can be connected to
+func startMySQLContainer(t *testing.T) (string, func()) {
+	shared := *mustGetPool(t)
+	pool := &shared
+	pool.MaxWait = time.Minute
+	opts := &dockertest.RunOptions{
+		Name:       fmt.Sprintf("%s-%s", randomdata.SillyName(), "mysql"),
+		Repository: "mysql",
+		Tag:        "8",
+		Env: []string{
+			fmt.Sprintf("MYSQL_ROOT_PASSWORD=%s", MySQLPassword),
+			fmt.Sprintf("MYSQL_DATABASE=%s", MySQLDatabase),
+		},
+		ExposedPorts: []string{"3306/tcp"},
+	}
+	mysql, err := pool.RunWithOptions(opts)
+	if err != nil {
+		t.Fatalf("Could not start resource: %s", err)
+	}
+	if err = pool.Retry(func() error {
+		db, err := sql.Open("mysql", fmt.Sprintf("root:%s@tcp(%s:%s)/%s", MySQLPassword, "0.0.0.0", mysql.GetPort("3306/tcp"), MySQLDatabase))
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return db.PingContext(context.Background())
+	}); err != nil {
+		DumpContainerLogs(t, pool, mysql)
+		t.Fatal(err)
+	}
+	teardown := func() {
+		if t.Failed() {
+			DumpContainerLogs(t, pool, mysql)
+		}
+		if err = pool.Purge(mysql); err != nil {
+			t.Fatalf("Could not purge resource: %s", err)
+		}
+	}
+	return mysql.GetPort("3306/tcp"), teardown
+}
+
+func addMySQLSourceToHasura(logger Logger, hasuraEndpoint, host, port, sourceName string) error {
+	portNumber, err := strconv.Atoi(port)
+	if err != nil {
+		return err
+	}
+	return addSourceToHasura(logger, hasuraEndpoint, "mysql", sourceName, map[string]interface{}{
+		"host":     host,
+		"port":     portNumber,
+		"user":     "root",
+		"password": MySQLPassword,
+		"database": MySQLDatabase,
+	})
+}
+
 func NewHttpcClient(t *testing.T, port string, headers map[string]string) *httpc.Client {
 	adminSecret := os.Getenv("HASURA_GRAPHQL_TEST_ADMIN_SECRET")
 	if headers == nil {
@@ -33,6 +33,8 @@ var (
 	Hostname      = "localhost"
 	BaseURL       = fmt.Sprintf("http://%s", Hostname)
 	MSSQLPassword = "MSSQLp@ssw0rd"
+	MySQLPassword = "MySQLp@ssw0rd"
+	MySQLDatabase = "hasura"
 	CLIBinaryPath = func() string {
 		if os.Getenv("CI") == "true" {
 			return "/build/_cli_output/binaries/cli-hasura-linux-amd64"
@@ -45,4 +47,8 @@ var (
 
 		return "hasura"
 	}()
+	// directory to which logs of containers are written on test failures
+	ContainerLogsDir = os.Getenv("HASURA_TEST_CONTAINER_LOGS_DIR")
+	// when set, StartHasura reuses the containers started by StartHasuraShared
+	ReuseContainers = os.Getenv("HASURA_TEST_REUSE_CONTAINER") == "true"
 )
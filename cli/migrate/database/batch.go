@@ -0,0 +1,35 @@
+package database
+
+import (
+	"fmt"
+	"io"
+)
+
+// BatchDriver is implemented by drivers which can run many migrations in a
+// single request to the server
+type BatchDriver interface {
+	// CanRunInBatch reports whether migrations of fileType can be run using RunBatch
+	CanRunInBatch(fileType string) bool
+	// RunBatch runs migrations, in order, in a single request. When one of
+	// the migrations fails the returned error is a *BatchError
+	RunBatch(migrations []io.Reader) error
+}
+
+// BatchError is returned by BatchDriver.RunBatch when a migration of the batch fails
+type BatchError struct {
+	// Index is the position of the failed migration in the batch,
+	// -1 when it cannot be determined from the response of the server
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	if e.Index < 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("migration %d of batch failed: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
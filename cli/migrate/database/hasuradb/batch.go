@@ -0,0 +1,91 @@
+package hasuradb
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+
+	"github.com/hasura/graphql-engine/cli/internal/hasura"
+	"github.com/hasura/graphql-engine/cli/migrate/database"
+)
+
+type bulkSender interface {
+	Bulk([]hasura.RequestBody) (io.Reader, error)
+}
+
+var errBatchNotSupported = fmt.Errorf("running migrations in batches is not supported for this database")
+
+// errors of bulk requests have the path of the failed request eg: $.args[2].args
+var bulkErrorPathRegex = regexp.MustCompile(`\$\.args\[(\d+)\]`)
+
+func (h *HasuraDB) bulkSender() (bulkSender, string) {
+	switch h.hasuraOpts.SourceKind {
+	case hasura.SourceKindPG:
+		sender, _ := h.pgSourceOps.(bulkSender)
+		return sender, "run_sql"
+	case hasura.SourceKindMSSQL:
+		sender, _ := h.mssqlSourceOps.(bulkSender)
+		return sender, "mssql_run_sql"
+	}
+	return nil, ""
+}
+
+func (h *HasuraDB) CanRunInBatch(fileType string) bool {
+	sender, _ := h.bulkSender()
+	return fileType == "sql" && sender != nil
+}
+
+// RunBatch runs SQL migrations as a single bulk request
+func (h *HasuraDB) RunBatch(migrations []io.Reader) error {
+	sender, requestType := h.bulkSender()
+	if sender == nil {
+		return &database.BatchError{Index: -1, Err: errBatchNotSupported}
+	}
+	var requests []hasura.RequestBody
+	// position of the migration in the batch for each request, empty
+	// migrations are not sent
+	var indexes []int
+	for idx, migration := range migrations {
+		b, err := ioutil.ReadAll(migration)
+		if err != nil {
+			return &database.BatchError{Index: idx, Err: err}
+		}
+		if len(b) == 0 {
+			continue
+		}
+		sqlInput := hasura.PGRunSQLInput{
+			SQL:    string(b),
+			Source: h.hasuraOpts.SourceName,
+		}
+		if h.config.enableCheckMetadataConsistency {
+			sqlInput.CheckMetadataConsistency = func() *bool { b := false; return &b }()
+		}
+		var args interface{} = sqlInput
+		if h.hasuraOpts.SourceKind == hasura.SourceKindMSSQL {
+			args = hasura.MSSQLRunSQLInput(sqlInput)
+		}
+		requests = append(requests, hasura.RequestBody{Type: requestType, Args: args})
+		indexes = append(indexes, idx)
+	}
+	if len(requests) == 0 {
+		return nil
+	}
+	if _, err := sender.Bulk(requests); err != nil {
+		return &database.BatchError{Index: failedBatchIndex(err, indexes), Err: err}
+	}
+	return nil
+}
+
+func failedBatchIndex(err error, indexes []int) int {
+	matches := bulkErrorPathRegex.FindStringSubmatch(err.Error())
+	if len(matches) != 2 {
+		return -1
+	}
+	idx, convErr := strconv.Atoi(matches[1])
+	if convErr != nil || idx >= len(indexes) {
+		return -1
+	}
+	return indexes[idx]
+}
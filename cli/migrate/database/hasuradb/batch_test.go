@@ -0,0 +1,39 @@
+package hasuradb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_failedBatchIndex(t *testing.T) {
+	// the second migration of the batch was empty and not sent
+	indexes := []int{0, 2, 3}
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{
+			"maps the index of the failed request to the migration",
+			errors.New(`bulk request failed: 400 {"path":"$.args[1].args","error":"syntax error","code":"postgres-error"}`),
+			2,
+		},
+		{
+			"unknown when the path is missing",
+			errors.New("bulk request failed: 500 internal error"),
+			-1,
+		},
+		{
+			"unknown when the path is out of range",
+			errors.New(`bulk request failed: 400 {"path":"$.args[5].args"}`),
+			-1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, failedBatchIndex(tt.err, indexes))
+		})
+	}
+}
@@ -103,6 +103,9 @@ type Migrate struct {
 
 	SkipExecution bool
 	DryRun        bool
+	// BatchSize when > 1 runs up to BatchSize migrations in a single request,
+	// when supported by the database driver. Versions are still recorded individually
+	BatchSize int
 }
 
 type NewMigrateOpts struct {
@@ -1151,6 +1154,9 @@ func (m *Migrate) readDown(limit int64, ret chan<- interface{}) {
 // to stop execution because it might have received a stop signal on the
 // GracefulStop channel.
 func (m *Migrate) runMigrations(ret <-chan interface{}) error {
+	batchDrv, batching := m.databaseDrv.(database.BatchDriver)
+	batching = batching && m.BatchSize > 1 && !m.SkipExecution
+	var batch []*Migration
 	for r := range ret {
 		if m.stop() {
 			return nil
@@ -1164,26 +1170,76 @@ func (m *Migrate) runMigrations(ret <-chan interface{}) error {
 		case *Migration:
 			migr := r.(*Migration)
 			if migr.Body != nil {
+				if batching && batchDrv.CanRunInBatch(migr.FileType) {
+					batch = append(batch, migr)
+					if len(batch) >= m.BatchSize {
+						if err := m.runBatch(batchDrv, batch); err != nil {
+							return err
+						}
+						batch = nil
+					}
+					continue
+				}
+				// migrations are applied in order, so the pending
+				// batch is run before a migration which cannot be batched
+				if err := m.runBatch(batchDrv, batch); err != nil {
+					return err
+				}
+				batch = nil
 				if !m.SkipExecution {
 					m.Logger.Debugf("applying migration: %s", migr.FileName)
 					if err := m.databaseDrv.Run(migr.BufferedBody, migr.FileType, migr.FileName); err != nil {
 						return err
 					}
 				}
-				version := int64(migr.Version)
-				// Insert Version number into the table
-				if err := m.databaseDrv.SetVersion(version, false); err != nil {
+				if err := m.recordMigration(migr); err != nil {
 					return err
 				}
-				if version != migr.TargetVersion {
-					// Delete Version number from the table
-					if err := m.databaseDrv.RemoveVersion(version); err != nil {
-						return err
-					}
-				}
 			}
 		}
 	}
+	return m.runBatch(batchDrv, batch)
+}
+
+// runBatch runs migrations in a single request and records their versions.
+// When the batch fails, none of the versions of the batch are recorded
+func (m *Migrate) runBatch(drv database.BatchDriver, batch []*Migration) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	bodies := make([]io.Reader, 0, len(batch))
+	for _, migr := range batch {
+		m.Logger.Debugf("applying migration in batch: %s", migr.FileName)
+		bodies = append(bodies, migr.BufferedBody)
+	}
+	if err := drv.RunBatch(bodies); err != nil {
+		var batchErr *database.BatchError
+		if errors.As(err, &batchErr) && batchErr.Index >= 0 && batchErr.Index < len(batch) {
+			failed := batch[batchErr.Index]
+			return fmt.Errorf("applying migration %s (version %d) of batch: %w", failed.FileName, failed.Version, batchErr.Err)
+		}
+		return fmt.Errorf("applying batch of migrations %d to %d: %w", batch[0].Version, batch[len(batch)-1].Version, err)
+	}
+	for _, migr := range batch {
+		if err := m.recordMigration(migr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Migrate) recordMigration(migr *Migration) error {
+	version := int64(migr.Version)
+	// Insert Version number into the table
+	if err := m.databaseDrv.SetVersion(version, false); err != nil {
+		return err
+	}
+	if version != migr.TargetVersion {
+		// Delete Version number from the table
+		if err := m.databaseDrv.RemoveVersion(version); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
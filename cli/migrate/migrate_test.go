@@ -0,0 +1,146 @@
+package migrate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/hasura/graphql-engine/cli/migrate/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// batchDriver records the migrations it runs and the versions it records,
+// the methods of database.Driver not used by runMigrations are not implemented
+type batchDriver struct {
+	database.Driver
+	events []string
+	// failBatch is the number of the batch (starting at 1) which fails
+	failBatch int
+	batches   int
+}
+
+func (d *batchDriver) CanRunInBatch(fileType string) bool {
+	return fileType == "sql"
+}
+
+func (d *batchDriver) RunBatch(migrations []io.Reader) error {
+	d.batches++
+	var names []string
+	for _, migration := range migrations {
+		b, err := ioutil.ReadAll(migration)
+		if err != nil {
+			return err
+		}
+		names = append(names, string(b))
+	}
+	if d.batches == d.failBatch {
+		return &database.BatchError{Index: len(migrations) - 1, Err: fmt.Errorf("migration failed")}
+	}
+	d.events = append(d.events, "batch "+strings.Join(names, " "))
+	return nil
+}
+
+func (d *batchDriver) Run(migration io.Reader, fileType, fileName string) error {
+	d.events = append(d.events, "run "+fileName)
+	return nil
+}
+
+func (d *batchDriver) ResetQuery() {}
+
+func (d *batchDriver) SetVersion(version int64, dirty bool) error {
+	d.events = append(d.events, fmt.Sprintf("set %d", version))
+	return nil
+}
+
+func (d *batchDriver) RemoveVersion(version int64) error {
+	d.events = append(d.events, fmt.Sprintf("remove %d", version))
+	return nil
+}
+
+func TestMigrate_runMigrations(t *testing.T) {
+	tests := []struct {
+		name      string
+		batchSize int
+		// file types of the migrations, which have versions 1, 2, ...
+		fileTypes  []string
+		failBatch  int
+		wantEvents []string
+		wantErr    string
+	}{
+		{
+			"batch is run once it has batch size migrations",
+			2,
+			[]string{"sql", "sql", "sql", "sql"},
+			0,
+			[]string{"batch m1 m2", "set 1", "set 2", "batch m3 m4", "set 3", "set 4"},
+			"",
+		},
+		{
+			"pending batch is run when there are no more migrations",
+			2,
+			[]string{"sql", "sql", "sql"},
+			0,
+			[]string{"batch m1 m2", "set 1", "set 2", "batch m3", "set 3"},
+			"",
+		},
+		{
+			"pending batch is run before a migration which cannot be batched",
+			3,
+			[]string{"sql", "sql", "meta", "sql"},
+			0,
+			[]string{"batch m1 m2", "set 1", "set 2", "run m3", "set 3", "batch m4", "set 4"},
+			"",
+		},
+		{
+			"migrations are run one at a time when batch size is 1",
+			1,
+			[]string{"sql", "sql"},
+			0,
+			[]string{"run m1", "set 1", "run m2", "set 2"},
+			"",
+		},
+		{
+			"versions of a failed batch are not recorded",
+			2,
+			[]string{"sql", "sql", "sql", "sql", "sql"},
+			2,
+			[]string{"batch m1 m2", "set 1", "set 2"},
+			"applying migration m4 (version 4) of batch: migration failed",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			drv := &batchDriver{failBatch: tc.failBatch}
+			m := &Migrate{
+				databaseDrv:  drv,
+				Logger:       logrus.New(),
+				GracefulStop: make(chan bool, 1),
+				BatchSize:    tc.batchSize,
+			}
+			ret := make(chan interface{}, len(tc.fileTypes))
+			for idx, fileType := range tc.fileTypes {
+				name := fmt.Sprintf("m%d", idx+1)
+				ret <- &Migration{
+					Version:       uint64(idx + 1),
+					TargetVersion: int64(idx + 1),
+					FileType:      fileType,
+					FileName:      name,
+					Body:          ioutil.NopCloser(bytes.NewBufferString(name)),
+					BufferedBody:  bytes.NewBufferString(name),
+				}
+			}
+			close(ret)
+			err := m.runMigrations(ret)
+			if len(tc.wantErr) > 0 {
+				assert.EqualError(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.wantEvents, drv.events)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package seed
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// LockFileName is the name of the file which records checksums of
+// the seed files of a database, in the format used by sha256sum
+const LockFileName = ".lock"
+
+// WriteLockFile records checksums of all seed files in seedsDirectory
+// (eg: seeds/<database>) in the lockfile of the directory
+func WriteLockFile(fs afero.Fs, seedsDirectory string) error {
+	checksums, err := getChecksums(fs, seedsDirectory)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, name := range sortedNames(checksums) {
+		fmt.Fprintf(&buf, "%s  %s\n", checksums[name], name)
+	}
+	if err := afero.WriteFile(fs, filepath.Join(seedsDirectory, LockFileName), buf.Bytes(), 0644); err != nil {
+		return errors.Wrap(err, "writing seeds lockfile")
+	}
+	return nil
+}
+
+// VerifyLockFile returns the names of seed files in seedsDirectory which were
+// added, modified or removed since the lockfile of the directory was written
+func VerifyLockFile(fs afero.Fs, seedsDirectory string) ([]string, error) {
+	locked, err := readLockFile(fs, filepath.Join(seedsDirectory, LockFileName))
+	if err != nil {
+		return nil, err
+	}
+	checksums, err := getChecksums(fs, seedsDirectory)
+	if err != nil {
+		return nil, err
+	}
+	var changed []string
+	for name, checksum := range checksums {
+		if locked[name] != checksum {
+			changed = append(changed, name)
+		}
+	}
+	for name := range locked {
+		if _, ok := checksums[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+func readLockFile(fs afero.Fs, path string) (map[string]string, error) {
+	b, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading seeds lockfile")
+	}
+	checksums := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		parts := strings.SplitN(line, "  ", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid line in seeds lockfile %s: %q", path, line)
+		}
+		checksums[parts[1]] = parts[0]
+	}
+	return checksums, scanner.Err()
+}
+
+// getChecksums returns the sha256 checksums of seed files in seedsDirectory
+// keyed by their slash separated path relative to seedsDirectory
+func getChecksums(fs afero.Fs, seedsDirectory string) (map[string]string, error) {
+	checksums := map[string]string{}
+	err := afero.Walk(fs, seedsDirectory, func(path string, file os.FileInfo, err error) error {
+		if file == nil || err != nil {
+			return err
+		}
+		if file.IsDir() || hasAllowedSeedFileExtensions(file.Name()) != nil {
+			return nil
+		}
+		b, err := afero.ReadFile(fs, path)
+		if err != nil {
+			return errors.Wrap(err, "error opening file")
+		}
+		name, err := filepath.Rel(seedsDirectory, path)
+		if err != nil {
+			return err
+		}
+		checksums[filepath.ToSlash(name)] = fmt.Sprintf("%x", sha256.Sum256(b))
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "computing checksums of seed files")
+	}
+	return checksums, nil
+}
+
+func sortedNames(m map[string]string) []string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
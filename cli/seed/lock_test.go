@@ -0,0 +1,37 @@
+package seed
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyLockFile(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for name, content := range map[string]string{
+		"seeds/default/1_users.sql":    "INSERT INTO users VALUES (1);",
+		"seeds/default/2_articles.sql": "INSERT INTO articles VALUES (1);",
+		"seeds/default/3_authors.sql":  "INSERT INTO authors VALUES (1);",
+		"seeds/default/README.md":      "not a seed",
+	} {
+		if err := afero.WriteFile(fs, name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assert.NoError(t, WriteLockFile(fs, "seeds/default"))
+
+	changed, err := VerifyLockFile(fs, "seeds/default")
+	assert.NoError(t, err)
+	assert.Empty(t, changed)
+
+	assert.NoError(t, afero.WriteFile(fs, "seeds/default/1_users.sql", []byte("INSERT INTO users VALUES (2);"), 0644))
+	assert.NoError(t, fs.Remove("seeds/default/2_articles.sql"))
+	assert.NoError(t, afero.WriteFile(fs, "seeds/default/4_tags.sql", []byte("INSERT INTO tags VALUES (1);"), 0644))
+	changed, err = VerifyLockFile(fs, "seeds/default")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1_users.sql", "2_articles.sql", "4_tags.sql"}, changed)
+
+	_, err = VerifyLockFile(fs, "seeds/other")
+	assert.Error(t, err)
+}
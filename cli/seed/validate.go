@@ -0,0 +1,69 @@
+package seed
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// ValidateSeedFile does a light check of a seed file. SQL files should not be
+// empty and should have their last statement terminated, CSV files should have
+// the same number of columns in every row. Files of other types are not checked.
+func ValidateSeedFile(fs afero.Fs, path string) error {
+	b, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".sql":
+		return validateSQL(b)
+	case ".csv":
+		return validateCSV(b)
+	}
+	return nil
+}
+
+func validateSQL(b []byte) error {
+	var lines []string
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		// comments are not statements
+		if len(line) == 0 || strings.HasPrefix(line, "--") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return fmt.Errorf("file has no SQL statements")
+	}
+	if !strings.HasSuffix(lines[len(lines)-1], ";") {
+		return fmt.Errorf("last SQL statement is not terminated with a ;")
+	}
+	return nil
+}
+
+func validateCSV(b []byte) error {
+	// a zero FieldsPerRecord makes the reader require every
+	// row to have as many columns as the first one
+	r := csv.NewReader(bytes.NewReader(b))
+	rows := 0
+	for {
+		_, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		rows++
+	}
+	if rows == 0 {
+		return fmt.Errorf("file has no rows")
+	}
+	return nil
+}
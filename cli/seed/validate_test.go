@@ -0,0 +1,39 @@
+package seed
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSeedFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr bool
+	}{
+		{"valid sql", "seed.sql", "INSERT INTO t VALUES (1);\n-- done\n", false},
+		{"empty sql", "seed.sql", "\n-- nothing here\n", true},
+		{"unterminated sql", "seed.sql", "INSERT INTO t VALUES (1);\nINSERT INTO t VALUES (2)\n", true},
+		{"valid csv", "seed.csv", "id,name\n1,a\n2,b\n", false},
+		{"inconsistent csv", "seed.csv", "id,name\n1,a,extra\n", true},
+		{"empty csv", "seed.csv", "", true},
+		{"other files are not checked", "seed.txt", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			if err := afero.WriteFile(fs, tt.file, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			err := ValidateSeedFile(fs, tt.file)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -44,6 +44,35 @@ func GetSelectPrompt(message string, options []string) (selection string, err er
 	return
 }
 
+// GetSelectPromptWithSearch works like GetSelectPrompt but starts in search mode,
+// filtering the options as the user types using a fuzzy match
+func GetSelectPromptWithSearch(message string, options []string) (selection string, err error) {
+	prompt := promptui.Select{
+		Label: message,
+		Items: options,
+		Searcher: func(input string, index int) bool {
+			return FuzzyMatch(input, options[index])
+		},
+		StartInSearchMode: true,
+	}
+	_, selection, err = prompt.Run()
+	return
+}
+
+// FuzzyMatch reports whether all characters of input appear in target in the
+// same order, ignoring case and whitespace in input
+func FuzzyMatch(input, target string) bool {
+	target = strings.ToLower(target)
+	for _, c := range strings.ToLower(strings.Join(strings.Fields(input), "")) {
+		idx := strings.IndexRune(target, c)
+		if idx < 0 {
+			return false
+		}
+		target = target[idx+len(string(c)):]
+	}
+	return true
+}
+
 func GetInputPrompt(message string) (input string, err error) {
 	prompt := promptui.Prompt{
 		Label: message,
@@ -0,0 +1,33 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		target string
+		want   bool
+	}{
+		{"empty input", "", "default", true},
+		{"exact match", "default", "default", true},
+		{"prefix", "def", "default", true},
+		{"subsequence", "dfl", "default", true},
+		{"input is case insensitive", "DFL", "default", true},
+		{"target is case insensitive", "dfl", "DeFauLt", true},
+		{"whitespace in input is ignored", "d f l", "default", true},
+		{"characters out of order", "lfd", "default", false},
+		{"character not in target", "dx", "default", false},
+		{"repeated character used once in target", "dd", "default", false},
+		{"input longer than target", "defaults", "default", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FuzzyMatch(tt.input, tt.target))
+		})
+	}
+}